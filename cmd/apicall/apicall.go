@@ -0,0 +1,261 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package apicall implements the "api" command, which makes authenticated
+// calls to arbitrary platform API endpoints
+package apicall
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/cmdkit"
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+var supportedMethods = []string{
+	http.MethodGet,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodHead,
+	http.MethodOptions,
+}
+
+// apiCmd represents the api command
+var apiCmd = &cobra.Command{
+	Use:   "api METHOD PATH",
+	Short: "Make an authenticated call to any platform API endpoint",
+	Long: `Make an authenticated call to any platform API endpoint and display the response.
+
+The PATH is relative to the profile's URL, e.g., /knowledge-store/v1/objects/extensibility:solution. A full URL
+to the profile's server is also accepted. fsoc logs in as needed and adds the authentication headers to the request.
+
+The request body can be provided from a file or from stdin with --input. The body is assumed to be JSON, unless
+a Content-Type header is provided with --header, in which case it is sent as is.
+
+A JSON response is displayed in the selected output format; --fields can be used to transform it. Responses in
+other formats (e.g., text or HTML) are displayed as they are.
+With --paginate, GET requests follow the Link header pagination of collections and display all items.
+With --profiles or --all-profiles, only GET and HEAD requests are allowed.`,
+	Example: `
+  fsoc api GET /knowledge-store/v1/objects/extensibility:solution --header layer-type=TENANT --header layer-id=$TENANT
+  fsoc api GET /knowledge-store/v1/objects/extensibility:solution -H layer-type=TENANT -H layer-id=$TENANT --paginate -o ndjson
+  fsoc api GET /iam/policy-admin/v1beta2/roles --query max=10 -o json
+  fsoc api POST /knowledge-store/v1/objects/myapp:config --input object.json -H layer-type=TENANT -H layer-id=$TENANT
+  echo '{"name":"test"}' | fsoc api PATCH /myapp/v1/things/123 --input -`,
+	Args:             checkArgs,
+	RunE:             apiCall,
+	TraverseChildren: true,
+	Annotations:      map[string]string{config.AnnotationForMultipleProfiles: ""},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return supportedMethods, cobra.ShellCompDirectiveNoFileComp
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+// NewSubCmd returns the api command
+func NewSubCmd() *cobra.Command {
+	apiCmd.Flags().String("input", "", `file with the request body, or "-" to read it from stdin`)
+	apiCmd.Flags().StringArrayP("header", "H", nil, "request header in the form NAME=VALUE or NAME:VALUE (can be repeated)")
+	apiCmd.Flags().StringArrayP("query", "q", nil, "query parameter in the form NAME=VALUE (can be repeated)")
+	apiCmd.Flags().Bool("paginate", false, "follow pagination links and display all items of a collection (GET only)")
+	apiCmd.Flags().Int("limit", 0, "maximum number of collection items to fetch with --paginate (default no limit)")
+
+	return apiCmd
+}
+
+// checkArgs checks the arguments, allowing only read-only requests for multiple profiles (see the
+// --profiles and --all-profiles flags)
+func checkArgs(cmd *cobra.Command, args []string) error {
+	if err := cobra.ExactArgs(2)(cmd, args); err != nil {
+		return err
+	}
+	profiles, _ := cmd.Flags().GetStringSlice("profiles")
+	allProfiles, _ := cmd.Flags().GetBool("all-profiles")
+	method := strings.ToUpper(args[0])
+	if (len(profiles) > 0 || allProfiles) && method != http.MethodGet && method != http.MethodHead {
+		return fmt.Errorf("only GET and HEAD requests can be made for multiple profiles, not %v", method)
+	}
+	return nil
+}
+
+func apiCall(cmd *cobra.Command, args []string) error {
+	method := strings.ToUpper(args[0])
+	if !slices.Contains(supportedMethods, method) {
+		return fmt.Errorf("unsupported method %q; must be one of %v", args[0], strings.Join(supportedMethods, ", "))
+	}
+	path, err := resolvePath(config.CurrentContext(cmd.Context()), args[1])
+	if err != nil {
+		return err
+	}
+
+	// collect headers and query parameters
+	headerSpecs, _ := cmd.Flags().GetStringArray("header")
+	headers, err := parseHeaders(headerSpecs)
+	if err != nil {
+		return err
+	}
+	querySpecs, _ := cmd.Flags().GetStringArray("query")
+	query, err := parseQuery(querySpecs)
+	if err != nil {
+		return err
+	}
+
+	// read body, if any
+	var body any
+	if input, _ := cmd.Flags().GetString("input"); input != "" {
+		body, err = readBody(cmd, input, headers)
+		if err != nil {
+			return err
+		}
+	}
+
+	paginate, _ := cmd.Flags().GetBool("paginate")
+	if paginate && method != http.MethodGet {
+		return fmt.Errorf("--paginate can be used only with GET requests")
+	}
+	limit, _ := cmd.Flags().GetInt("limit")
+
+	// collections are JSON; display them in the selected output format
+	if paginate {
+		return cmdkit.FetchAndPrint(cmd, path, &cmdkit.FetchAndPrintOptions{
+			Method:       &method,
+			Headers:      headers,
+			IsCollection: true,
+			Filters:      query,
+			Limit:        limit,
+		})
+	}
+
+	// other responses may be in any format, so they are displayed as is unless they are JSON
+	if len(query) > 0 {
+		separator := "?"
+		if strings.Contains(path, "?") {
+			separator = "&"
+		}
+		path += separator + strings.Join(query, "&")
+	}
+	var response []byte
+	if err := api.JSONRequest(method, path, body, &response, &api.Options{Headers: headers, Context: cmd.Context()}); err != nil {
+		return fmt.Errorf("platform API call failed: %w", err)
+	}
+	printResponse(cmd, response)
+	return nil
+}
+
+// printResponse displays a JSON response body in the selected output format and any other
+// response body (e.g., text or HTML) as is
+func printResponse(cmd *cobra.Command, response []byte) {
+	if len(bytes.TrimSpace(response)) == 0 {
+		return
+	}
+	var value any
+	if err := json.Unmarshal(response, &value); err != nil {
+		cmd.Print(string(response))
+		if !bytes.HasSuffix(response, []byte("\n")) {
+			cmd.Println()
+		}
+		return
+	}
+	output.PrintCmdOutput(cmd, value)
+}
+
+// resolvePath converts the path argument to a path relative to the profile's URL, accepting
+// full URLs only if they point to the profile's server
+func resolvePath(cfg *config.Context, arg string) (string, error) {
+	if !strings.HasPrefix(arg, "http://") && !strings.HasPrefix(arg, "https://") {
+		return arg, nil
+	}
+	argUrl, err := url.Parse(arg)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse URL %q: %v", arg, err)
+	}
+	profileUrl, err := url.Parse(cfg.URL)
+	if err != nil || !strings.EqualFold(profileUrl.Host, argUrl.Host) {
+		return "", fmt.Errorf("URL %q does not match the profile's URL %q; please provide a path or select a different profile", arg, cfg.URL)
+	}
+	return argUrl.RequestURI(), nil
+}
+
+// parseHeaders parses header specifications in the form NAME=VALUE or NAME:VALUE
+func parseHeaders(specs []string) (map[string]string, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	headers := map[string]string{}
+	for _, spec := range specs {
+		idx := strings.IndexAny(spec, ":=")
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid header %q; must be in the form NAME=VALUE or NAME:VALUE", spec)
+		}
+		headers[http.CanonicalHeaderKey(strings.TrimSpace(spec[:idx]))] = strings.TrimSpace(spec[idx+1:])
+	}
+	return headers, nil
+}
+
+// parseQuery parses query parameter specifications in the form NAME=VALUE, returning them URL-encoded
+func parseQuery(specs []string) ([]string, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	query := []string{}
+	for _, spec := range specs {
+		name, value, found := strings.Cut(spec, "=")
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid query parameter %q; must be in the form NAME=VALUE", spec)
+		}
+		query = append(query, url.QueryEscape(name)+"="+url.QueryEscape(value))
+	}
+	return query, nil
+}
+
+// readBody reads the request body from a file or stdin ("-"). Unless a Content-Type header is
+// provided, the body must be JSON and is parsed, so that it is sent as JSON.
+func readBody(cmd *cobra.Command, input string, headers map[string]string) (any, error) {
+	var data []byte
+	var err error
+	if input == "-" {
+		data, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		data, err = os.ReadFile(input)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %v", err)
+	}
+
+	if headers["Content-Type"] != "" {
+		return data, nil // sent as is
+	}
+
+	var body any
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("failed to parse request body as JSON: %v; provide a Content-Type header to send a non-JSON body", err)
+	}
+	return body, nil
+}
@@ -0,0 +1,148 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apicall
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+func TestParseHeaders(t *testing.T) {
+	headers, err := parseHeaders([]string{"layer-type=TENANT", "content-type: text/plain", "X-Value: a=b"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"Layer-Type":   "TENANT",
+		"Content-Type": "text/plain",
+		"X-Value":      "a=b",
+	}, headers)
+
+	_, err = parseHeaders([]string{"no-separator"})
+	assert.Error(t, err)
+	_, err = parseHeaders([]string{"=value"})
+	assert.Error(t, err)
+}
+
+func TestParseQuery(t *testing.T) {
+	query, err := parseQuery([]string{"max=10", "filter=name eq \"a&b\""})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"max=10", "filter=name+eq+%22a%26b%22"}, query)
+
+	_, err = parseQuery([]string{"max"})
+	assert.Error(t, err)
+}
+
+func TestCheckArgs(t *testing.T) {
+	cmd := &cobra.Command{}
+	cmd.Flags().StringSlice("profiles", nil, "")
+	cmd.Flags().Bool("all-profiles", false, "")
+	assert.NoError(t, checkArgs(cmd, []string{"POST", "/things"}))
+	assert.Error(t, checkArgs(cmd, []string{"GET"}))
+
+	// only read-only requests for multiple profiles
+	require.Nil(t, cmd.Flags().Set("profiles", "dev,prod"))
+	assert.NoError(t, checkArgs(cmd, []string{"get", "/things"}))
+	assert.NoError(t, checkArgs(cmd, []string{"HEAD", "/things"}))
+	assert.ErrorContains(t, checkArgs(cmd, []string{"post", "/things"}), "only GET and HEAD")
+	assert.ErrorContains(t, checkArgs(cmd, []string{"DELETE", "/things/1"}), "only GET and HEAD")
+}
+
+func TestReadBody(t *testing.T) {
+	cmd := &cobra.Command{}
+
+	cmd.SetIn(strings.NewReader(`{"name": "test"}`))
+	body, err := readBody(cmd, "-", nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"name": "test"}, body)
+
+	cmd.SetIn(strings.NewReader("not json"))
+	_, err = readBody(cmd, "-", nil)
+	assert.Error(t, err)
+
+	cmd.SetIn(strings.NewReader("not json"))
+	body, err = readBody(cmd, "-", map[string]string{"Content-Type": "text/plain"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("not json"), body)
+}
+
+func TestApiCall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/text":
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("plain text"))
+		case "/html":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte("<html><body>ok</body></html>\n"))
+		case "/json":
+			assert.Equal(t, "10", r.URL.Query().Get("max"))
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"id":"a","count":2}`))
+		case "/echo":
+			data, _ := io.ReadAll(r.Body)
+			assert.Equal(t, "text/plain", r.Header.Get("Content-Type"))
+			assert.Equal(t, "hello", string(data))
+			w.WriteHeader(http.StatusAccepted) // no body
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	config.SetEphemeralContext(&config.Context{Name: "test", AuthMethod: config.AuthMethodNone, URL: server.URL})
+	defer config.SetEphemeralContext(nil)
+
+	cmd := NewSubCmd()
+	cmd.Flags().String("output", "json", "")
+	run := func(args ...string) string {
+		out := &bytes.Buffer{}
+		cmd.SetOut(out)
+		require.Nil(t, apiCall(cmd, args))
+		return out.String()
+	}
+
+	// non-JSON responses are displayed as is
+	assert.Equal(t, "plain text\n", run("GET", "/text"))
+	assert.Equal(t, "<html><body>ok</body></html>\n", run("get", server.URL+"/html"))
+
+	// JSON responses are displayed in the selected output format
+	require.Nil(t, cmd.Flags().Set("query", "max=10"))
+	var value map[string]any
+	require.Nil(t, json.Unmarshal([]byte(run("GET", "/json")), &value))
+	assert.Equal(t, map[string]any{"id": "a", "count": 2.0}, value)
+
+	// bodies are sent as is with a content type; empty responses display nothing
+	require.Nil(t, cmd.Flags().Lookup("query").Value.(pflag.SliceValue).Replace(nil))
+	cmd.SetIn(strings.NewReader("hello"))
+	require.Nil(t, cmd.Flags().Set("input", "-"))
+	require.Nil(t, cmd.Flags().Set("header", "Content-Type=text/plain"))
+	assert.Equal(t, "", run("POST", "/echo"))
+
+	// failures are returned for classifying them
+	require.Nil(t, cmd.Flags().Set("input", ""))
+	err := apiCall(cmd, []string{"GET", "/missing"})
+	assert.Equal(t, api.ErrorKindNotFound, api.ErrorKindOf(err))
+}
@@ -36,7 +36,9 @@ func NewSubCmd() *cobra.Command {
   fsoc config set auth=service-principal secret-file=my-svc-principal.json --profile ci
   fsoc config get -o yaml
   fsoc config use ci
-  fsoc config delete ci`,
+  fsoc config delete ci
+  fsoc config export ci --file ci-profile.yaml
+  fsoc config import ci-profile.yaml --conflict rename`,
 		TraverseChildren: true,
 	}
 
@@ -46,6 +48,9 @@ func NewSubCmd() *cobra.Command {
 	cmd.AddCommand(newCmdConfigList())
 	cmd.AddCommand(newCmdConfigDelete())
 	cmd.AddCommand(newCmdConfigShowFields())
+	cmd.AddCommand(newCmdConfigMigrate())
+	cmd.AddCommand(newCmdConfigExport())
+	cmd.AddCommand(newCmdConfigImport())
 
 	return cmd
 }
@@ -58,6 +63,7 @@ func GetAuthMethodsStringList() []string {
 		cfg.AuthMethodServicePrincipal,
 		cfg.AuthMethodAgentPrincipal,
 		cfg.AuthMethodJWT,
+		cfg.AuthMethodSessionManager,
 		cfg.AuthMethodLocal,
 	}
 }
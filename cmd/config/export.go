@@ -0,0 +1,113 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	cfg "github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+)
+
+const exportLong = `Export one or more profiles into a portable YAML or JSON bundle, e.g., to share them with a teammate,
+who can add them to their config file with "fsoc config import".
+
+By default, the current profile is exported. Secrets are not exported unless --include-secrets is specified: the
+access and refresh tokens are removed, while tokens provided by the user (for the "jwt" auth method) and the
+contents of secret files are replaced with the ` + cfg.RedactedValue + ` placeholder. With --include-secrets, the
+tokens and the contents of the secret files are included in the bundle, which must then be kept safe. Credential
+helper commands are exported only with --include-secrets, too.
+
+A profile that inherits from a parent profile keeps the inheritance if the parent is exported too; otherwise, it
+is exported with all of its effective settings. The bundle is in JSON if -o json is specified or if the file
+name ends with .json, in YAML otherwise.`
+
+func newCmdConfigExport() *cobra.Command {
+
+	var cmd = &cobra.Command{
+		Use:   "export [PROFILE]... [--all] [--file FILE] [--include-secrets]",
+		Short: "Export profiles into a portable bundle",
+		Long:  exportLong,
+		Example: `  fsoc config export prod dev --file team-profiles.yaml
+  fsoc config export --all -o json > profiles.json
+  fsoc config export ci --include-secrets --file ci-profile.yaml`,
+		ValidArgsFunction: validArgsAutocomplete,
+		Run:               configExport,
+	}
+	cmd.Flags().Bool("all", false, "Export all profiles")
+	cmd.Flags().String("file", "", "File to write the bundle into (default is stdout)")
+	cmd.Flags().Bool("include-secrets", false, "Include the tokens and the contents of the secret files in the bundle")
+	return cmd
+}
+
+func configExport(cmd *cobra.Command, args []string) {
+	all, _ := cmd.Flags().GetBool("all")
+	file, _ := cmd.Flags().GetString("file")
+	includeSecrets, _ := cmd.Flags().GetBool("include-secrets")
+
+	// select the profiles
+	names := args
+	if all {
+		if len(args) > 0 {
+			log.Fatalf("Profile names cannot be specified with --all")
+		}
+		names = cfg.ListAllContexts()
+	} else if len(names) == 0 {
+		names = []string{cfg.GetCurrentProfileName()}
+	}
+	bundle, err := cfg.ExportProfiles(names, includeSecrets)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	// encode the bundle
+	format, _ := cmd.Flags().GetString("output")
+	if (format == "" || format == "auto") && strings.HasSuffix(strings.ToLower(file), ".json") {
+		format = "json"
+	}
+	var data []byte
+	switch format {
+	case "json":
+		data, err = json.MarshalIndent(bundle, "", "  ")
+		data = append(data, '\n')
+	case "", "auto", "yaml":
+		data, err = yaml.Marshal(bundle)
+	default:
+		log.Fatalf("Unsupported output format %q for exporting profiles; use yaml or json", format)
+	}
+	if err != nil {
+		log.Fatalf("Failed to encode the profiles: %v", err)
+	}
+
+	// write the bundle
+	if includeSecrets {
+		log.Warnf("The exported profiles include secrets; keep the bundle safe")
+	}
+	if file == "" {
+		fmt.Fprint(cmd.OutOrStdout(), string(data))
+		return
+	}
+	if err := os.WriteFile(file, data, 0600); err != nil {
+		log.Fatalf("Failed to write the profiles into %q: %v", file, err)
+	}
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Exported %d profile(s) into %q\n", len(bundle.Contexts), file))
+}
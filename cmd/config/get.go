@@ -82,15 +82,37 @@ func outputContext(cmd *cobra.Command, context *cfg.Context, useIndicator string
 			values = append(values, value)
 		}
 	}
-	appendIfPresent("Auth Method", ctx.AuthMethod)
-	appendIfPresent("URL", ctx.URL)
-	appendIfPresent("Tenant", ctx.Tenant)
+	// annotate the values inherited from parent profiles with their origin
+	origins := map[string]string{}
+	if ctx.Parent != "" {
+		if _, o, err := cfg.ResolveContext(ctx.Name); err == nil {
+			origins = o
+		}
+	}
+	appendSetting := func(header, value string, paths ...string) {
+		if value != "" {
+			appendIfPresent(header, value+inheritedFrom(origins, ctx.Name, paths...))
+		}
+	}
+
+	appendIfPresent("Parent", ctx.Parent)
+	appendSetting("Auth Method", ctx.AuthMethod, "auth_method")
+	appendSetting("URL", ctx.URL, "url")
+	appendSetting("Tenant", ctx.Tenant, "tenant")
 	appendIfPresent("User ID", ctx.User)
 	appendIfPresent("Token", ctx.Token)
 	appendIfPresent("Refresh Token", ctx.RefreshToken)
-	appendIfPresent("Secret File", ctx.SecretFile)
-	appendIfPresent("Environment", humanizeEnvType(ctx.EnvType))
-	appendIfPresent("Local Auth", ctx.LocalAuthOptions.String())
+	appendIfPresent("Token Expiry", ctx.TokenExpiry)
+	appendSetting("Secret File", ctx.SecretFile, "secret_file", "csv_file")
+	appendSetting("Session Manager", ctx.SessionManagerURL, "session_manager_url")
+	appendSetting("Environment", humanizeEnvType(ctx.EnvType), "env_type")
+	appendSetting("Local Auth", ctx.LocalAuthOptions.String(), "auth-options")
+	appendSetting("Retry", ctx.RetryOptions.String(), "retry")
+	appendSetting("Token Refresh Skew", ctx.TokenRefreshSkew, "token_refresh_skew")
+	appendSetting("Transport", ctx.TransportOptions.String(), "transport")
+	appendSetting("Tracing", ctx.TracingOptions.String(), "tracing")
+	appendSetting("Headers", ctx.HeaderOptions.String(), "headers")
+	appendSetting("Secrets", cfg.SecretsLocation(&ctx), "credential_store", "credential_helper")
 
 	if ctx.SubsystemConfigs != nil && len(ctx.SubsystemConfigs) > 0 {
 		// get sorted list of subsystems
@@ -116,7 +138,9 @@ func outputContext(cmd *cobra.Command, context *cfg.Context, useIndicator string
 			}
 
 			// produce single line config for the subsystem
-			values := formatSubsystemConfig(ctx.SubsystemConfigs[name])
+			values := formatSubsystemConfig(ctx.SubsystemConfigs[name], func(setting string) string {
+				return inheritedFrom(origins, ctx.Name, "subsystems."+name+"."+setting)
+			})
 			appendIfPresent(fmt.Sprintf("\t%c %*s", graph, width, name), values) // tab indents unlike spaces
 		}
 	}
@@ -141,20 +165,48 @@ func humanizeEnvType(val string) string {
 	}
 }
 
-func formatSubsystemConfig(config map[string]any) string {
+func formatSubsystemConfig(config map[string]any, note func(setting string) string) string {
 	if len(config) == 0 {
 		return "(empty)" // shouldn't happen but provide for it if it does
 	}
 
 	params := []string{}
 	for name, value := range config {
-		params = append(params, fmt.Sprintf("%v=%v", name, subsystemValue(value)))
+		params = append(params, fmt.Sprintf("%v=%v%v", name, subsystemValue(value), note(name)))
 	}
 
 	// TODO: ellide if too long
 	return strings.Join(params, " ")
 }
 
+// inheritedFrom returns a note naming the profile(s) that a displayed value is inherited from, given
+// the paths of the settings that make up the value (see cfg.ResolveContext), or an empty string if the
+// value is the profile's own
+func inheritedFrom(origins map[string]string, profile string, paths ...string) string {
+	own := false
+	parents := []string{}
+	for path, origin := range origins {
+		for _, prefix := range paths {
+			if path != prefix && !strings.HasPrefix(path, prefix+".") {
+				continue
+			}
+			if origin == profile {
+				own = true
+			} else if !slices.Contains(parents, origin) {
+				parents = append(parents, origin)
+			}
+		}
+	}
+	if len(parents) == 0 {
+		return ""
+	}
+	slices.Sort(parents)
+	if own {
+		return fmt.Sprintf(" (partly from %v)", strings.Join(parents, ", "))
+	}
+	return fmt.Sprintf(" (from %v)", strings.Join(parents, ", "))
+}
+
 func subsystemValue(v any) string {
 	// format to string using Go default value formatting
 	val := fmt.Sprintf("%v", v)
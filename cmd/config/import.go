@@ -0,0 +1,119 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	cfg "github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+)
+
+const importLong = `Import profiles from a bundle created with "fsoc config export" (or from another fsoc config file) into
+the fsoc config file, creating the config file if needed.
+
+Each profile is checked with the same checks as "fsoc config set" before anything is imported. If a profile with
+the same name already exists, the import fails unless --conflict is specified: "rename" imports the profile under
+a new name (e.g., prod-2, updating the profiles that inherit from it) and "overwrite" replaces the existing profile.
+
+Secret files included in the bundle are saved next to the config file, in the CONFIG_FILE.secrets directory.
+Secrets that were not exported need to be provided again, e.g., with "fsoc config set"; fsoc lists them.
+
+Credential helper commands are not imported unless --allow-credential-helper is specified, since fsoc runs them
+when the profile is used; review the bundle before allowing them.`
+
+func newCmdConfigImport() *cobra.Command {
+
+	var cmd = &cobra.Command{
+		Use:   "import FILE [--conflict fail|rename|overwrite] [--dry-run] [--allow-credential-helper]",
+		Short: "Import profiles from a bundle",
+		Long:  importLong,
+		Example: `  fsoc config import team-profiles.yaml
+  fsoc config import team-profiles.yaml --conflict rename --dry-run
+  cat profiles.json | fsoc config import - --conflict overwrite`,
+		Args:        cobra.ExactArgs(1),
+		Annotations: map[string]string{cfg.AnnotationForConfigBypass: ""},
+		Run:         configImport,
+	}
+	cmd.Flags().String("conflict", cfg.ImportConflictFail, fmt.Sprintf("What to do with profiles that already exist, one of {%v}", strings.Join(cfg.GetImportConflictPolicies(), ", ")))
+	cmd.Flags().Bool("dry-run", false, "Check the profiles and show how they would be imported, without modifying the config file")
+	cmd.Flags().Bool("allow-credential-helper", false, "Import the profiles' credential helper commands, which run when the profiles are used")
+	_ = cmd.RegisterFlagCompletionFunc("conflict", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return cfg.GetImportConflictPolicies(), cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return cmd
+}
+
+func configImport(cmd *cobra.Command, args []string) {
+	conflict, _ := cmd.Flags().GetString("conflict")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	allowCredentialHelper, _ := cmd.Flags().GetBool("allow-credential-helper")
+
+	// read the bundle
+	file := args[0]
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		log.Fatalf("Failed to read profiles from %q: %v", file, err)
+	}
+	bundle, err := cfg.ReadProfileBundle(data, file)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	// import the profiles
+	validate := func(ctx *cfg.Context) error {
+		return validateContext(cmd, ctx)
+	}
+	results, err := cfg.ImportProfiles(bundle, conflict, validate, allowCredentialHelper, dryRun)
+	if err != nil {
+		log.Fatalf("Failed to import profiles: %v", err)
+	}
+
+	// report the results
+	var sb strings.Builder
+	for _, result := range results {
+		switch {
+		case result.Replaced:
+			fmt.Fprintf(&sb, "Replaced profile %q\n", result.ImportedAs)
+		case result.ImportedAs != result.Name:
+			fmt.Fprintf(&sb, "Imported profile %q as %q\n", result.Name, result.ImportedAs)
+		default:
+			fmt.Fprintf(&sb, "Imported profile %q\n", result.ImportedAs)
+		}
+		if result.SecretFile != "" {
+			fmt.Fprintf(&sb, "  saved the secret file as %q\n", result.SecretFile)
+		}
+		for _, warning := range result.Warnings {
+			fmt.Fprintf(&sb, "  %v\n", warning)
+		}
+	}
+	if dryRun {
+		fmt.Fprintf(&sb, "Dry run: the config file was not modified\n")
+	}
+	output.PrintCmdStatus(cmd, sb.String())
+}
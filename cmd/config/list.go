@@ -30,7 +30,8 @@ const tableFieldSpec = `` +
 	`name:.name, ` +
 	`auth_method:(if .auth_method == "" then "-" else .auth_method end), ` +
 	`url:(if .url == "" then "-" else .url end), ` +
-	`env_type:(if .env_type == null then "" else .env_type end)`
+	`env_type:(if .env_type == null then "" else .env_type end), ` +
+	`secrets:.secrets`
 
 func newCmdConfigList() *cobra.Command {
 
@@ -61,7 +62,7 @@ func configListContexts(cmd *cobra.Command, args []string) error {
 
 	contextList := []map[string]any{}
 	for _, name := range profiles {
-		context, err := cfg.GetContext(name)
+		context, err := cfg.GetStoredContext(name) // no need to access credential stores to list profiles
 		if err != nil {
 			log.Warnf("(bug?) can't find listed context %q: %v; skipping", name, err)
 			continue
@@ -88,8 +89,9 @@ func configListContexts(cmd *cobra.Command, args []string) error {
 			continue
 		}
 
-		// add the use indicator and append the entry
+		// add the use indicator and secrets location, and append the entry
 		cMap["use"] = use
+		cMap["secrets"] = cfg.SecretsLocation(context)
 		contextList = append(contextList, cMap)
 	}
 
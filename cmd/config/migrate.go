@@ -0,0 +1,86 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	cfg "github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+)
+
+const migrateLong = `Upgrade the fsoc config file to the schema version used by this version of fsoc.
+
+fsoc upgrades config files automatically when it reads them, so this command is needed mostly to review the
+changes ahead of time, with --dry-run, or to record the current schema version in the file. The file is backed up
+(as FILE.vN.bak, where N is the file's previous schema version) before it is rewritten. Config files written by a
+newer version of fsoc are never modified.`
+
+func newCmdConfigMigrate() *cobra.Command {
+
+	var cmd = &cobra.Command{
+		Use:   "migrate [--dry-run]",
+		Short: "Upgrade the fsoc config file to the current schema version",
+		Long:  migrateLong,
+		Example: `  fsoc config migrate --dry-run
+  fsoc config migrate --config ~/.fsoc-ci`,
+		Args: cobra.NoArgs,
+		Annotations: map[string]string{
+			cfg.AnnotationForConfigBypass:        "",
+			cfg.AnnotationForSchemaUpgradeBypass: "",
+		},
+		Run: configMigrate,
+	}
+	cmd.Flags().Bool("dry-run", false, "Show the pending migrations and their changes without modifying the config file")
+
+	return cmd
+}
+
+func configMigrate(cmd *cobra.Command, args []string) {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	report, err := cfg.MigrateConfigFile(dryRun)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	if report.FromVersion == report.ToVersion {
+		output.PrintCmdStatus(cmd, fmt.Sprintf("Config file %q is up to date (schema version %d)\n", report.File, report.ToVersion))
+		return
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Config file %q: schema version %d, current version %d\n", report.File, report.FromVersion, report.ToVersion)
+	for _, m := range report.Migrations {
+		fmt.Fprintf(&sb, "  %d: %v\n", m.Version, m.Description)
+		if len(m.Changes) == 0 {
+			fmt.Fprintf(&sb, "       (no changes needed)\n")
+		}
+		for _, change := range m.Changes {
+			fmt.Fprintf(&sb, "       %v\n", change)
+		}
+	}
+	if dryRun {
+		fmt.Fprintf(&sb, "Dry run: the config file was not modified\n")
+	} else if report.Backup != "" {
+		fmt.Fprintf(&sb, "Config file upgraded to schema version %d; the previous version was saved as %q\n", report.ToVersion, report.Backup)
+	} else {
+		fmt.Fprintf(&sb, "Config file upgraded to schema version %d\n", report.ToVersion)
+	}
+	output.PrintCmdStatus(cmd, sb.String())
+}
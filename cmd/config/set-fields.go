@@ -89,6 +89,19 @@ func getAuthFieldWritePermissions() map[string]AuthFieldConfigRow {
 			cfg.AppdPty:     ClearField,
 			cfg.AppdPid:     ClearField,
 		},
+		cfg.AuthMethodSessionManager: {
+			"client-ID":           ClearField,
+			"secret-file":         AllowField,
+			"token":               ClearField,
+			"tenant":              AllowField,
+			"url":                 AllowField,
+			"refresh-token":       ClearField,
+			"user":                ClearField,
+			"session-manager-url": AllowField,
+			cfg.AppdTid:           ClearField,
+			cfg.AppdPty:           ClearField,
+			cfg.AppdPid:           ClearField,
+		},
 		cfg.AuthMethodLocal: {
 			"client-ID":     ClearField,
 			"secret-file":   ClearField,
@@ -169,6 +182,19 @@ func getAuthFieldClearConfig() map[string]authClearFields {
 			cfg.AppdPty:     {},
 			cfg.AppdPid:     {},
 		},
+		cfg.AuthMethodSessionManager: {
+			"client-ID":           {},
+			"secret-file":         {"user", "token", "refresh-token"},
+			"token":               {},
+			"tenant":              {"user", "token", "refresh-token"},
+			"url":                 {"tenant", "user", "token", "refresh-token"},
+			"refresh-token":       {},
+			"user":                {},
+			"session-manager-url": {"user", "token", "refresh-token"},
+			cfg.AppdTid:           {},
+			cfg.AppdPty:           {},
+			cfg.AppdPid:           {},
+		},
 		cfg.AuthMethodLocal: {
 			"client-ID":     {},
 			"secret-file":   {},
@@ -21,6 +21,8 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/apex/log"
@@ -29,6 +31,7 @@ import (
 	"golang.org/x/exp/slices"
 
 	cfg "github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/tracing"
 )
 
 var (
@@ -48,6 +51,7 @@ Specifying a profile name that doesn't exist will create a new profile and set t
 
 To see the list of configuration settings supported by fsoc, use the "fsoc config show-fields" command.	
 Note that each authentication method requires a slightly different set of values, see examples below.
+The "session-manager" authentication method is not supported yet: its settings are accepted, but login fails.
 `
 
 	setContextExample = `
@@ -62,6 +66,15 @@ Note that each authentication method requires a slightly different set of values
   # Set local access
   fsoc config set auth=local url=http://localhost appd-pid=PID appd-tid=TID appd-pty=PTY
   
+  # Add default headers to all requests and to the requests of the knowledge subsystem (values may reference env vars)
+  fsoc config set header.X-Team=observability knowledge.header.X-Api-Key='${KNOWLEDGE_API_KEY}'
+
+  # Create a profile that inherits all settings from the "prod" profile, except for the tenant
+  fsoc config set --profile prod-eu parent=prod tenant=654321
+
+  # Override an inherited setting with an empty or false value
+  fsoc config set --profile prod-eu insecure=false
+
   # Set the token field on the "prod" context entry without touching other values
   fsoc config set profile prod token=top-secret --patch
  
@@ -73,17 +86,18 @@ Note that each authentication method requires a slightly different set of values
 // configArgs are the positional arguments of form <name>=<value> that can be set.
 // They also correspond to the --flags for the same, for backward compatibility (deprecated)
 // The order here is how the fields are displayed in `config show-help` topic
-var configArgs = []string{"auth", "url", "tenant", "secret-file", "envtype", "token", cfg.AppdTid, cfg.AppdPty, cfg.AppdPid, "server"}
+var configArgs = []string{"auth", "url", "tenant", "secret-file", "session-manager-url", "envtype", "token", cfg.AppdTid, cfg.AppdPty, cfg.AppdPid, "retries", "retry-max-delay", "token-refresh-skew", "credential-store", "credential-helper", "proxy", "ca-cert", "client-cert", "client-key", "insecure", "trace-exporter", "trace-endpoint", "trace-file", "parent", "server"}
 
 func newCmdConfigSet() *cobra.Command {
 
 	var cmd = &cobra.Command{
-		Use:         "set [--config CONFIG_FILE] [--profile CONTEXT] [SETTING=VALUE]+",
-		Short:       "Create or modify a context entry in an fsoc config file",
-		Long:        setContextLong,
-		Example:     setContextExample,
-		Annotations: map[string]string{cfg.AnnotationForConfigBypass: ""},
-		Run:         configSetContext,
+		Use:               "set [--config CONFIG_FILE] [--profile CONTEXT] [SETTING=VALUE]+",
+		Short:             "Create or modify a context entry in an fsoc config file",
+		Long:              setContextLong,
+		Example:           setContextExample,
+		Annotations:       map[string]string{cfg.AnnotationForConfigBypass: ""},
+		Run:               configSetContext,
+		ValidArgsFunction: configSetAutocomplete,
 	}
 
 	// real command flag(s)
@@ -111,6 +125,38 @@ func newCmdConfigSet() *cobra.Command {
 	cmd.Flags().String("envtype", "", "envtype can be \"dev\", \"prod\", or \"\". When it is \"dev\", solution tags will always be set to stable")
 	_ = cmd.Flags().MarkDeprecated("envtype", `please use non-flag argument in the form "envtype=ENVTYPE"`)
 
+	// hidden flags for settings that are available only as arguments
+	cmd.Flags().String("retries", "", "Maximum number of retries for throttled or failed platform API calls")
+	_ = cmd.Flags().MarkHidden("retries")
+	cmd.Flags().String("retry-max-delay", "", "Maximum delay between retries of platform API calls")
+	_ = cmd.Flags().MarkHidden("retry-max-delay")
+	cmd.Flags().String("session-manager-url", "", "Token endpoint of the session manager")
+	_ = cmd.Flags().MarkHidden("session-manager-url")
+	cmd.Flags().String("token-refresh-skew", "", "How long before its expiry an access token is refreshed")
+	_ = cmd.Flags().MarkHidden("token-refresh-skew")
+	cmd.Flags().String("credential-store", "", "Where to keep the profile's access and refresh tokens")
+	_ = cmd.Flags().MarkHidden("credential-store")
+	cmd.Flags().String("credential-helper", "", "Credential helper command for the helper credential store")
+	_ = cmd.Flags().MarkHidden("credential-helper")
+	cmd.Flags().String("proxy", "", "Proxy URL for connecting to the platform")
+	_ = cmd.Flags().MarkHidden("proxy")
+	cmd.Flags().String("ca-cert", "", "PEM file with additional CA certificates to trust")
+	_ = cmd.Flags().MarkHidden("ca-cert")
+	cmd.Flags().String("client-cert", "", "PEM file with a client certificate for mTLS")
+	_ = cmd.Flags().MarkHidden("client-cert")
+	cmd.Flags().String("client-key", "", "PEM file with the client certificate's private key")
+	_ = cmd.Flags().MarkHidden("client-key")
+	cmd.Flags().String("insecure", "", "Skip verification of the server certificate (true/false)")
+	_ = cmd.Flags().MarkHidden("insecure")
+	cmd.Flags().String("trace-exporter", "", "Where to export the traces of fsoc's own operations")
+	_ = cmd.Flags().MarkHidden("trace-exporter")
+	cmd.Flags().String("trace-endpoint", "", "OTLP/HTTP endpoint to export the traces to")
+	_ = cmd.Flags().MarkHidden("trace-endpoint")
+	cmd.Flags().String("trace-file", "", "File to export the traces to")
+	_ = cmd.Flags().MarkHidden("trace-file")
+	cmd.Flags().String("parent", "", "Profile to inherit settings from")
+	_ = cmd.Flags().MarkHidden("parent")
+
 	return cmd
 }
 
@@ -151,27 +197,37 @@ func configSetContext(cmd *cobra.Command, args []string) {
 
 	patch, _ := cmd.Flags().GetBool("patch")
 
+	// inherit from the parent profile first, so that the other settings apply on top of the inherited ones
+	if flags.Changed("parent") {
+		val, _ := flags.GetString("parent")
+		ctxPtr, err = cfg.SetParent(ctxPtr, val)
+		if err != nil {
+			log.Fatalf("Invalid parent profile %q: %v", val, err)
+		}
+	}
+
 	// update only the fields for which flags were specified explicitly
 	// (and, force-clear dependent/auto-derived fields unless --patch)
 
 	if flags.Changed("auth") {
 		val, _ := flags.GetString("auth")
-		if val != "" && !slices.Contains(GetAuthMethodsStringList(), val) {
-			log.Fatalf(`Invalid --auth method %q; must be one of {"%v"}`, val, strings.Join(GetAuthMethodsStringList(), `", "`))
+		if val != "" {
+			if err := validateAuthMethod(val); err != nil {
+				log.Fatalf("%v", err)
+			}
 		}
 		ctxPtr.AuthMethod = val
 
 		// Clear All fields before setting other fields
 		if !patch {
-			clearFields([]string{"url", "server", "tenant", "user", "token", "refresh_token", "secret-file"}, ctxPtr)
+			clearFields([]string{"url", "server", "tenant", "user", "token", "refresh_token", "secret-file", "session-manager-url"}, ctxPtr)
 		}
 	}
 
 	if flags.Changed("envtype") {
 		val, _ := flags.GetString("envtype")
-		potentialEnvTypes := []string{"prod", "dev"}
-		if !slices.Contains(potentialEnvTypes, val) {
-			log.Fatalf("envtype can only take on one of the following values: %s", strings.Join(potentialEnvTypes, ", "))
+		if err := validateEnvType(val); err != nil {
+			log.Fatalf("%v", err)
 		}
 		ctxPtr.EnvType = val
 	}
@@ -232,6 +288,7 @@ func configSetContext(cmd *cobra.Command, args []string) {
 		} else {
 			ctxPtr.Token = value
 		}
+		ctxPtr.TokenExpiry = "" // unknown for tokens provided by the user
 		if !patch {
 			automatedFieldClearing(ctxPtr, "token")
 		}
@@ -255,6 +312,23 @@ func configSetContext(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	if flags.Changed("session-manager-url") {
+		err := validateWriteReq(cmd, ctxPtr.AuthMethod, "session-manager-url")
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		val, _ := flags.GetString("session-manager-url")
+		if val != "" {
+			if err := validateHTTPURL("session-manager-url", val, "http://localhost:8200/token"); err != nil {
+				log.Fatalf("%v", err)
+			}
+		}
+		ctxPtr.SessionManagerURL = val
+		if !patch {
+			automatedFieldClearing(ctxPtr, "session-manager-url")
+		}
+	}
+
 	// populate fields for local auth
 	if ctxPtr.AuthMethod == cfg.AuthMethodLocal {
 		if flags.Changed(cfg.AppdPid) {
@@ -292,12 +366,135 @@ func configSetContext(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	// populate retry options (applicable to all auth methods)
+	if flags.Changed("retries") {
+		val, _ := flags.GetString("retries")
+		if val == "" {
+			ctxPtr.RetryOptions.MaxRetries = nil // revert to default
+		} else {
+			retries, err := strconv.Atoi(val)
+			if err != nil {
+				log.Fatalf("Invalid retries value %q: must be a non-negative integer (0 disables retries)", val)
+			}
+			if err := validateRetries(retries); err != nil {
+				log.Fatalf("%v", err)
+			}
+			ctxPtr.RetryOptions.MaxRetries = &retries
+		}
+	}
+	if flags.Changed("retry-max-delay") {
+		val, _ := flags.GetString("retry-max-delay")
+		if val != "" {
+			if err := validateRetryMaxDelay(val); err != nil {
+				log.Fatalf("%v", err)
+			}
+		}
+		ctxPtr.RetryOptions.MaxDelay = val
+	}
+
+	// populate token refresh skew (applicable to all auth methods that can refresh tokens)
+	if flags.Changed("token-refresh-skew") {
+		val, _ := flags.GetString("token-refresh-skew")
+		if val != "" {
+			if err := validateTokenRefreshSkew(val); err != nil {
+				log.Fatalf("%v", err)
+			}
+		}
+		ctxPtr.TokenRefreshSkew = val
+	}
+
+	// populate credential store settings (the secrets are moved to the new store when the profile is saved)
+	if flags.Changed("credential-store") {
+		val, _ := flags.GetString("credential-store")
+		ctxPtr.CredentialStore = val
+	}
+	if flags.Changed("credential-helper") {
+		val, _ := flags.GetString("credential-helper")
+		ctxPtr.CredentialHelper = val
+	}
+	if err := validateCredentialStore(ctxPtr.CredentialStore, ctxPtr.CredentialHelper); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	// populate transport options (applicable to all auth methods)
+	if flags.Changed("proxy") {
+		val, _ := flags.GetString("proxy")
+		if val != "" {
+			if err := validateProxy(val); err != nil {
+				log.Fatalf("%v", err)
+			}
+		}
+		ctxPtr.TransportOptions.Proxy = val
+	}
+	if flags.Changed("ca-cert") {
+		ctxPtr.TransportOptions.CACertFile = transportFileSetting(flags, "ca-cert")
+	}
+	if flags.Changed("client-cert") {
+		ctxPtr.TransportOptions.ClientCertFile = transportFileSetting(flags, "client-cert")
+	}
+	if flags.Changed("client-key") {
+		ctxPtr.TransportOptions.ClientKeyFile = transportFileSetting(flags, "client-key")
+	}
+	if flags.Changed("insecure") {
+		val, _ := flags.GetString("insecure")
+		insecure := false
+		if val != "" {
+			insecure, err = strconv.ParseBool(val)
+			if err != nil {
+				log.Fatalf("Invalid insecure value %q: must be true or false", val)
+			}
+		}
+		ctxPtr.TransportOptions.InsecureSkipVerify = insecure
+	}
+	if (ctxPtr.TransportOptions.ClientCertFile == "") != (ctxPtr.TransportOptions.ClientKeyFile == "") {
+		log.Warnf("Both client-cert and client-key must be set to use a client certificate")
+	}
+
+	// populate tracing options (applicable to all auth methods)
+	if flags.Changed("trace-exporter") {
+		val, _ := flags.GetString("trace-exporter")
+		if val != "" {
+			if err := validateTraceExporter(val); err != nil {
+				log.Fatalf("%v", err)
+			}
+		}
+		ctxPtr.TracingOptions.Exporter = val
+	}
+	if flags.Changed("trace-endpoint") {
+		val, _ := flags.GetString("trace-endpoint")
+		if val != "" {
+			if err := validateHTTPURL("trace-endpoint", val, tracing.DefaultOTLPEndpoint); err != nil {
+				log.Fatalf("%v", err)
+			}
+		}
+		ctxPtr.TracingOptions.Endpoint = val
+	}
+	if flags.Changed("trace-file") {
+		val, _ := flags.GetString("trace-file")
+		if val != "" {
+			val = expandHomePath(val)
+			if absPath, err := filepath.Abs(val); err == nil {
+				val = absPath
+			}
+		}
+		ctxPtr.TracingOptions.File = val
+	}
+	if err := validateTraceFile(&ctxPtr.TracingOptions); err != nil {
+		log.Fatalf("%v", err)
+	}
+
 	// upgrade config format from CsvFile to SecretFile, opportunistically using the update
 	if ctxPtr.SecretFile == "" && ctxPtr.CsvFile != "" {
 		ctxPtr.SecretFile = ctxPtr.CsvFile
 		ctxPtr.CsvFile = ""
 	}
 
+	// populate default headers (applicable to all auth methods)
+	headerArgs, subsystemSettingArgs := splitHeaderArgs(subsystemSettingArgs)
+	if err := processHeaderSettings(cmd, ctxPtr, headerArgs); err != nil {
+		log.Fatalf("Failed to set default headers: %v", err)
+	}
+
 	// process subsystem-specific settings
 	if err := processSubsystemSettings(ctxPtr, subsystemSettingArgs); err != nil {
 		log.Fatalf("Failed to set subsystem-specific settings: %v", err)
@@ -353,6 +550,7 @@ func clearFields(fields []string, ctxPtr *cfg.Context) {
 	}
 	if slices.Contains(fields, "token") {
 		ctxPtr.Token = ""
+		ctxPtr.TokenExpiry = ""
 	}
 	if slices.Contains(fields, "refresh-token") {
 		ctxPtr.RefreshToken = ""
@@ -360,6 +558,9 @@ func clearFields(fields []string, ctxPtr *cfg.Context) {
 	if slices.Contains(fields, "secret-file") {
 		ctxPtr.SecretFile = ""
 	}
+	if slices.Contains(fields, "session-manager-url") {
+		ctxPtr.SessionManagerURL = ""
+	}
 }
 
 func automatedFieldClearing(ctxPtr *cfg.Context, field string) {
@@ -466,3 +667,165 @@ func processSubsystemSettings(ctx *cfg.Context, args []string) error {
 
 	return nil
 }
+
+// splitHeaderArgs separates the default header settings (header.NAME=VALUE and SUBSYSTEM.header.NAME=VALUE)
+// from the subsystem-specific settings
+func splitHeaderArgs(args []string) (headerArgs []string, remainder []string) {
+	for _, arg := range args {
+		name, _, _ := strings.Cut(arg, "=")
+		nameSegments := strings.Split(name, ".")
+		if nameSegments[0] == "header" || (len(nameSegments) > 2 && nameSegments[1] == "header") {
+			headerArgs = append(headerArgs, arg)
+		} else {
+			remainder = append(remainder, arg)
+		}
+	}
+	return headerArgs, remainder
+}
+
+func processHeaderSettings(cmd *cobra.Command, ctx *cfg.Context, args []string) error {
+	for _, arg := range args {
+		name, value, _ := strings.Cut(arg, "=") // nb: args are already vetted to be in the form KEY=VALUE
+		nameSegments := strings.Split(name, ".")
+
+		// parse subsystem name (none for global headers) and header name
+		var subsystemName, headerName string
+		switch len(nameSegments) {
+		case 2: // header.NAME
+			headerName = nameSegments[1]
+		case 3: // SUBSYSTEM.header.NAME
+			subsystemName, headerName = nameSegments[0], nameSegments[2]
+			if !isSubsystem(cmd, subsystemName) {
+				return fmt.Errorf("error processing argument %q: unknown subsystem %q", arg, subsystemName)
+			}
+		default:
+			return fmt.Errorf("the setting name in argument %q must be in the form header.NAME or SUBSYSTEM.header.NAME", arg)
+		}
+
+		// update (or delete) the header
+		if err := ctx.HeaderOptions.SetHeader(subsystemName, headerName, value); err != nil {
+			return fmt.Errorf("error processing argument %q: %v", arg, err)
+		}
+	}
+	return nil
+}
+
+// configSetAutocomplete completes the SETTING=VALUE arguments: the names of the core and subsystem-specific
+// settings and, once the name is complete, the values of settings that take one of a known set of values
+func configSetAutocomplete(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	name, value, found := strings.Cut(toComplete, "=")
+	if !found {
+		return completeSettingNames(args, toComplete), cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+	}
+
+	completions := []string{}
+	for _, v := range settingValues(name) {
+		if strings.HasPrefix(v, value) {
+			completions = append(completions, name+"="+v)
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeSettingNames returns the setting names (with the "=" that follows them) that start with toComplete
+// and are not already set by the other arguments. Default headers are completed up to the header name.
+func completeSettingNames(args []string, toComplete string) []string {
+	candidates := []string{}
+	for _, name := range configArgs {
+		if name != "server" { // deprecated in favor of url
+			candidates = append(candidates, name+"=")
+		}
+	}
+	candidates = append(candidates, "header.")
+	subsystems := cfg.GetRegisteredSubsystems()
+	slices.Sort(subsystems)
+	for _, subsystemName := range subsystems {
+		settings, err := cfg.GetSubsystemSettings(subsystemName)
+		if err != nil {
+			continue
+		}
+		for _, setting := range settings {
+			candidates = append(candidates, subsystemName+"."+setting.Name+"=")
+		}
+		candidates = append(candidates, subsystemName+".header.")
+	}
+
+	completions := []string{}
+	for _, candidate := range candidates {
+		if !strings.HasPrefix(candidate, toComplete) {
+			continue
+		}
+		if strings.HasSuffix(candidate, "=") && slices.ContainsFunc(args, func(arg string) bool { return strings.HasPrefix(arg, candidate) }) {
+			continue // already set
+		}
+		completions = append(completions, candidate)
+	}
+	return completions
+}
+
+// settingValues returns the known values of a core or subsystem-specific setting, or nil if the setting
+// takes arbitrary values
+func settingValues(name string) []string {
+	switch name {
+	case "auth":
+		return GetAuthMethodsStringList()
+	case "envtype":
+		return envTypes
+	case "credential-store":
+		return cfg.GetCredentialStores()
+	case "trace-exporter":
+		return tracing.Exporters()
+	case "insecure":
+		return []string{"true", "false"}
+	case "parent":
+		return cfg.ListAllContexts()
+	}
+
+	subsystemName, settingName, found := strings.Cut(name, ".")
+	if !found {
+		return nil
+	}
+	settings, err := cfg.GetSubsystemSettings(subsystemName)
+	if err != nil {
+		return nil
+	}
+	for _, setting := range settings {
+		if setting.Name != settingName {
+			continue
+		}
+		if setting.Type.Kind() == reflect.Bool || (setting.Type.Kind() == reflect.Pointer && setting.Type.Elem().Kind() == reflect.Bool) {
+			return []string{"true", "false"}
+		}
+		return setting.Values
+	}
+	return nil
+}
+
+// isSubsystem returns true if name is the name of an fsoc subsystem (top-level command)
+func isSubsystem(cmd *cobra.Command, name string) bool {
+	for _, subsystem := range cmd.Root().Commands() {
+		if subsystem.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// transportFileSetting returns the absolute path of a file setting's value, failing if the file
+// doesn't exist. An empty value clears the setting.
+func transportFileSetting(flags *pflag.FlagSet, name string) string {
+	path, _ := flags.GetString(name)
+	if path == "" {
+		return ""
+	}
+	path = expandHomePath(path)
+	if _, err := os.Stat(path); err != nil {
+		log.Fatalf("Invalid %v file %q: %v", name, path, err)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		log.WithFields(log.Fields{"path": path, "error": err}).Warnf("Failed to convert %v file's path to absolute path; using it as is", name)
+		return path
+	}
+	return absPath
+}
@@ -3,7 +3,10 @@ package config
 import (
 	"testing"
 
+	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
+
+	cfg "github.com/cisco-open/fsoc/config"
 )
 
 func TestValidateUrlWithInvalidURL(t *testing.T) {
@@ -33,3 +36,28 @@ func TestValidateUrlWithValidData(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, url, "http://mytenant.saas.observe.com")
 }
+
+type testCompletionConfig struct {
+	Verbose bool   `mapstructure:"verbose"`
+	Label   string `mapstructure:"label"`
+}
+
+func TestConfigSetAutocomplete(t *testing.T) {
+	_ = cfg.RegisterSubsystemConfigStorage("completionsub", &testCompletionConfig{})
+
+	completions, directive := configSetAutocomplete(nil, []string{"completionsub.label=x"}, "completionsub.")
+	assert.Equal(t, []string{"completionsub.verbose=", "completionsub.header."}, completions)
+	assert.NotZero(t, directive&cobra.ShellCompDirectiveNoSpace)
+
+	completions, _ = configSetAutocomplete(nil, nil, "tr")
+	assert.Equal(t, []string{"trace-exporter=", "trace-endpoint=", "trace-file="}, completions)
+
+	completions, directive = configSetAutocomplete(nil, nil, "completionsub.verbose=t")
+	assert.Equal(t, []string{"completionsub.verbose=true"}, completions)
+	assert.Zero(t, directive&cobra.ShellCompDirectiveNoSpace)
+
+	completions, _ = configSetAutocomplete(nil, nil, "auth=o")
+	assert.Equal(t, []string{"auth=oauth"}, completions)
+	completions, _ = configSetAutocomplete(nil, nil, "completionsub.label=")
+	assert.Empty(t, completions)
+}
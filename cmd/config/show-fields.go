@@ -15,8 +15,8 @@
 package config
 
 import (
+	"fmt"
 	"os"
-	"reflect"
 	"strings"
 
 	"github.com/apex/log"
@@ -25,6 +25,7 @@ import (
 	"golang.org/x/term"
 
 	cfg "github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/tracing"
 )
 
 func newCmdConfigShowFields() *cobra.Command {
@@ -52,16 +53,70 @@ Examples:
 Settings:`
 
 var fieldHelp = map[string]string{
-	"auth":        `authentication method, required. Must be one of "` + strings.Join(GetAuthMethodsStringList(), `", "`) + `".`,
-	"url":         `URL to the tenant, scheme and host/port only; required. For example, https://mytenant.observe.appdynamics.com`,
-	"tenant":      `tenant ID that is required only for auth methods that cannot automatically obtain it. Not needed for the "oauth", "service-principal" and "local" auth methods.`,
-	"secret-file": `file containing login credentials for "service-principal" and "agent-principal" auth methods. The file must remain available, as fsoc saves only the file's path.`,
-	"envtype":     `platform environment type, optional. Used only for special development/test environments. If specified, can be "dev" or "prod".`,
-	"token":       `authentication token needed only for the "token" auth method.`,
-	cfg.AppdTid:   `value of ` + cfg.AppdPid + ` to use with the "local" auth method.`,
-	cfg.AppdPty:   `value of ` + cfg.AppdPid + ` to use with the "local" auth method.`,
-	cfg.AppdPid:   `value of ` + cfg.AppdPid + ` to use with the "local" auth method.`,
-	"server":      `synonym for the "url" setting. Deprecated.`,
+	"auth":                `authentication method, required. Must be one of "` + strings.Join(GetAuthMethodsStringList(), `", "`) + `". The "session-manager" method is not supported yet: its settings are accepted, but login fails.`,
+	"url":                 `URL to the tenant, scheme and host/port only; required. For example, https://mytenant.observe.appdynamics.com`,
+	"tenant":              `tenant ID that is required only for auth methods that cannot automatically obtain it. Not needed for the "oauth", "service-principal" and "local" auth methods.`,
+	"secret-file":         `file containing login credentials for "service-principal" and "agent-principal" auth methods. The file must remain available, as fsoc saves only the file's path. A service principal JSON file may provide a private key ("Key ID" and "Private Key" or "Private Key File") or a list of "Keys" instead of a secret, to log in with a signed JWT client assertion.`,
+	"session-manager-url": `URL of the session manager's token endpoint, required for the "session-manager" auth method (not supported yet). For example, http://localhost:8200/token.`,
+	"envtype":             `platform environment type, optional. Used only for special development/test environments. If specified, can be "dev" or "prod".`,
+	"token":               `authentication token needed only for the "token" auth method.`,
+	cfg.AppdTid:           `value of ` + cfg.AppdPid + ` to use with the "local" auth method.`,
+	cfg.AppdPty:           `value of ` + cfg.AppdPid + ` to use with the "local" auth method.`,
+	cfg.AppdPid:           `value of ` + cfg.AppdPid + ` to use with the "local" auth method.`,
+	"retries":             `maximum number of times a throttled (429) or transiently failed (502, 503, 504, dropped connection) platform API call is retried, optional. Defaults to 3; use 0 to disable retries.`,
+	"retry-max-delay":     `maximum delay between retries of platform API calls, optional. Defaults to 30s. Specified as a duration, e.g., 10s or 2m.`,
+	"token-refresh-skew":  `how long before the access token expires fsoc proactively refreshes it, optional. Defaults to 30s. Applies to the "oauth", "service-principal" and "agent-principal" auth methods.`,
+	"credential-store":    `where the profile's access and refresh tokens are kept, optional. One of "` + strings.Join(cfg.GetCredentialStores(), `", "`) + `". Defaults to "` + cfg.CredentialStoreInline + `" (in the config file). The "` + cfg.CredentialStoreEncryptedFile + `" store keeps them in a file next to the config file, encrypted with a passphrase taken from the ` + cfg.FSOC_CREDENTIALS_PASSPHRASE_ENVVAR + ` environment variable or prompted for.`,
+	"credential-helper":   `command implementing the "` + cfg.CredentialStoreHelper + `" credential store, required for it. The command is invoked with "get", "store" or "erase" as its last argument and exchanges key=value lines (config, profile, token, refresh_token) on stdin/stdout, similar to git credential helpers. Quote paths and arguments that contain spaces with single or double quotes, e.g., credential-helper="'/opt/my tools/helper' --vault fsoc"; other shell features (e.g., variables) are not supported.`,
+	"proxy":               `URL of the proxy to use for connecting to the platform, optional. For example, http://proxy.example.com:8080. By default, the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables are used.`,
+	"ca-cert":             `PEM file with additional CA certificates to trust (e.g., a corporate or internal CA), optional.`,
+	"client-cert":         `PEM file with a client certificate to present to the server (mTLS), optional. Requires client-key.`,
+	"client-key":          `PEM file with the private key of the client certificate, optional. Requires client-cert.`,
+	"insecure":            `set to true to skip verification of the server's TLS certificate, optional. Use only with local development environments.`,
+	"trace-exporter":      `where fsoc exports the traces of its own operations (commands, platform API calls, logins, pages and MELT exports), optional. One of "` + strings.Join(tracing.Exporters(), `", "`) + `". Tracing is disabled by default. Use "` + tracing.ExporterOTLP + `" to send the traces to an OpenTelemetry collector or "` + tracing.ExporterFile + `" to append them to trace-file in the OTLP JSON format.`,
+	"trace-endpoint":      `OTLP/HTTP endpoint for the "` + tracing.ExporterOTLP + `" trace exporter, optional. Defaults to ` + tracing.DefaultOTLPEndpoint + ` (a local collector).`,
+	"trace-file":          `file for the "` + tracing.ExporterFile + `" trace exporter, required for it.`,
+	"parent":              `name of a profile to inherit settings from, optional. The profile uses the parent's settings (incl. subsystem settings and headers, merged setting by setting) unless it sets them itself, and keeps only the settings that differ from the parent's. Tokens are never inherited. Use parent="" to stop inheriting, keeping the inherited settings as the profile's own.`,
+	"server":              `synonym for the "url" setting. Deprecated.`,
+}
+
+// headerFields are the settings for default headers, displayed after the core fields
+var headerFields = []string{"header.NAME", "SUBSYSTEM.header.NAME"}
+
+var headerFieldHelp = map[string]string{
+	"header.NAME":           `default header NAME to add to all platform API requests, optional. The value may reference environment variables as ${VAR}, which are expanded when requests are made; headers whose value expands to an empty string are not sent. Headers given explicitly by a command take precedence.`,
+	"SUBSYSTEM.header.NAME": `default header NAME to add only to the platform API requests made by the SUBSYSTEM's commands (e.g., knowledge.header.NAME or uql.header.NAME), optional. Takes precedence over a global header with the same name. The value may reference environment variables as ${VAR}.`,
+}
+
+const envHelpIntro = `Without a config file, fsoc uses a profile defined by the following environment variables, if
+FSOC_AUTH_METHOD or FSOC_URL is set. The profile is named "` + cfg.EnvProfileName + `" (or as set in FSOC_PROFILE) and is kept
+in memory only: tokens obtained by logging in are not saved. For example:
+  FSOC_AUTH_METHOD=service-principal FSOC_URL=https://mytenant.observe.appdynamics.com \
+    FSOC_SECRET_FILE=credentials.json FSOC_SUBSYSTEM_UQL_APIVER=v1 fsoc uql "FETCH id FROM entities(k8s:workload)"
+
+Environment variables:`
+
+// envSettingFields maps the profile settings, by their path in the config file, to the names used
+// with "config set", for describing the environment variables that define them
+var envSettingFields = map[string]string{
+	"auth_method":                    "auth",
+	"secret_file":                    "secret-file",
+	"env_type":                       "envtype",
+	"auth-options." + cfg.AppdPty:    cfg.AppdPty,
+	"auth-options." + cfg.AppdTid:    cfg.AppdTid,
+	"auth-options." + cfg.AppdPid:    cfg.AppdPid,
+	"retry.max-retries":              "retries",
+	"retry.max-delay":                "retry-max-delay",
+	"token_refresh_skew":             "token-refresh-skew",
+	"session_manager_url":            "session-manager-url",
+	"transport.proxy":                "proxy",
+	"transport.ca-cert-file":         "ca-cert",
+	"transport.client-cert-file":     "client-cert",
+	"transport.client-key-file":      "client-key",
+	"transport.insecure-skip-verify": "insecure",
+	"tracing.exporter":               "trace-exporter",
+	"tracing.endpoint":               "trace-endpoint",
+	"tracing.file":                   "trace-file",
 }
 
 func configShowFields(cmd *cobra.Command, args []string) {
@@ -80,37 +135,54 @@ func configShowFields(cmd *cobra.Command, args []string) {
 		helps = append(helps, help)
 	}
 
+	// add default header fields
+	for _, field := range headerFields {
+		fields = append(fields, field)
+		helps = append(helps, headerFieldHelp[field])
+	}
+
 	// add subsystem-specific configuration fields
 	for _, subsystemName := range cfg.GetRegisteredSubsystems() {
-		// get config template structure for the subsystem
-		template, err := cfg.GetSubsytemConfigTemplate(subsystemName)
+		// get the settings of the subsystem
+		settings, err := cfg.GetSubsystemSettings(subsystemName)
 		if err != nil {
 			log.Warnf("Could not obtain config settings for subsysem %q: %v; skipping subsystem", subsystemName, err)
 			continue
 		}
 
 		// collect field names and helps
-		typ := reflect.TypeOf(template).Elem()
-		for i := 0; i < typ.NumField(); i++ {
-			// get info for the field
-			structField := typ.Field(i)
-			mapstructTag := structField.Tag.Get("mapstructure")
-			fsochelpTag := structField.Tag.Get("fsoc-help")
-			mapstructElems := strings.SplitN(mapstructTag, ",", 2)
-			fieldName := mapstructElems[0]
-			if fieldName == "" {
-				log.Warnf("(bug) cannot find name config field %v; skipping", structField.Name)
-				continue
+		for _, setting := range settings {
+			help := setting.Help
+			if help == "" {
+				help = "(no description available)"
 			}
-			if fsochelpTag == "" {
-				fsochelpTag = "(no description available)"
+			if len(setting.Values) > 0 {
+				help += fmt.Sprintf(` Valid values: "%v".`, strings.Join(setting.Values, `", "`))
 			}
 
 			// append info
-			fields = append(fields, subsystemName+"."+fieldName) // subsystem.setting
-			helps = append(helps, fsochelpTag)
+			fields = append(fields, subsystemName+"."+setting.Name) // subsystem.setting
+			helps = append(helps, help)
+		}
+	}
+	formatAndDisplayFields(cmd, fields, helps)
+
+	// display environment variables for the ephemeral profile
+	cmd.Println(envHelpIntro)
+	fields = []string{}
+	helps = []string{}
+	for _, v := range cfg.EnvVars() {
+		field := v.Setting
+		if name, found := envSettingFields[field]; found {
+			field = name
+		} else if setting, found := strings.CutPrefix(field, "subsystems."); found {
+			field = setting // subsystem.setting
 		}
+		fields = append(fields, v.Name)
+		helps = append(helps, `same as the "`+field+`" setting`)
 	}
+	fields = append(fields, "FSOC_HEADER_NAME")
+	helps = append(helps, `same as the "header.NAME" setting, with underscores in NAME standing for dashes (e.g., FSOC_HEADER_X_TEAM for the X-Team header)`)
 	formatAndDisplayFields(cmd, fields, helps)
 }
 
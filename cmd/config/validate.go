@@ -0,0 +1,213 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/maps"
+
+	cfg "github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/tracing"
+)
+
+// Setting checks shared by "config set" and by the commands that take whole profiles (e.g., "config import")
+
+func validateAuthMethod(val string) error {
+	if !slices.Contains(GetAuthMethodsStringList(), val) {
+		return fmt.Errorf(`invalid auth method %q; must be one of {"%v"}`, val, strings.Join(GetAuthMethodsStringList(), `", "`))
+	}
+	return nil
+}
+
+var envTypes = []string{"prod", "dev"}
+
+func validateEnvType(val string) error {
+	if !slices.Contains(envTypes, val) {
+		return fmt.Errorf("envtype can only take on one of the following values: %s", strings.Join(envTypes, ", "))
+	}
+	return nil
+}
+
+// validateHTTPURL checks that a setting's value is an http or https URL
+func validateHTTPURL(setting string, val string, example string) error {
+	if u, err := url.Parse(val); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid %v %q: must be an http or https URL, e.g., %v", setting, val, example)
+	}
+	return nil
+}
+
+func validateProxy(val string) error {
+	if u, err := url.Parse(val); err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid proxy URL %q: must include scheme and host, e.g., http://proxy.example.com:8080", val)
+	}
+	return nil
+}
+
+func validateRetries(retries int) error {
+	if retries < 0 {
+		return fmt.Errorf("invalid retries value %d: must be a non-negative integer (0 disables retries)", retries)
+	}
+	return nil
+}
+
+func validateRetryMaxDelay(val string) error {
+	if d, err := time.ParseDuration(val); err != nil || d <= 0 {
+		return fmt.Errorf("invalid retry-max-delay value %q: must be a positive duration, e.g., 30s or 2m", val)
+	}
+	return nil
+}
+
+func validateTokenRefreshSkew(val string) error {
+	if d, err := time.ParseDuration(val); err != nil || d < 0 {
+		return fmt.Errorf("invalid token-refresh-skew value %q: must be a non-negative duration, e.g., 30s or 2m", val)
+	}
+	return nil
+}
+
+// validateCredentialStore checks the credential store and that the helper store has a helper command
+func validateCredentialStore(store string, helper string) error {
+	if store != "" && !slices.Contains(cfg.GetCredentialStores(), store) {
+		return fmt.Errorf(`invalid credential-store %q; must be one of {"%v"}`, store, strings.Join(cfg.GetCredentialStores(), `", "`))
+	}
+	if store == cfg.CredentialStoreHelper && strings.TrimSpace(helper) == "" {
+		return fmt.Errorf(`the %q credential store requires a credential helper command, e.g., credential-helper="my-helper --vault fsoc"`, cfg.CredentialStoreHelper)
+	}
+	if helper != "" {
+		if _, err := cfg.SplitCommandLine(helper); err != nil {
+			return fmt.Errorf("invalid credential-helper command %q: %v", helper, err)
+		}
+	}
+	return nil
+}
+
+func validateTraceExporter(val string) error {
+	if !slices.Contains(tracing.Exporters(), val) {
+		return fmt.Errorf(`invalid trace-exporter %q; must be one of {"%v"}`, val, strings.Join(tracing.Exporters(), `", "`))
+	}
+	return nil
+}
+
+// validateTraceFile checks that the file trace exporter has a trace file
+func validateTraceFile(opts *cfg.TracingOptions) error {
+	if opts.Exporter == tracing.ExporterFile && opts.File == "" {
+		return fmt.Errorf(`the %q trace exporter requires a trace file, e.g., trace-file=fsoc-traces.json`, tracing.ExporterFile)
+	}
+	return nil
+}
+
+// validateContext checks all settings of a profile with the same checks "config set" applies to
+// each setting it sets. The settings populated by logging in (e.g., the tenant for oauth) are not
+// checked against the auth method. Subsystem settings are parsed into the subsystems' configs.
+func validateContext(cmd *cobra.Command, ctx *cfg.Context) error {
+	if ctx.AuthMethod == "" {
+		return fmt.Errorf("missing auth method")
+	}
+	if err := validateAuthMethod(ctx.AuthMethod); err != nil {
+		return err
+	}
+	if ctx.EnvType != "" {
+		if err := validateEnvType(ctx.EnvType); err != nil {
+			return err
+		}
+	}
+	if ctx.URL != "" {
+		if _, err := validateUrl(ctx.URL); err != nil {
+			return err
+		}
+	}
+
+	// settings that are allowed only for some auth methods
+	authFields := map[string]string{
+		"secret-file":         ctx.SecretFile,
+		"session-manager-url": ctx.SessionManagerURL,
+		cfg.AppdPid:           ctx.LocalAuthOptions.AppdPid,
+		cfg.AppdTid:           ctx.LocalAuthOptions.AppdTid,
+		cfg.AppdPty:           ctx.LocalAuthOptions.AppdPty,
+	}
+	fields := maps.Keys(authFields)
+	slices.Sort(fields)
+	for _, field := range fields {
+		if authFields[field] != "" && getAuthFieldConfigRow(ctx.AuthMethod)[field] == ClearField {
+			return fmt.Errorf("field %s is not allowed for authentication method %s", field, ctx.AuthMethod)
+		}
+	}
+	if ctx.SessionManagerURL != "" {
+		if err := validateHTTPURL("session-manager-url", ctx.SessionManagerURL, "http://localhost:8200/token"); err != nil {
+			return err
+		}
+	}
+
+	// settings that apply to all auth methods
+	if ctx.RetryOptions.MaxRetries != nil {
+		if err := validateRetries(*ctx.RetryOptions.MaxRetries); err != nil {
+			return err
+		}
+	}
+	if ctx.RetryOptions.MaxDelay != "" {
+		if err := validateRetryMaxDelay(ctx.RetryOptions.MaxDelay); err != nil {
+			return err
+		}
+	}
+	if ctx.TokenRefreshSkew != "" {
+		if err := validateTokenRefreshSkew(ctx.TokenRefreshSkew); err != nil {
+			return err
+		}
+	}
+	if err := validateCredentialStore(ctx.CredentialStore, ctx.CredentialHelper); err != nil {
+		return err
+	}
+	if ctx.TransportOptions.Proxy != "" {
+		if err := validateProxy(ctx.TransportOptions.Proxy); err != nil {
+			return err
+		}
+	}
+	if ctx.TracingOptions.Exporter != "" {
+		if err := validateTraceExporter(ctx.TracingOptions.Exporter); err != nil {
+			return err
+		}
+	}
+	if ctx.TracingOptions.Endpoint != "" {
+		if err := validateHTTPURL("trace-endpoint", ctx.TracingOptions.Endpoint, tracing.DefaultOTLPEndpoint); err != nil {
+			return err
+		}
+	}
+	if err := validateTraceFile(&ctx.TracingOptions); err != nil {
+		return err
+	}
+
+	// default headers and subsystem settings
+	for name := range ctx.HeaderOptions.Global {
+		if err := cfg.ValidateHeaderName(name); err != nil {
+			return err
+		}
+	}
+	for subsystemName, headers := range ctx.HeaderOptions.Subsystems {
+		if !isSubsystem(cmd, subsystemName) {
+			return fmt.Errorf("default headers for unknown subsystem %q", subsystemName)
+		}
+		for name := range headers {
+			if err := cfg.ValidateHeaderName(name); err != nil {
+				return err
+			}
+		}
+	}
+	return cfg.UpdateSubsystemConfigs(ctx)
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+
+	cfg "github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/tracing"
+)
+
+func TestValidateContext(t *testing.T) {
+	cmd := &cobra.Command{}
+	retries := 3
+	valid := cfg.Context{
+		Name:         "test",
+		AuthMethod:   cfg.AuthMethodServicePrincipal,
+		URL:          "https://mytenant.observe.appdynamics.com",
+		SecretFile:   "/tmp/creds.json",
+		RetryOptions: cfg.RetryOptions{MaxRetries: &retries, MaxDelay: "10s"},
+	}
+	assert.Nil(t, validateContext(cmd, &valid))
+
+	negative := -1
+	invalid := map[string]func(ctx *cfg.Context){
+		"missing auth method":        func(ctx *cfg.Context) { ctx.AuthMethod = "" },
+		"invalid auth method":        func(ctx *cfg.Context) { ctx.AuthMethod = "password" },
+		"envtype":                    func(ctx *cfg.Context) { ctx.EnvType = "test" },
+		"no host":                    func(ctx *cfg.Context) { ctx.URL = "/path" },
+		"not allowed":                func(ctx *cfg.Context) { ctx.AuthMethod = cfg.AuthMethodOAuth },
+		"invalid retries":            func(ctx *cfg.Context) { ctx.RetryOptions.MaxRetries = &negative },
+		"invalid retry-max-delay":    func(ctx *cfg.Context) { ctx.RetryOptions.MaxDelay = "soon" },
+		"invalid token-refresh-skew": func(ctx *cfg.Context) { ctx.TokenRefreshSkew = "-1s" },
+		"invalid credential-store":   func(ctx *cfg.Context) { ctx.CredentialStore = "vault" },
+		"requires a credential":      func(ctx *cfg.Context) { ctx.CredentialStore = cfg.CredentialStoreHelper },
+		"invalid proxy":              func(ctx *cfg.Context) { ctx.TransportOptions.Proxy = "proxy" },
+		"invalid trace-exporter":     func(ctx *cfg.Context) { ctx.TracingOptions.Exporter = "jaeger" },
+		"requires a trace file":      func(ctx *cfg.Context) { ctx.TracingOptions.Exporter = tracing.ExporterFile },
+		"invalid header name":        func(ctx *cfg.Context) { ctx.HeaderOptions.Global = map[string]string{"X Team": "blue"} },
+		"headers for unknown": func(ctx *cfg.Context) {
+			ctx.HeaderOptions.Subsystems = map[string]map[string]string{"nope": {"X-Team": "blue"}}
+		},
+		"invalid session-manager-url": func(ctx *cfg.Context) {
+			ctx.AuthMethod = cfg.AuthMethodSessionManager
+			ctx.SessionManagerURL = "localhost"
+		},
+	}
+	for message, change := range invalid {
+		ctx := valid
+		change(&ctx)
+		assert.ErrorContains(t, validateContext(cmd, &ctx), message)
+	}
+}
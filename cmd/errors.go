@@ -0,0 +1,197 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+// Supported values of the --error-format flag
+const (
+	errorFormatText = "text"
+	errorFormatJSON = "json"
+)
+
+var errorFormat = errorFormatText
+
+// usageError indicates an invalid command line (unknown command, invalid flags or arguments)
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+// fatalHandler is a log handler that terminates fsoc on fatal-level log entries, optionally
+// reporting the failure as JSON instead of text. Entries are passed to the CLI handler (stderr)
+// and the log file handler.
+type fatalHandler struct {
+	cliHandler   log.Handler
+	fileHandler  log.Handler     // may be nil
+	ctx          context.Context // the command's context, if known; nil otherwise
+	errOut       io.Writer
+	exit         func(code int)
+	panicOnFatal bool // end only the failing run with a *fatalError panic (see profilesRun)
+}
+
+// fatalError is the panic with which a fatal-level log entry ends a command run for one of multiple
+// profiles, so that the failure is reported for the profile without terminating fsoc
+type fatalError struct {
+	message string
+}
+
+func (e *fatalError) Error() string {
+	return e.message
+}
+
+func (h *fatalHandler) HandleLog(e *log.Entry) error {
+	if h.fileHandler != nil {
+		_ = h.fileHandler.HandleLog(e)
+	}
+	if e.Level != log.FatalLevel {
+		return h.cliHandler.HandleLog(e)
+	}
+	if h.panicOnFatal {
+		panic(&fatalError{message: e.Message})
+	}
+
+	var ctxErr error
+	if h.ctx != nil {
+		ctxErr = h.ctx.Err()
+	}
+	report := fatalReport(e.Message, ctxErr)
+	if errorFormat == errorFormatJSON {
+		writeErrorReport(h.errOut, report)
+	} else {
+		_ = h.cliHandler.HandleLog(e)
+	}
+	finishTracing(errors.New(e.Message))
+	h.exit(report.ExitCode) // nb: the logger would exit with 1 after this
+	return nil
+}
+
+// fatalReport creates the error report for a fatal log message. Commands that fail with log.Fatal
+// report the failure as text only, so it is classified as a general failure, unless the command's
+// context was cancelled or timed out (ctxErr). Commands that return errors are classified by
+// ReportError instead.
+func fatalReport(message string, ctxErr error) *api.ErrorReport {
+	kind := api.ErrorKindGeneral
+	if ctxErr != nil {
+		kind = api.ErrorKindOf(ctxErr)
+	}
+	return &api.ErrorReport{Kind: kind, ExitCode: api.ExitCodeForKind(kind), Message: message}
+}
+
+func writeErrorReport(w io.Writer, report *api.ErrorReport) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		fmt.Fprintf(w, "%v\n", report.Message) // unlikely
+		return
+	}
+	fmt.Fprintf(w, "%s\n", data)
+}
+
+// ReportError reports an error returned from Execute in the selected error format
+// and returns the exit code for it
+func ReportError(err error) int {
+	finishTracing(err)
+	return reportError(os.Stderr, err)
+}
+
+// reportError implements ReportError, writing JSON reports to w
+func reportError(w io.Writer, err error) int {
+	var report *api.ErrorReport
+	var usageErr *usageError
+	var profilesErr *profilesError
+	if errors.As(err, &usageErr) || strings.HasPrefix(err.Error(), "unknown command") {
+		report = &api.ErrorReport{Kind: api.ErrorKindUsage, ExitCode: api.ExitCodeForKind(api.ErrorKindUsage), Message: err.Error()}
+	} else if errors.As(err, &profilesErr) {
+		report = &api.ErrorReport{Kind: profilesErr.kind, ExitCode: api.ExitCodeForKind(profilesErr.kind), Message: err.Error()}
+	} else {
+		report = api.NewErrorReport(err)
+	}
+
+	if errorFormat == errorFormatJSON {
+		writeErrorReport(w, report)
+	} else {
+		log.WithFields(log.Fields{"error": err}).Error("command failed")
+	}
+	return report.ExitCode
+}
+
+// flagUsageError marks invalid flags as usage errors (see cobra.Command.SetFlagErrorFunc)
+func flagUsageError(c *cobra.Command, err error) error {
+	return &usageError{err: err}
+}
+
+// markUsageErrors wraps the argument validation of the command and its subcommands, so that
+// invalid arguments are reported with the usage exit code (flag errors are wrapped by the root
+// command). Errors returned by the commands' RunE are failures of the command rather than of
+// the command line, so they are reported by ReportError only, without the usage.
+func markUsageErrors(cmd *cobra.Command) {
+	if validator := cmd.Args; validator != nil {
+		cmd.Args = func(c *cobra.Command, args []string) error {
+			if err := validator(c, args); err != nil {
+				return &usageError{err: err}
+			}
+			return nil
+		}
+	}
+	if run := cmd.RunE; run != nil {
+		cmd.RunE = func(c *cobra.Command, args []string) error {
+			c.SilenceUsage = true
+			c.SilenceErrors = true
+			return run(c, args)
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		markUsageErrors(sub)
+	}
+}
+
+// errorFormatValue implements pflag.Value for the --error-format flag, validating its value
+type errorFormatValue struct{}
+
+func (v *errorFormatValue) String() string {
+	return errorFormat
+}
+
+func (v *errorFormatValue) Set(s string) error {
+	if s != errorFormatText && s != errorFormatJSON {
+		return fmt.Errorf("must be %q or %q", errorFormatText, errorFormatJSON)
+	}
+	errorFormat = s
+	return nil
+}
+
+func (v *errorFormatValue) Type() string {
+	return "string"
+}
@@ -0,0 +1,183 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/memory"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+// useErrorFormat selects the error format for the duration of the test
+func useErrorFormat(t *testing.T, format string) {
+	saved := errorFormat
+	errorFormat = format
+	t.Cleanup(func() { errorFormat = saved })
+}
+
+func TestFatalHandler(t *testing.T) {
+	useErrorFormat(t, errorFormatJSON)
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	timedOut, cancelTimeout := context.WithTimeout(context.Background(), 0)
+	defer cancelTimeout()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		kind api.ErrorKind
+		code int
+	}{
+		{"no context", nil, api.ErrorKindGeneral, 1},
+		{"active context", context.Background(), api.ErrorKindGeneral, 1},
+		{"interrupted", cancelled, api.ErrorKindCancelled, 130},
+		{"timed out", timedOut, api.ErrorKindTimeout, 11},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errOut := &bytes.Buffer{}
+			exitCode := -1
+			cli := memory.New()
+			h := &fatalHandler{cliHandler: cli, ctx: tt.ctx, errOut: errOut, exit: func(code int) { exitCode = code }}
+
+			// entries below the fatal level are only logged
+			require.Nil(t, h.HandleLog(&log.Entry{Level: log.WarnLevel, Message: "careful"}))
+			assert.Equal(t, -1, exitCode)
+			assert.Equal(t, 1, len(cli.Entries))
+
+			require.Nil(t, h.HandleLog(&log.Entry{Level: log.FatalLevel, Message: "Failed to do it: status 404"}))
+			assert.Equal(t, tt.code, exitCode)
+			var report api.ErrorReport
+			require.Nil(t, json.Unmarshal(errOut.Bytes(), &report))
+			assert.Equal(t, tt.kind, report.Kind)
+			assert.Equal(t, tt.code, report.ExitCode)
+			assert.Equal(t, "Failed to do it: status 404", report.Message)
+		})
+	}
+
+	// in text format, the fatal entry is logged rather than reported as JSON
+	useErrorFormat(t, errorFormatText)
+	errOut := &bytes.Buffer{}
+	cli := memory.New()
+	exitCode := -1
+	h := &fatalHandler{cliHandler: cli, errOut: errOut, exit: func(code int) { exitCode = code }}
+	require.Nil(t, h.HandleLog(&log.Entry{Level: log.FatalLevel, Message: "boom"}))
+	assert.Equal(t, 1, exitCode)
+	assert.Empty(t, errOut.String())
+	require.Equal(t, 1, len(cli.Entries))
+	assert.Equal(t, "boom", cli.Entries[0].Message)
+}
+
+func TestReportError(t *testing.T) {
+	useErrorFormat(t, errorFormatJSON)
+	problem := &api.Problem{Title: "Conflict", Detail: "the object was changed", Status: http.StatusConflict}
+	apiErr := &api.HttpStatusError{StatusCode: http.StatusConflict, WrappedErr: problem}
+
+	tests := []struct {
+		name   string
+		err    error
+		kind   api.ErrorKind
+		code   int
+		status int
+	}{
+		{"usage", &usageError{err: errors.New("unknown flag: --bogus")}, api.ErrorKindUsage, 2, 0},
+		{"unknown command", errors.New(`unknown command "bogus" for "fsoc"`), api.ErrorKindUsage, 2, 0},
+		{"profiles", &profilesError{failed: []string{"a"}, total: 2, kind: api.ErrorKindNotFound}, api.ErrorKindNotFound, 5, 0},
+		{"api", fmt.Errorf("failed to update: %w", apiErr), api.ErrorKindConflict, 6, http.StatusConflict},
+		{"timeout", fmt.Errorf("stopped: %w", context.DeadlineExceeded), api.ErrorKindTimeout, 11, 0},
+		{"other", errors.New("something else"), api.ErrorKindGeneral, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			assert.Equal(t, tt.code, reportError(w, tt.err))
+			var report api.ErrorReport
+			require.Nil(t, json.Unmarshal(w.Bytes(), &report))
+			assert.Equal(t, tt.kind, report.Kind)
+			assert.Equal(t, tt.code, report.ExitCode)
+			assert.Equal(t, tt.err.Error(), report.Message)
+			assert.Equal(t, tt.status, report.Status)
+		})
+	}
+
+	// the problem details are included in the report
+	w := &bytes.Buffer{}
+	reportError(w, fmt.Errorf("failed to update: %w", apiErr))
+	var report api.ErrorReport
+	require.Nil(t, json.Unmarshal(w.Bytes(), &report))
+	assert.Equal(t, "Conflict", report.Title)
+	assert.Equal(t, "the object was changed", report.Detail)
+}
+
+func TestCommandErrorExitCodes(t *testing.T) {
+	useErrorFormat(t, errorFormatJSON)
+	notFound := &api.HttpStatusError{Message: "Not Found", StatusCode: http.StatusNotFound}
+
+	// execute runs a test command tree with the arguments, returning the exit code and the
+	// output displayed by cobra
+	execute := func(args ...string) (int, string) {
+		root := &cobra.Command{Use: "fsoc", SilenceErrors: true}
+		root.SetFlagErrorFunc(flagUsageError)
+		root.AddCommand(&cobra.Command{
+			Use:  "get <name>",
+			Args: cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				if args[0] == "missing" {
+					return fmt.Errorf("failed to get %q: %w", args[0], notFound)
+				}
+				return nil
+			},
+		})
+		markUsageErrors(root)
+		out := &bytes.Buffer{}
+		root.SetOut(out)
+		root.SetErr(out)
+		root.SetArgs(args)
+		err := root.Execute()
+		if err == nil {
+			return 0, out.String()
+		}
+		return reportError(&bytes.Buffer{}, err), out.String()
+	}
+
+	code, _ := execute("get", "found")
+	assert.Equal(t, 0, code)
+
+	// invalid command lines exit with the usage code
+	code, out := execute("get", "--bogus", "found")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out, "Usage:")
+	code, _ = execute("get")
+	assert.Equal(t, 2, code)
+	code, _ = execute("bogus")
+	assert.Equal(t, 2, code)
+
+	// failures returned by commands exit with the code for their cause, without the usage
+	code, out = execute("get", "missing")
+	assert.Equal(t, 5, code)
+	assert.NotContains(t, out, "Usage:")
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/cisco-open/fsoc/cmdkit"
+	"github.com/cisco-open/fsoc/config"
 	"github.com/cisco-open/fsoc/output"
 )
 
@@ -38,10 +39,11 @@ Detail and json/yaml output include role permissions; the table view contains on
   fsoc roles list -o json
   fsoc role list -o detail`,
 	Args: cobra.NoArgs,
-	Run:  listRoles,
+	RunE: listRoles,
 	Annotations: map[string]string{
-		output.TableFieldsAnnotation:  "id:.id, name:.data.displayName, description:.data.description",
-		output.DetailFieldsAnnotation: "id:.id, name:.data.displayName, description:.data.description, permissions:(reduce .data.permissions[].id as $o ([]; . + [$o])), scopes:.data.scopes",
+		config.AnnotationForMultipleProfiles: "",
+		output.TableFieldsAnnotation:         "id:.id, name:.data.displayName, description:.data.description",
+		output.DetailFieldsAnnotation:        "id:.id, name:.data.displayName, description:.data.description, permissions:(reduce .data.permissions[].id as $o ([]; . + [$o])), scopes:.data.scopes",
 	},
 }
 
@@ -50,6 +52,6 @@ func newCmdRoleList() *cobra.Command {
 	return iamRoleListCmd
 }
 
-func listRoles(cmd *cobra.Command, args []string) {
-	cmdkit.FetchAndPrint(cmd, getIamRoleUrl("", ""), &cmdkit.FetchAndPrintOptions{IsCollection: true})
+func listRoles(cmd *cobra.Command, args []string) error {
+	return cmdkit.FetchAndPrint(cmd, getIamRoleUrl("", ""), &cmdkit.FetchAndPrintOptions{IsCollection: true})
 }
@@ -18,6 +18,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/cisco-open/fsoc/cmdkit"
+	"github.com/cisco-open/fsoc/config"
 	"github.com/cisco-open/fsoc/output"
 )
 
@@ -35,9 +36,10 @@ The json/yaml output include the actions and resources for each permissions; the
   fsoc role permissions spacefleet:commandingOfficer
   fsoc role permissions iam:agent -o json`,
 	Args: cobra.ExactArgs(1),
-	Run:  listPermissions,
+	RunE: listPermissions,
 	Annotations: map[string]string{
-		output.TableFieldsAnnotation: "id:.id, name:.data.displayName, description:.data.description",
+		config.AnnotationForMultipleProfiles: "",
+		output.TableFieldsAnnotation:         "id:.id, name:.data.displayName, description:.data.description",
 	},
 }
 
@@ -46,6 +48,6 @@ func newCmdRolePermissions() *cobra.Command {
 	return iamRolePermissionsCmd
 }
 
-func listPermissions(cmd *cobra.Command, args []string) {
-	cmdkit.FetchAndPrint(cmd, getIamRoleUrl(args[0], "permissions"), &cmdkit.FetchAndPrintOptions{IsCollection: true})
+func listPermissions(cmd *cobra.Command, args []string) error {
+	return cmdkit.FetchAndPrint(cmd, getIamRoleUrl(args[0], "permissions"), &cmdkit.FetchAndPrintOptions{IsCollection: true})
 }
@@ -15,9 +15,9 @@
 package iamrole
 
 import (
-	"github.com/apex/log"
 	"github.com/spf13/cobra"
 
+	"github.com/cisco-open/fsoc/config"
 	"github.com/cisco-open/fsoc/output"
 	"github.com/cisco-open/fsoc/platform/api"
 )
@@ -35,9 +35,10 @@ This command requires a principal with tenant administrator access.`,
   fsoc iam-role principals spacefleet:commandingOfficer
   fsoc role principals iam:agent -o json`,
 	Args: cobra.ExactArgs(1),
-	Run:  listPrincipals,
+	RunE: listPrincipals,
 	Annotations: map[string]string{
-		output.TableFieldsAnnotation: "id:.id, type:.type",
+		config.AnnotationForMultipleProfiles: "",
+		output.TableFieldsAnnotation:         "id:.id, type:.type",
 	},
 }
 
@@ -61,15 +62,16 @@ type principalsCollection struct {
 	Items []principalEntry `json:"items"`
 }
 
-func listPrincipals(cmd *cobra.Command, args []string) {
+func listPrincipals(cmd *cobra.Command, args []string) error {
 	// note: the API is not compliant with collections/pagination, so collect as a single request
 	var out principalsResponse
-	err := api.JSONGet(getIamRoleUrl(args[0], "principals"), &out, nil)
+	err := api.JSONGet(getIamRoleUrl(args[0], "principals"), &out, &api.Options{Context: cmd.Context()})
 	if err != nil {
-		log.Fatal(err.Error())
+		return err
 	}
 
 	// reflow into a collection structure
 	data := principalsCollection{Total: out.Total, Items: out.Principals}
 	output.PrintCmdOutput(cmd, data)
+	return nil
 }
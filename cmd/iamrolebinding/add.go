@@ -15,7 +15,6 @@
 package iamrolebinding
 
 import (
-	"github.com/apex/log"
 	"github.com/spf13/cobra"
 
 	"github.com/cisco-open/fsoc/output"
@@ -34,7 +33,7 @@ This command requires a principal with tenant administrator access.`,
   fsoc rb add john@example.com iam:observer spacefleet:crewMember
   fsoc rb add srv_1ZGdlbcm8NajPxY4o43SNv optimize:optimizationManager`,
 	Args: cobra.MinimumNArgs(2),
-	Run:  addRoles,
+	RunE: addRoles,
 }
 
 // Package registration function for the iam-role-binding command root
@@ -42,10 +41,11 @@ func newCmdRbAdd() *cobra.Command {
 	return iamRbAddCmd
 }
 
-func addRoles(cmd *cobra.Command, args []string) {
+func addRoles(cmd *cobra.Command, args []string) error {
 	if err := patchRoles(args[0], args[1:], true); err != nil {
-		log.Fatal(err.Error())
+		return err
 	}
 
 	output.PrintCmdStatus(cmd, "Roles added successfully.\n")
+	return nil
 }
@@ -15,7 +15,6 @@
 package iamrolebinding
 
 import (
-	"github.com/apex/log"
 	"github.com/spf13/cobra"
 
 	"github.com/cisco-open/fsoc/output"
@@ -34,7 +33,7 @@ This command requires a principal with tenant administrator access.`,
   fsoc rb remove riker@example.com iam:tenantAdmin spacefleet:commandingOfficer
   fsoc rb remove srv_1ZGdlbcm8NajPxY4o43SNv optimize:optimizationManager`,
 	Args: cobra.MinimumNArgs(2),
-	Run:  removeRoles,
+	RunE: removeRoles,
 }
 
 // Package registration function for the iam-role-binding command root
@@ -42,10 +41,11 @@ func newCmdRbRemove() *cobra.Command {
 	return iamRbRemoveCmd
 }
 
-func removeRoles(cmd *cobra.Command, args []string) {
+func removeRoles(cmd *cobra.Command, args []string) error {
 	if err := patchRoles(args[0], args[1:], false); err != nil {
-		log.Fatal(err.Error())
+		return err
 	}
 
 	output.PrintCmdStatus(cmd, "Roles removed successfully.\n")
+	return nil
 }
@@ -0,0 +1,72 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+// interruptGracePeriod is how long a command may take to wind down after it is interrupted
+const interruptGracePeriod = 2 * time.Second
+
+// InterruptContext returns a context that is cancelled when fsoc is interrupted (e.g., with Ctrl-C),
+// so that the platform API calls in progress are aborted cleanly. Commands that don't complete
+// within a short grace period after that (e.g., because they don't use the context) are ended
+// with the interrupted exit code; interrupting again exits immediately. Call the returned
+// function when the command is complete.
+func InterruptContext(parent context.Context) (context.Context, context.CancelFunc) {
+	signals := []os.Signal{os.Interrupt, syscall.SIGTERM}
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, signals...)
+	ctx, stop := interruptContext(parent, interrupts, interruptGracePeriod, os.Exit, func() { signal.Reset(signals...) })
+	return ctx, func() {
+		signal.Stop(interrupts)
+		stop()
+	}
+}
+
+// interruptContext implements InterruptContext for a channel of interrupts; restore is called on
+// the first interrupt to restore the default handling of subsequent interrupts
+func interruptContext(parent context.Context, interrupts <-chan os.Signal, grace time.Duration, exit func(code int), restore func()) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-interrupts:
+		case <-done:
+			return
+		}
+		cancel()
+		restore()
+		select {
+		case <-time.After(grace):
+			exit(api.ExitCodeForKind(api.ErrorKindCancelled))
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() { close(done) })
+		cancel()
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterruptContext(t *testing.T) {
+	// an interrupt cancels the context and, if the command doesn't complete in time, exits
+	interrupts := make(chan os.Signal, 1)
+	exitCodes := make(chan int, 1)
+	restored := make(chan bool, 1)
+	ctx, stop := interruptContext(context.Background(), interrupts, 10*time.Millisecond, func(code int) { exitCodes <- code }, func() { restored <- true })
+	defer stop()
+	interrupts <- os.Interrupt
+	<-ctx.Done()
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	assert.True(t, <-restored)
+	select {
+	case code := <-exitCodes:
+		assert.Equal(t, 130, code)
+	case <-time.After(5 * time.Second):
+		t.Fatal("command was not ended after the grace period")
+	}
+
+	// a command that completes within the grace period exits normally
+	interrupts = make(chan os.Signal, 1)
+	ctx, stop = interruptContext(context.Background(), interrupts, 50*time.Millisecond, func(code int) { exitCodes <- code }, func() {})
+	interrupts <- os.Interrupt
+	<-ctx.Done()
+	stop()
+	select {
+	case code := <-exitCodes:
+		t.Fatalf("unexpected exit with code %d", code)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// completing without an interrupt cancels the context only
+	ctx, stop = interruptContext(context.Background(), make(chan os.Signal), time.Millisecond, func(code int) { exitCodes <- code }, func() {})
+	assert.Nil(t, ctx.Err())
+	stop()
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	assert.Empty(t, exitCodes)
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/cisco-open/fsoc/cmdkit"
+	"github.com/cisco-open/fsoc/config"
 )
 
 func newGetObjectCmd() *cobra.Command {
@@ -45,12 +46,16 @@ func newGetObjectCmd() *cobra.Command {
   # Get list of objects filtering by a data field
   fsoc knowledge get --type=extensibility:solution --layer-type=TENANT --filter="data.isSystem eq true"
   fsoc knowledge get --type=preferences:theme --layer-type=TENANT --filter="data.backgroundColor eq \"green\""
+
+  # Stream a large list of objects, one JSON object per line, as pages are retrieved
+  fsoc knowledge get --type=extensibility:solution --layer-type=TENANT -o ndjson --limit=500
   `,
 		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return getObject(cmd, args, ltFlag)
 		},
 		TraverseChildren: true,
+		Annotations:      map[string]string{config.AnnotationForMultipleProfiles: ""},
 	}
 
 	// get object
@@ -70,6 +75,7 @@ func newGetObjectCmd() *cobra.Command {
 
 	getCmd.PersistentFlags().String("filter", "", "Filter condition in SCIM filter format for getting knowledge objects")
 	getCmd.PersistentFlags().String("fields", "", "Specific fields to fetch when getting knowledge objects.  By default, all fields are returned unless otherwise specified.  Please specify fields as a csv string.")
+	getCmd.PersistentFlags().Int("limit", 0, "Maximum number of knowledge objects to fetch when getting a list of objects (default no limit)")
 	_ = getCmd.MarkPersistentFlagRequired("type")
 	_ = getCmd.MarkPersistentFlagRequired("layer-type")
 
@@ -90,6 +96,7 @@ func newGetTypeCmd() *cobra.Command {
 			return getType(cmd, args)
 		},
 		TraverseChildren: true,
+		Annotations:      map[string]string{config.AnnotationForMultipleProfiles: ""},
 	}
 
 	// get type
@@ -112,8 +119,7 @@ func getType(cmd *cobra.Command, args []string) error {
 	}
 
 	// execute command and print result
-	cmdkit.FetchAndPrint(cmd, getTypeUrl(fqtn), nil)
-	return nil
+	return cmdkit.FetchAndPrint(cmd, getTypeUrl(fqtn), nil)
 }
 
 func getObject(cmd *cobra.Command, args []string, ltFlag layerType) error {
@@ -169,8 +175,8 @@ func getObject(cmd *cobra.Command, args []string, ltFlag layerType) error {
 		objStoreUrl = getObjectListUrl(fqtn)
 	}
 
-	cmdkit.FetchAndPrint(cmd, objStoreUrl, &cmdkit.FetchAndPrintOptions{Headers: headers, IsCollection: isCollection})
-	return nil
+	limit, _ := cmd.Flags().GetInt("limit")
+	return cmdkit.FetchAndPrint(cmd, objStoreUrl, &cmdkit.FetchAndPrintOptions{Headers: headers, IsCollection: isCollection, Limit: limit})
 }
 
 func getTypeUrl(fqtn string) string {
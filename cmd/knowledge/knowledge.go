@@ -15,6 +15,7 @@
 package knowledge
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -109,7 +110,7 @@ func getObjectsForType(typeName string, lType string, layerID string, prefix str
 		if lType == "SOLUTION" {
 			return objects // Not suppored
 		} else {
-			layerID = getCorrectLayerID(lType, typeName)
+			layerID = getCorrectLayerID(context.Background(), lType, typeName)
 		}
 	}
 
@@ -175,7 +176,7 @@ func parseObjectInfo(cmd *cobra.Command) (typeName string, objectID string, laye
 			err = fmt.Errorf("requests made to the SOLUTION layer require the --layer-id flag")
 			return "", "", "", "", err
 		} else {
-			layerID = getCorrectLayerID(layerType, typeName)
+			layerID = getCorrectLayerID(cmd.Context(), layerType, typeName)
 		}
 	}
 
@@ -15,13 +15,16 @@
 package knowledge
 
 import (
+	"context"
 	"strings"
 
 	"github.com/cisco-open/fsoc/config"
 )
 
-func getCorrectLayerID(layerType string, fqtn string) string {
-	cfg := config.GetCurrentContext()
+// getCorrectLayerID returns the layer ID for the layer type, using the profile from the Go context
+// (see config.CurrentContext)
+func getCorrectLayerID(ctx context.Context, layerType string, fqtn string) string {
+	cfg := config.CurrentContext(ctx)
 	var layerID string
 
 	if layerType == "TENANT" {
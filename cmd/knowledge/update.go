@@ -20,7 +20,6 @@ import (
 	"io"
 	"os"
 
-	"github.com/apex/log"
 	"github.com/spf13/cobra"
 
 	"github.com/cisco-open/fsoc/output"
@@ -47,7 +46,7 @@ var objStoreUpdateCmd = &cobra.Command{
 	--layer-id - OPTIONAL Flag to specify a custom layer ID for the knowledge object that you would like to update.  This is calculated automatically for all layers currently supported but can be overridden with this flag`,
 
 	Args:             cobra.ExactArgs(0),
-	Run:              updateObject,
+	RunE:             updateObject,
 	TraverseChildren: true,
 }
 
@@ -78,13 +77,13 @@ func getUpdateObjectCmd() *cobra.Command {
 
 }
 
-func updateObject(cmd *cobra.Command, args []string) {
+func updateObject(cmd *cobra.Command, args []string) error {
 	objType, _ := cmd.Flags().GetString("type")
 
 	objJsonFilePath, _ := cmd.Flags().GetString("object-file")
 	objectFile, err := os.Open(objJsonFilePath)
 	if err != nil {
-		log.Fatalf("Can't find the knowledge object definition file named %s", objJsonFilePath)
+		return fmt.Errorf("can't find the knowledge object definition file named %s", objJsonFilePath)
 	}
 	defer objectFile.Close()
 
@@ -92,19 +91,19 @@ func updateObject(cmd *cobra.Command, args []string) {
 	var objectStruct map[string]interface{}
 	err = json.Unmarshal(objectBytes, &objectStruct)
 	if err != nil {
-		log.Fatalf("Can't parse file %q. Make sure the knowledge object definition has all the required field and is valid according to the type definition.", objJsonFilePath)
+		return fmt.Errorf("can't parse file %q. Make sure the knowledge object definition has all the required field and is valid according to the type definition", objJsonFilePath)
 	}
 
 	layerType, _ := cmd.Flags().GetString("layer-type")
-	layerID := getCorrectLayerID(layerType, objType)
+	layerID := getCorrectLayerID(cmd.Context(), layerType, objType)
 
 	if layerID == "" {
 		if !cmd.Flags().Changed("layer-id") {
-			log.Fatal("Unable to set layer-id flag from given context. Please specify a unique layer-id value with the --layer-id flag")
+			return fmt.Errorf("unable to set layer-id flag from given context. Please specify a unique layer-id value with the --layer-id flag")
 		}
 		layerID, err = cmd.Flags().GetString("layer-id")
 		if err != nil {
-			log.Fatalf("error trying to get %q flag value: %w", "layer-id", err)
+			return fmt.Errorf("error trying to get %q flag value: %w", "layer-id", err)
 		}
 	}
 
@@ -121,7 +120,8 @@ func updateObject(cmd *cobra.Command, args []string) {
 	output.PrintCmdStatus(cmd, fmt.Sprintf("Replacing knowledge object %q with the new data from %q \n", objId, objJsonFilePath))
 	err = api.JSONPut(objectUrl, objectStruct, &res, &api.Options{Headers: headers})
 	if err != nil {
-		log.Fatalf("Knowledge object update failed: %v", err)
+		return fmt.Errorf("knowledge object update failed: %w", err)
 	}
 	output.PrintCmdStatus(cmd, "Knowledge object updated successfully.\n")
+	return nil
 }
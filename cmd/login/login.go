@@ -15,7 +15,8 @@
 package login
 
 import (
-	"github.com/apex/log"
+	"fmt"
+
 	"github.com/spf13/cobra"
 
 	"github.com/cisco-open/fsoc/output"
@@ -29,31 +30,33 @@ var loginCmd = &cobra.Command{
 	Long: `This command logs in the principal specified in the profile, obtaining a temporary JWT token
 that will be automatically used by other commands.
 
+For the oauth auth method, the login is performed in a browser. When a browser cannot be launched, when running
+in an SSH session or, on Linux, without a graphical display, fsoc switches to a headless login: it displays a URL to open in a browser on any machine and asks
+for the URL that the browser is redirected to after login. Use the --no-browser flag to select the headless login
+explicitly, e.g., in a container or in a VM where launching a browser appears to succeed but no browser window opens.
+
 Usage:
-	fsoc login`,
-	Run:              login,
+	fsoc login
+	fsoc login --no-browser`,
+	RunE:             login,
 	TraverseChildren: true,
 }
 
 func init() {
-	// Here you will define your flags and configuration settings.
-
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// loginCmd.PersistentFlags().String("foo", "", "A help for foo")
-
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// loginCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	loginCmd.Flags().Bool("no-browser", false, "Log in without a local browser by pasting back the redirect URL (oauth only)")
 }
 
 func NewSubCmd() *cobra.Command {
 	return loginCmd
 }
 
-func login(cmd *cobra.Command, args []string) {
+func login(cmd *cobra.Command, args []string) error {
+	if noBrowser, _ := cmd.Flags().GetBool("no-browser"); noBrowser {
+		api.FlagHeadlessLogin = true
+	}
 	if err := api.Login(); err != nil {
-		log.Fatalf("Login failed: %v", err)
+		return fmt.Errorf("login failed: %w", err)
 	}
 	output.PrintCmdStatus(cmd, "Login completed successfully.\n")
+	return nil
 }
@@ -0,0 +1,147 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logout
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+// logoutCmd represents the logout command
+var logoutCmd = &cobra.Command{
+	Use:   "logout [--all-profiles]",
+	Short: "Log out of the current profile (or all profiles), removing its saved tokens",
+	Long: `This command ends the session of the current profile by removing its access and refresh tokens from
+the config file (or the profile's credential store). For the oauth auth method, the refresh token is first revoked
+at the auth server, if the server supports revocation, so that the session cannot be used anymore even if a copy
+of the tokens exists elsewhere. For other auth methods, such as service principals, the saved token is simply dropped;
+the next command will log in again.
+
+Use --all-profiles to log out of all profiles in the config file.`,
+	Example: `
+  fsoc logout
+  fsoc logout --profile prod
+  fsoc logout --all-profiles`,
+	Args:             cobra.NoArgs,
+	Run:              logout,
+	Annotations:      map[string]string{config.AnnotationForConfigBypass: ""},
+	TraverseChildren: true,
+}
+
+// logoutResult is what was done for each profile
+type logoutResult struct {
+	Profile    string   `json:"profile"`
+	AuthMethod string   `json:"authMethod"`
+	Cleared    []string `json:"cleared"`
+	Revocation string   `json:"revocation"` // "revoked", "not-supported", "not-applicable" or "failed"
+	Error      string   `json:"error,omitempty"`
+}
+
+func init() {
+	logoutCmd.Flags().Bool("all-profiles", false, "Log out of all profiles in the config file")
+}
+
+func NewSubCmd() *cobra.Command {
+	return logoutCmd
+}
+
+func logout(cmd *cobra.Command, args []string) {
+	var profiles []string
+	if all, _ := cmd.Flags().GetBool("all-profiles"); all {
+		profiles = config.ListAllContexts()
+		if len(profiles) == 0 {
+			log.Fatalf("No profiles found in the config file")
+		}
+	} else {
+		name := config.GetCurrentProfileName()
+		if _, err := config.GetStoredContext(name); err != nil {
+			log.Fatalf("Cannot log out: %v", err)
+		}
+		profiles = []string{name}
+	}
+
+	results := []logoutResult{}
+	for _, name := range profiles {
+		results = append(results, logoutProfile(name))
+	}
+
+	var lines [][]string
+	for _, r := range results {
+		cleared := strings.Join(r.Cleared, ", ")
+		if cleared == "" {
+			cleared = "(nothing)"
+		}
+		revocation := r.Revocation
+		if r.Error != "" {
+			revocation += ": " + r.Error
+		}
+		lines = append(lines, []string{r.Profile, r.AuthMethod, cleared, revocation})
+	}
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []logoutResult `json:"items"`
+		Total int            `json:"total"`
+	}{results, len(results)}, &output.Table{
+		Headers: []string{"Profile", "Auth Method", "Cleared", "Revocation"},
+		Lines:   lines,
+	})
+}
+
+// logoutProfile revokes the profile's refresh token, if applicable, and clears its tokens
+func logoutProfile(name string) logoutResult {
+	result := logoutResult{Profile: name, Cleared: []string{}, Revocation: "not-applicable"}
+
+	ctx, err := config.GetContext(name)
+	if err != nil {
+		result.Revocation = "failed"
+		result.Error = err.Error()
+		return result
+	}
+	result.AuthMethod = ctx.AuthMethod
+
+	// revoke first, while the refresh token is still known; clear the tokens even if revocation fails
+	revoked, err := api.RevokeRefreshToken(ctx)
+	switch {
+	case errors.Is(err, api.ErrRevocationNotSupported):
+		result.Revocation = "not-supported"
+		log.WithField("profile", name).Info("The auth server does not support token revocation; clearing the tokens only")
+	case err != nil:
+		result.Revocation = "failed"
+		result.Error = err.Error()
+		log.Warnf("Failed to revoke the refresh token of profile %q: %v; clearing the tokens anyway", name, err)
+	case revoked:
+		result.Revocation = "revoked"
+	}
+
+	previous, err := config.ClearTokens(name)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to clear tokens: %v", err)
+		return result
+	}
+	if previous.Token != "" {
+		result.Cleared = append(result.Cleared, "access token")
+	}
+	if previous.RefreshToken != "" {
+		result.Cleared = append(result.Cleared, "refresh token")
+	}
+	return result
+}
@@ -15,6 +15,8 @@
 package logs
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -149,7 +151,7 @@ func fetchLogs(cmd *cobra.Command, args []string) error {
 	printLogs(resp, formatter, cmd)
 
 	if follow {
-		return followLogs(resp, formatter, variables.Count, cmd)
+		return followLogs(cmd.Context(), extractEventDataSet(resp), formatter, variables.Count, cmd)
 	}
 
 	return nil
@@ -160,18 +162,28 @@ type eventResult struct {
 	err  error
 }
 
-func followLogs(initialResponse *uql.Response, formatter rowFormatter, limit int, p printer) error {
+func followLogs(ctx context.Context, initialDataSet *uql.DataSet, formatter rowFormatter, limit int, p printer) error {
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
 	eventResults := make(chan eventResult, 1)
-	eventResults <- eventResult{data: extractEventDataSet(initialResponse)}
+	eventResults <- eventResult{data: initialDataSet}
 
 	for {
+		// stop following when interrupted (not a failure) or timed out (see --timeout)
+		if ctx.Err() != nil {
+			return followDone(ctx)
+		}
+
 		select {
 		case <-interrupt:
 			return nil
+		case <-ctx.Done():
+			return followDone(ctx)
 		case followResult := <-eventResults:
 			if followResult.err != nil {
+				if ctx.Err() != nil {
+					return followDone(ctx) // the call was aborted
+				}
 				log.Fatal(followResult.err.Error())
 			}
 
@@ -201,6 +213,16 @@ func followLogs(initialResponse *uql.Response, formatter rowFormatter, limit int
 	}
 }
 
+// followDone returns the error for ending to follow the logs when the context is done: nil if
+// the command was interrupted, since that is how following is normally ended, or the context's
+// error if it timed out
+func followDone(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("stopped following logs: %w", ctx.Err())
+	}
+	return nil
+}
+
 func queryLogs(query string) (*uql.Response, error) {
 	resp, err := uql.Client.ExecuteQuery(&uql.Query{Str: query})
 	if err != nil {
@@ -0,0 +1,43 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testPrinter struct{}
+
+func (testPrinter) Println(v ...any)               { fmt.Println(v...) }
+func (testPrinter) Printf(format string, i ...any) { fmt.Printf(format, i...) }
+
+func TestFollowLogsTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	err := followLogs(ctx, nil, nil, 100, testPrinter{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestFollowLogsInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Nil(t, followLogs(ctx, nil, nil, 100, testPrinter{}))
+}
@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 
 	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/platform/api"
 )
 
 var meltMelitiniCmd = &cobra.Command{
@@ -37,8 +39,13 @@ func meltMeltini(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	// Send a POST request with the JSON data
-	response, err := http.Post(meltiniURL, "application/json", bytes.NewBuffer(jsonData))
+	// Send a POST request with the JSON data (using the profile's transport settings)
+	client, err := api.NewHTTPClient(config.GetCurrentContext())
+	if err != nil {
+		fmt.Println("Error setting up connection to meltini:", err)
+		return
+	}
+	response, err := client.Post(meltiniURL, "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
 		fmt.Println("Error sending request to meltini:", err)
 		return
@@ -65,11 +65,10 @@ func meltSendWithUsageCheck(cmd *cobra.Command, args []string) error {
 	}
 
 	// process command
-	meltSend(cmd, args)
-	return nil
+	return meltSend(cmd, args)
 }
 
-func meltSend(cmd *cobra.Command, args []string) {
+func meltSend(cmd *cobra.Command, args []string) error {
 	// Make this tolerate empty arg list, in which case it should use stdin
 	var dataFileName string
 	if len(args) > 0 {
@@ -77,13 +76,13 @@ func meltSend(cmd *cobra.Command, args []string) {
 	} else {
 		output.PrintCmdStatus(cmd, "Reading MELT data from STDIN\n")
 	}
-	sendDataFromFile(cmd, dataFileName)
+	return sendDataFromFile(cmd, dataFileName)
 }
 
-func sendDataFromFile(cmd *cobra.Command, dataFileName string) {
+func sendDataFromFile(cmd *cobra.Command, dataFileName string) error {
 	fsoData, err := loadDataFile(dataFileName)
 	if err != nil {
-		log.Fatalf("Can't open data file %q: %v", dataFileName, err)
+		return fmt.Errorf("can't open data file %q: %w", dataFileName, err)
 	}
 
 	for _, entity := range fsoData.Melt {
@@ -168,14 +167,16 @@ func sendDataFromFile(cmd *cobra.Command, dataFileName string) {
 		}
 	}
 
-	exportMeltStraight(cmd, fsoData)
+	return exportMeltStraight(cmd, fsoData)
 }
 
-func exportMeltStraight(cmd *cobra.Command, fsoData *melt.FsocData) {
-	exportMelt(cmd, *fsoData)
+func exportMeltStraight(cmd *cobra.Command, fsoData *melt.FsocData) error {
+	return exportMelt(cmd, *fsoData)
 }
 
-func exportMelt(cmd *cobra.Command, fsoData melt.FsocData) {
+// exportMelt sends the MELT data (or dumps it); the returned error wraps the API call's error, if any,
+// so that it can be classified (see api.ErrorKindOf)
+func exportMelt(cmd *cobra.Command, fsoData melt.FsocData) error {
 	// construct the exporter with options from the command line
 	exp := &melt.Exporter{}
 	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
@@ -207,24 +208,25 @@ func exportMelt(cmd *cobra.Command, fsoData melt.FsocData) {
 	output.PrintCmdStatus(cmd, formatSection("Metrics", format))
 	err := exp.ExportMetrics(fsoData.Melt)
 	if err != nil {
-		log.Fatalf("Error exporting metrics: %s", err)
+		return fmt.Errorf("error exporting metrics: %w", err)
 	}
 
 	output.PrintCmdStatus(cmd, formatSection("Logs", format))
 	err = exp.ExportLogs(fsoData.Melt)
 	if err != nil {
-		log.Fatalf("Error exporting logs: %s", err)
+		return fmt.Errorf("error exporting logs: %w", err)
 	}
 
 	output.PrintCmdStatus(cmd, formatSection("Spans", format))
 	err = exp.ExportSpans(fsoData.Melt)
 	if err != nil {
-		log.Fatalf("Error exporting spans: %s", err)
+		return fmt.Errorf("error exporting spans: %w", err)
 	}
 
 	if !dump {
 		output.PrintCmdStatus(cmd, "\nMELT data sent (see log for traceresponse ID)\n")
 	}
+	return nil
 }
 
 func loadDataFile(fileName string) (*melt.FsocData, error) {
@@ -73,6 +73,5 @@ func listStatus(cmd *cobra.Command, args []string) error {
 	objStoreUrl := getKnowledgeURL(cmd, "status", "data.optimizer")
 
 	headers := getOrionTenantHeaders()
-	cmdkit.FetchAndPrint(cmd, objStoreUrl, &cmdkit.FetchAndPrintOptions{Headers: headers, IsCollection: true})
-	return nil
+	return cmdkit.FetchAndPrint(cmd, objStoreUrl, &cmdkit.FetchAndPrintOptions{Headers: headers, IsCollection: true})
 }
@@ -0,0 +1,276 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+const defaultMaxParallel = 4
+
+// profilesError is returned when a command run for multiple profiles failed for some of them
+type profilesError struct {
+	failed []string
+	total  int
+	kind   api.ErrorKind
+}
+
+func (e *profilesError) Error() string {
+	return fmt.Sprintf("the command failed for %d of %d profiles: %v", len(e.failed), e.total, strings.Join(e.failed, ", "))
+}
+
+// selectedProfiles returns the profiles selected with the --profiles or --all-profiles flags, or
+// nil if the command is to be run for a single profile
+func selectedProfiles(cmd *cobra.Command) ([]string, error) {
+	if all, _ := cmd.Flags().GetBool("all-profiles"); all {
+		profiles := config.ListAllContexts()
+		if len(profiles) == 0 {
+			return nil, fmt.Errorf("there are no profiles in the config file")
+		}
+		return profiles, nil
+	}
+	list, _ := cmd.Flags().GetStringSlice("profiles")
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	profiles := []string{}
+	missing := []string{}
+	for _, name := range list {
+		name = strings.TrimSpace(name)
+		if name == "" || slices.Contains(profiles, name) {
+			continue
+		}
+		if _, err := config.GetStoredContext(name); err != nil {
+			missing = append(missing, name)
+		}
+		profiles = append(profiles, name)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("could not find profile(s) %v", strings.Join(missing, ", "))
+	}
+	return profiles, nil
+}
+
+// setUpProfilesRun replaces the command's handler with one that runs the original handler for each
+// of the profiles (see runForProfiles). Only commands annotated as able to run for multiple profiles
+// are supported, as the others use the current profile directly.
+func setUpProfilesRun(cmd *cobra.Command, profiles []string, handler *fatalHandler) {
+	run := cmd.RunE
+	if run == nil && cmd.Run != nil {
+		runFunc := cmd.Run
+		run = func(c *cobra.Command, args []string) error {
+			runFunc(c, args)
+			return nil
+		}
+	}
+	cmd.Run = nil
+	cmd.SilenceUsage = true  // failures are for profiles, not for the command line
+	cmd.SilenceErrors = true // reported by ReportError
+
+	if _, found := cmd.Annotations[config.AnnotationForMultipleProfiles]; !found || run == nil {
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			return &usageError{err: fmt.Errorf("the %q command cannot be run for multiple profiles", cmd.CommandPath())}
+		}
+		return
+	}
+
+	// subsystem settings (e.g., API versions) apply to all profiles, so they must be the same
+	if err := loadCommonSubsystemConfigs(profiles); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	maxParallel, _ := cmd.Flags().GetInt("max-parallel")
+	pr := &profilesRun{run: run, profiles: profiles, maxParallel: maxParallel, handler: handler}
+	cmd.RunE = pr.execute
+}
+
+// loadCommonSubsystemConfigs applies the subsystem settings of the profiles, failing if they are not
+// the same for all profiles
+func loadCommonSubsystemConfigs(profiles []string) error {
+	var first *config.Context
+	for _, name := range profiles {
+		profile, err := config.GetStoredContext(name)
+		if err != nil {
+			return err
+		}
+		if first == nil {
+			first = profile
+		} else if !reflect.DeepEqual(first.SubsystemConfigs, profile.SubsystemConfigs) {
+			return fmt.Errorf("profiles %q and %q have different subsystem settings, which is not supported when running a command for multiple profiles; please run the command for them separately", first.Name, profile.Name)
+		}
+	}
+	if err := config.UpdateSubsystemConfigs(first); err != nil {
+		return fmt.Errorf("failed to parse subsystem configurations in profile %q: %w", first.Name, err)
+	}
+	return nil
+}
+
+// profilesRun runs a command's handler for each of multiple profiles
+type profilesRun struct {
+	run         func(*cobra.Command, []string) error // the command's handler
+	profiles    []string
+	maxParallel int
+	handler     *fatalHandler // may be nil (in tests)
+}
+
+// execute runs the command for each of the profiles, up to maxParallel at a time, and displays
+// the combined output. A failure for one profile does not stop the others; the failures are reported
+// and the command fails once all profiles are done.
+func (pr *profilesRun) execute(cmd *cobra.Command, args []string) error {
+	maxParallel := pr.maxParallel
+	if maxParallel <= 0 {
+		maxParallel = defaultMaxParallel
+	}
+	log.WithFields(log.Fields{"profiles": pr.profiles, "max_parallel": maxParallel}).Info("Running the command for multiple profiles")
+
+	// fatal failures end only the profile's run, see runForProfile
+	if pr.handler != nil {
+		pr.handler.panicOnFatal = true
+	}
+	stdin := &sharedInput{source: cmd.InOrStdin()}
+	results := make([]output.ProfileResult, len(pr.profiles))
+	semaphore := make(chan struct{}, maxParallel)
+	var wg sync.WaitGroup
+	for i, profile := range pr.profiles {
+		wg.Add(1)
+		go func(i int, profile string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			results[i] = runForProfile(cmd, pr.run, args, profile, stdin)
+		}(i, profile)
+	}
+	wg.Wait()
+	if pr.handler != nil {
+		pr.handler.panicOnFatal = false
+	}
+
+	failed := []string{}
+	var kind api.ErrorKind
+	for _, result := range results {
+		report, ok := result.Error.(*api.ErrorReport)
+		if !ok {
+			continue
+		}
+		log.Errorf("Profile %q: %v", result.Profile, report.Message)
+		failed = append(failed, result.Profile)
+		if kind == "" || kind == report.Kind {
+			kind = report.Kind
+		} else {
+			kind = api.ErrorKindGeneral // different failures
+		}
+	}
+
+	output.PrintProfileResults(cmd, results)
+
+	if len(failed) > 0 {
+		return &profilesError{failed: failed, total: len(pr.profiles), kind: kind}
+	}
+	return nil
+}
+
+// runForProfile runs the command's handler for a single profile, with a platform API client for the
+// profile. The handler gets a copy of the command whose Go context selects the profile and captures
+// the output, so that it can be combined with the output for the other profiles. Each run reads the
+// same input (e.g., a request body from stdin), which is read only once.
+func runForProfile(cmd *cobra.Command, run func(*cobra.Command, []string) error, args []string, name string, stdin *sharedInput) output.ProfileResult {
+	result := output.ProfileResult{Profile: name}
+	profile, err := config.GetContext(name)
+	if err != nil {
+		result.Error = api.NewErrorReport(err)
+		return result
+	}
+
+	ctx := config.WithCurrentContext(cmd.Context(), profile)
+	ctx = api.WithClient(ctx, api.NewConfigClient(profile, ctx))
+	capture := &output.Capture{}
+	ctx = output.WithCapture(ctx, capture)
+	var text bytes.Buffer
+	profileCmd := *cmd // nb: shares the flags and the parent command
+	profileCmd.SetContext(ctx)
+	profileCmd.SetOut(&text)
+	profileCmd.SetIn(stdin.reader())
+
+	err = runRecoveringFatal(&profileCmd, run, args)
+	result.Records = capture.Records()
+	result.Text = text.String()
+	var fatal *fatalError
+	if errors.As(err, &fatal) {
+		result.Error = fatalReport(fatal.message, ctx.Err())
+	} else if err != nil {
+		result.Error = api.NewErrorReport(err)
+	}
+	return result
+}
+
+// sharedInput provides the same input to each of the runs for multiple profiles; the source is read
+// in full on first use, so that commands that don't read their input don't wait for it
+type sharedInput struct {
+	source io.Reader
+	once   sync.Once
+	data   []byte
+	err    error
+}
+
+// reader returns a reader of the whole input
+func (s *sharedInput) reader() io.Reader {
+	return &sharedInputReader{input: s}
+}
+
+type sharedInputReader struct {
+	input  *sharedInput
+	reader *bytes.Reader
+}
+
+func (r *sharedInputReader) Read(p []byte) (int, error) {
+	if r.reader == nil {
+		r.input.once.Do(func() { r.input.data, r.input.err = io.ReadAll(r.input.source) })
+		if r.input.err != nil {
+			return 0, r.input.err
+		}
+		r.reader = bytes.NewReader(r.input.data)
+	}
+	return r.reader.Read(p)
+}
+
+// runRecoveringFatal runs the command's handler, returning a *fatalError if it fails with log.Fatal
+// (see fatalHandler)
+func runRecoveringFatal(cmd *cobra.Command, run func(*cobra.Command, []string) error, args []string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			fatal, ok := r.(*fatalError)
+			if !ok {
+				panic(r)
+			}
+			err = fatal
+		}
+	}()
+	return run(cmd, args)
+}
@@ -0,0 +1,131 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/memory"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+func TestRunForProfiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/dev/things" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"items":[{"id":"a"},{"id":"b"}],"total":2}`))
+	}))
+	defer server.Close()
+
+	viper.Reset()
+	defer viper.Reset()
+	viper.SetConfigFile(filepath.Join(t.TempDir(), "config.yaml"))
+	viper.SetConfigType("yaml")
+	for _, name := range []string{"dev", "prod", "broken"} {
+		require.NoError(t, config.UpsertContext(&config.Context{Name: name, AuthMethod: config.AuthMethodNone, URL: server.URL + "/" + name}))
+	}
+
+	// fatal failures end only the profile's run
+	handler := &fatalHandler{cliHandler: memory.New(), errOut: &bytes.Buffer{}, exit: func(code int) { t.Fatalf("exited with %d", code) }}
+	logger := log.Log.(*log.Logger)
+	savedHandler := logger.Handler
+	log.SetHandler(handler)
+	defer log.SetHandler(savedHandler)
+
+	// the command uses the profile and the platform API client from its Go context
+	cmd := &cobra.Command{Use: "things", RunE: func(cmd *cobra.Command, args []string) error {
+		profile := config.CurrentContext(cmd.Context())
+		if profile.Name == "broken" {
+			log.Fatalf("profile %v is broken", profile.Name)
+		}
+		var out any
+		if err := api.JSONGet("/things", &out, &api.Options{Context: cmd.Context()}); err != nil {
+			return err
+		}
+		output.PrintCmdOutput(cmd, out)
+		cmd.Printf("done with %v\n", profile.Name)
+		return nil
+	}}
+	cmd.Flags().String("output", "json", "")
+	cmd.SetContext(context.Background())
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	pr := &profilesRun{run: cmd.RunE, profiles: []string{"dev", "prod", "broken"}, maxParallel: 2, handler: handler}
+	err := pr.execute(cmd, nil)
+	var profilesErr *profilesError
+	require.True(t, errors.As(err, &profilesErr))
+	assert.Equal(t, []string{"prod", "broken"}, profilesErr.failed)
+	assert.Equal(t, api.ErrorKindGeneral, profilesErr.kind) // different failures
+	assert.False(t, handler.panicOnFatal)
+
+	var results []map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &results))
+	require.Len(t, results, 3)
+	assert.Equal(t, "dev", results[0]["profile"])
+	assert.Equal(t, 2.0, results[0]["output"].(map[string]any)["total"])
+	assert.Equal(t, "done with dev\n", results[0]["text"])
+	assert.Equal(t, "not_found", results[1]["error"].(map[string]any)["kind"])
+	assert.Equal(t, "profile broken is broken", results[2]["error"].(map[string]any)["message"])
+}
+
+func TestSetUpProfilesRun(t *testing.T) {
+	// commands that don't support multiple profiles fail with a usage error
+	cmd := &cobra.Command{Use: "things", Run: func(cmd *cobra.Command, args []string) {}}
+	setUpProfilesRun(cmd, []string{"dev", "prod"}, nil)
+	err := cmd.RunE(cmd, nil)
+	var usageErr *usageError
+	assert.True(t, errors.As(err, &usageErr))
+	assert.Nil(t, cmd.Run)
+}
+
+func TestSharedInput(t *testing.T) {
+	// each of the concurrent runs reads the whole input
+	stdin := &sharedInput{source: strings.NewReader(`{"name":"test"}`)}
+	var wg sync.WaitGroup
+	bodies := make([]string, 4)
+	for i := range bodies {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data, err := io.ReadAll(stdin.reader())
+			assert.NoError(t, err)
+			bodies[i] = string(data)
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, []string{`{"name":"test"}`, `{"name":"test"}`, `{"name":"test"}`, `{"name":"test"}`}, bodies)
+}
@@ -17,6 +17,7 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path"
@@ -26,7 +27,6 @@ import (
 	"github.com/Masterminds/semver/v3"
 	"github.com/apex/log"
 	"github.com/apex/log/handlers/json"
-	"github.com/apex/log/handlers/multi"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
@@ -51,6 +51,9 @@ const (
 
 var updateChannel chan *semver.Version
 
+// cancelTimeout releases the resources associated with the --timeout deadline, if one is set
+var cancelTimeout context.CancelFunc
+
 // rootCmd represents the base command when called without any subcommands
 // TODO: replace github link "for more info" with Cisco DevNet link for fsoc once published
 var rootCmd = &cobra.Command{
@@ -67,9 +70,42 @@ You can use the --config and --profile flags to select authentication credential
 environment variables FSOC_CONFIG and FSOC_PROFILE, respectively. The command line flags take precedence.
 If a profile is not specified otherwise, the current profile from the config file is used.
 
+Without a config file, fsoc can use a profile defined by environment variables, e.g., in CI pipelines: set
+FSOC_AUTH_METHOD, FSOC_URL and the other variables listed by "fsoc config show-fields" (e.g., FSOC_SECRET_FILE,
+FSOC_SUBSYSTEM_UQL_APIVER). The profile is kept in memory only; tokens obtained by logging in are not saved.
+
 fsoc checks once a day if a newer version is available on github and warns if not running the latest stable version.
 You can use the --no-version-check flag or the FSOC_NO_VERSION_CHECK=1 environment variable to suppress the check.
 
+You can use the --timeout flag to limit how long a command may take, including any platform API calls it makes.
+Interrupting fsoc (e.g., with Ctrl-C) cancels the platform API calls in progress and ends the command if it doesn't
+stop within a couple of seconds; interrupt again to exit immediately.
+
+fsoc exits with status 0 on success and with the following status codes on failure, so that scripts can
+react to the cause: 1 - general failure, 2 - invalid command line, 3 - authentication failed (incl. login),
+4 - permission denied, 5 - not found, 6 - conflict, 7 - invalid request (validation), 8 - throttled,
+9 - server error, 10 - network failure, 11 - timed out, 130 - interrupted. You can use --error-format=json
+to report failures on stderr as a JSON object with the kind of failure, its exit code and message, and the
+problem details returned by the platform (status, type, title, detail and errors), if any.
+
+You can use the --profiles flag (or --all-profiles) to run a command for several profiles at once, e.g., to list
+the solutions of several tenants. The command runs for up to --max-parallel profiles concurrently and the results
+are combined, with a Profile column in tables and a "profile" key in JSON and YAML. A failure for one profile does
+not stop the others; fsoc reports the failures and exits with a failure status after all profiles are done.
+Read-only commands such as "solution list", "uql", "knowledge get", "iam-role-binding list" and "api" support
+multiple profiles, provided that the profiles have the same subsystem settings (e.g., API versions).
+
+You can use the --record flag to save the platform API traffic of a command (incl. logins) into a cassette file,
+with credentials and tokens redacted, and the --replay flag to run the command again offline from that file.
+You can use the --har flag to save all HTTP requests and responses of a command (incl. logins), with their
+timings, headers and bodies, into an HTTP Archive (HAR) file, e.g., to share a failing command's session with
+support; credentials and tokens are redacted. HAR files can be viewed in browser developer tools.
+
+fsoc can trace its own operations (commands, platform API calls, logins, etc.) with OpenTelemetry, e.g., to find
+out why a command is slow; see the trace-exporter setting in "fsoc config show-fields". The trace context is
+propagated to the platform in the traceparent header; set the TRACEPARENT environment variable to make fsoc's
+trace part of an existing trace, e.g., of a CI pipeline.
+
 fsoc logs its execution details into a log file. By default, fsoc shows only warning- and error-level log messages on 
 the output. You can use the --verbose flag to show all log messages and/or the --log flag to set a desired location
 for saving the log file.
@@ -85,6 +121,7 @@ NOTE: fsoc is in alpha; breaking changes may occur.`,
   fsoc uql "FETCH id, type, attributes FROM entities(k8s:workload)"
   fsoc solution list
   fsoc solution list -o json
+  fsoc solution list --profiles dev,test,prod
   FSOC_CONFIG=tenant5-config.yaml fsoc solution subscribe spacefleet --profile admin`,
 
 	PersistentPreRun:  preExecHook,
@@ -96,6 +133,7 @@ NOTE: fsoc is in alpha; breaking changes may occur.`,
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute(ctx context.Context) error {
+	markUsageErrors(rootCmd)
 	return rootCmd.ExecuteContext(ctx)
 }
 
@@ -108,12 +146,23 @@ func init() {
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", fmt.Sprintf("config file (default is %s). May be .yaml or .json", config.DefaultConfigFile))
 	rootCmd.PersistentFlags().StringVar(&cfgProfile, "profile", "", "access profile (default is current or \"default\")")
-	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "auto", "output format (auto, table, detail, json, yaml)")
+	rootCmd.PersistentFlags().StringSlice("profiles", nil, "run the command for each of the listed profiles, e.g., --profiles dev,test,prod")
+	rootCmd.PersistentFlags().Bool("all-profiles", false, "run the command for each of the profiles in the config file")
+	rootCmd.PersistentFlags().Int("max-parallel", defaultMaxParallel, "maximum number of profiles to run the command for concurrently, with --profiles or --all-profiles")
+	rootCmd.MarkFlagsMutuallyExclusive("profile", "profiles", "all-profiles")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "auto", "output format (auto, table, detail, json, yaml, ndjson)")
 	rootCmd.PersistentFlags().String("fields", "", "perform specified fields transform/extract JQ expression")
 	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "enable detailed output")
 	rootCmd.PersistentFlags().Bool("curl", false, "log curl equivalent for platform API calls (implies --verbose)")
 	rootCmd.PersistentFlags().String("log", path.Join(os.TempDir(), "fsoc.log"), "set a location and name for the fsoc log file")
 	rootCmd.PersistentFlags().Bool("no-version-check", false, "skip the daily check for new versions of fsoc")
+	rootCmd.PersistentFlags().Duration("timeout", 0, "maximum time for the command to complete, e.g., 30s or 5m (default no timeout)")
+	rootCmd.PersistentFlags().String("record", "", "record platform API requests and responses into a cassette file (secrets are redacted)")
+	rootCmd.PersistentFlags().String("replay", "", "serve platform API requests from a cassette file recorded with --record, without network access")
+	rootCmd.MarkFlagsMutuallyExclusive("record", "replay")
+	rootCmd.PersistentFlags().String("har", "", "save the HTTP requests and responses of the command, with timings, into a HAR file (secrets are redacted)")
+	rootCmd.PersistentFlags().Var(&errorFormatValue{}, "error-format", "format for reporting failures on stderr: text or json")
+	rootCmd.SetFlagErrorFunc(flagUsageError)
 	rootCmd.SetOut(os.Stdout)
 	rootCmd.SetErr(os.Stderr)
 	rootCmd.SetIn(os.Stdin)
@@ -167,6 +216,18 @@ func registerSubSystemWithConfig(cmd *cobra.Command, store any) {
 	}
 }
 
+// subsystemName returns the name of the subsystem (top-level command) that cmd belongs to,
+// or an empty string for the root command
+func subsystemName(cmd *cobra.Command) string {
+	if !cmd.HasParent() {
+		return ""
+	}
+	for cmd.Parent().HasParent() {
+		cmd = cmd.Parent()
+	}
+	return cmd.Name()
+}
+
 func helperFlagFormatter(fs *pflag.FlagSet) string {
 	s := ""
 	if fs != nil {
@@ -202,13 +263,13 @@ func preExecHook(cmd *cobra.Command, args []string) {
 
 	_ = os.Truncate(logLocation, 0)
 	file, err := os.Create(logLocation)
+	handler := &fatalHandler{cliHandler: cliHandler, errOut: os.Stderr, exit: os.Exit}
 	if err != nil {
 		log.Warnf("failed to create log at %s", logLocation)
-		log.SetHandler(cliHandler)
 	} else {
-		jsonHandler := json.New(file)
-		log.SetHandler(multi.New(cliHandler, jsonHandler))
+		handler.fileHandler = json.New(file)
 	}
+	log.SetHandler(handler)
 
 	log.WithFields(version.GetVersion()).Info("fsoc version")
 
@@ -218,14 +279,69 @@ func preExecHook(cmd *cobra.Command, args []string) {
 		"flags":     helperFlagFormatter(cmd.Flags())}).
 		Info("fsoc command line")
 
+	// set up the Go context for platform API calls, applying the command timeout, if one is specified
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if timeout, _ := cmd.Flags().GetDuration("timeout"); timeout > 0 {
+		ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+		cmd.SetContext(ctx)
+	}
+	handler.ctx = ctx // for classifying fatal failures caused by a timeout or interruption
+	api.SetDefaultContext(ctx)
+	api.SetDefaultSubsystem(subsystemName(cmd))
+
+	// set up recording or replay of platform API traffic, if requested
+	if recordFile, _ := cmd.Flags().GetString("record"); recordFile != "" {
+		if err := api.StartRecording(recordFile); err != nil {
+			log.Fatalf("Failed to start recording: %v", err)
+		}
+	}
+	if replayFile, _ := cmd.Flags().GetString("replay"); replayFile != "" {
+		if err := api.StartReplay(replayFile); err != nil {
+			log.Fatalf("Failed to start replay: %v", err)
+		}
+	}
+	if harFile, _ := cmd.Flags().GetString("har"); harFile != "" {
+		if err := api.StartHAR(harFile, version.GetVersionShort()); err != nil {
+			log.Fatalf("Failed to start the HAR capture: %v", err)
+		}
+	}
+
 	// Determine if a configured profile is required for this command
 	// (bypassed only for commands that must work or can safely work without it)
 	bypass := bypassConfig(cmd) || cmd.Name() == "help" || isCompletionCommand(cmd)
+	if _, found := cmd.Annotations[config.AnnotationForSchemaUpgradeBypass]; found {
+		config.SetAutoUpgrade(false) // the command handles the config file's schema itself
+	}
 
 	// try to read the config file.and profile
 	err = viper.ReadInConfig()
 	if err != nil && !bypass {
-		log.Fatalf("fsoc is not configured, please use \"fsoc config set\" to configure an initial context")
+		// without a config file, use the profile defined by environment variables, if any
+		envCtx, envErr := config.ContextFromEnv()
+		if envErr != nil {
+			log.Fatalf("%v", envErr)
+		}
+		if envCtx == nil || !configFileNotFound(err) {
+			log.Fatalf("fsoc is not configured, please use \"fsoc config set\" to configure an initial context")
+		}
+		config.SetEphemeralContext(envCtx)
+		log.Infof("Unable to read config file (%v), using profile %q defined by environment variables", err, envCtx.Name)
+		err = nil
+	}
+
+	// run the command for each of multiple profiles, if requested
+	if !bypass {
+		profiles, profilesErr := selectedProfiles(cmd)
+		if profilesErr != nil {
+			log.Fatalf("%v", profilesErr)
+		}
+		if profiles != nil {
+			setUpProfilesRun(cmd, profiles, handler)
+			return
+		}
 	}
 
 	// override the config file's current profile from cmd line or env var
@@ -248,12 +364,14 @@ func preExecHook(cmd *cobra.Command, args []string) {
 				log.Fatalf("Failed to parse subsystem configurations in profile %q of config file %q: %v", profile, viper.ConfigFileUsed(), err)
 			}
 			customSubsysConfigs = maps.Keys(cfg.SubsystemConfigs)
+			startTracing(cmd, cfg)
 		}
 		log.WithFields(log.Fields{
 			"config_file":    viper.ConfigFileUsed(),
 			"profile":        profile,
 			"existing":       exists,
 			"custom_configs": customSubsysConfigs,
+			"ephemeral":      config.IsEphemeral(),
 		}).Info("fsoc context")
 	}
 
@@ -267,6 +385,11 @@ func preExecHook(cmd *cobra.Command, args []string) {
 }
 
 func postExecHook(cmd *cobra.Command, args []string) {
+	finishTracing(nil)
+	if cancelTimeout != nil {
+		cancelTimeout()
+	}
+
 	latestVersion := completeVersionCheck()
 	if versionCheckEnabled(cmd) {
 		reportNewVersionAvailable(latestVersion)
@@ -290,6 +413,12 @@ func bypassConfig(cmd *cobra.Command) bool {
 	return bypassConfig
 }
 
+// configFileNotFound returns true if the config file could not be read because it doesn't exist
+func configFileNotFound(err error) bool {
+	var notFound viper.ConfigFileNotFoundError
+	return errors.As(err, &notFound) || errors.Is(err, os.ErrNotExist)
+}
+
 func isCompletionCommand(cmd *cobra.Command) bool {
 	p := cmd.Parent()
 	return (p != nil && p.Name() == "completion")
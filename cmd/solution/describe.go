@@ -1,6 +1,8 @@
 package solution
 
 import (
+	"fmt"
+
 	"github.com/apex/log"
 	"github.com/spf13/cobra"
 
@@ -15,7 +17,10 @@ var solutionDescribeCmd = &cobra.Command{
 	Short:   "Describe solution",
 	Long:    `Obtain metadata about a solution`,
 	Example: `  fsoc solution describe spacefleet`,
-	Run:     solutionDescribe,
+	RunE:    solutionDescribe,
+	Annotations: map[string]string{
+		config.AnnotationForMultipleProfiles: "",
+	},
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		config.SetActiveProfile(cmd, args, false)
 		return getSolutionNames(toComplete), cobra.ShellCompDirectiveDefault
@@ -30,10 +35,10 @@ func getSolutionDescribeCmd() *cobra.Command {
 	return solutionDescribeCmd
 }
 
-func solutionDescribe(cmd *cobra.Command, args []string) {
+func solutionDescribe(cmd *cobra.Command, args []string) error {
 	solution := getSolutionNameFromArgs(cmd, args, "solution")
 
-	cfg := config.GetCurrentContext()
+	cfg := config.CurrentContext(cmd.Context())
 	layerID := cfg.Tenant
 
 	headers := map[string]string{
@@ -43,9 +48,10 @@ func solutionDescribe(cmd *cobra.Command, args []string) {
 
 	log.WithField("solution", solution).Info("Getting solution details")
 	var res Solution
-	err := api.JSONGet(getSolutionObjectUrl(solution), &res, &api.Options{Headers: headers})
+	err := api.JSONGet(getSolutionObjectUrl(solution), &res, &api.Options{Headers: headers, Context: cmd.Context()})
 	if err != nil {
-		log.Fatalf("Cannot get solution details: %v", err)
+		return fmt.Errorf("cannot get solution details: %w", err)
 	}
 	output.PrintCmdOutput(cmd, res)
+	return nil
 }
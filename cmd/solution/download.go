@@ -16,7 +16,6 @@ package solution
 import (
 	"fmt"
 
-	"github.com/apex/log"
 	"github.com/spf13/cobra"
 
 	"github.com/cisco-open/fsoc/config"
@@ -30,7 +29,7 @@ var solutionDownloadCmd = &cobra.Command{
 	Short:            "Download solution",
 	Long:             `This downloads the indicated solution into the current directory. Also see the "fork" command.`,
 	Example:          `  fsoc solution download spacefleet`,
-	Run:              downloadSolution,
+	RunE:             downloadSolution,
 	TraverseChildren: true,
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		config.SetActiveProfile(cmd, args, false)
@@ -46,7 +45,7 @@ func getSolutionDownloadCmd() *cobra.Command {
 	return solutionDownloadCmd
 }
 
-func downloadSolution(cmd *cobra.Command, args []string) {
+func downloadSolution(cmd *cobra.Command, args []string) error {
 	solutionName := getSolutionNameFromArgs(cmd, args, "name")
 	solutionNameWithZipExtension := getSolutionNameWithZip(solutionName)
 	solutionTagFlag, _ := cmd.Flags().GetString("tag")
@@ -59,11 +58,12 @@ func downloadSolution(cmd *cobra.Command, args []string) {
 	httpOptions := api.Options{Headers: headers}
 	bufRes := make([]byte, 0)
 	if err := api.HTTPGet(getSolutionDownloadUrl(solutionName), &bufRes, &httpOptions); err != nil {
-		log.Fatalf("Solution download command failed: %v", err)
+		return fmt.Errorf("solution download command failed: %w", err)
 	}
 
 	message := fmt.Sprintf("Solution %q with tag %s downloaded successfully.\n", solutionName, solutionTagFlag)
 	output.PrintCmdStatus(cmd, message)
+	return nil
 }
 
 func getSolutionDownloadUrl(solutionName string) string {
@@ -33,12 +33,14 @@ var solutionListCmd = &cobra.Command{
 	Short: "List all solutions available in this tenant",
 	Long:  `This command list all the solutions that are deployed in the current tenant specified in the profile.`,
 	Example: `  fsoc solution list
-  fsoc solution list -o json`,
-	Run:              getSolutionList,
+  fsoc solution list -o json
+  fsoc solution list -o ndjson --limit 10`,
+	RunE:             getSolutionList,
 	TraverseChildren: true,
 	Annotations: map[string]string{
-		output.TableFieldsAnnotation:  "name:.data.name, tag:.data.tag, isSystem:.data.isSystem, isSubscribed:.data.isSubscribed, dependencies:.data.dependencies",
-		output.DetailFieldsAnnotation: "name:.data.name, tag:.data.tag, isSystem:.data.isSystem, isSubscribed:.data.isSubscribed, dependencies:.data.dependencies, installDate:.createdAt, updateDate:.updatedAt",
+		config.AnnotationForMultipleProfiles: "",
+		output.TableFieldsAnnotation:         "name:.data.name, tag:.data.tag, isSystem:.data.isSystem, isSubscribed:.data.isSubscribed, dependencies:.data.dependencies",
+		output.DetailFieldsAnnotation:        "name:.data.name, tag:.data.tag, isSystem:.data.isSystem, isSubscribed:.data.isSubscribed, dependencies:.data.dependencies, installDate:.createdAt, updateDate:.updatedAt",
 	},
 }
 
@@ -48,19 +50,22 @@ func getSolutionListCmd() *cobra.Command {
 	solutionListCmd.Flags().
 		Bool("unsubscribed", false, "Use this to only see solutions that you are unsubscribed to")
 
+	solutionListCmd.Flags().
+		Int("limit", 0, "Maximum number of solutions to list (default no limit)")
+
 	solutionListCmd.MarkFlagsMutuallyExclusive("subscribed", "unsubscribed")
 
 	return solutionListCmd
 
 }
 
-func getSolutionList(cmd *cobra.Command, args []string) {
+func getSolutionList(cmd *cobra.Command, args []string) error {
 	log.Info("Fetching the list of solutions...")
 	// get subscribe and unsubscribe flags
 	subscribed := cmd.Flags().Lookup("subscribed").Changed
 	unsubscribed := cmd.Flags().Lookup("unsubscribed").Changed
 
-	cfg := config.GetCurrentContext()
+	cfg := config.CurrentContext(cmd.Context())
 	layerID := cfg.Tenant
 
 	headers := map[string]string{
@@ -76,7 +81,8 @@ func getSolutionList(cmd *cobra.Command, args []string) {
 	} else if unsubscribed {
 		filters = []string{"filter=" + url.QueryEscape("data.isSubscribed ne true")}
 	}
-	cmdkit.FetchAndPrint(cmd, solutionBaseURL, &cmdkit.FetchAndPrintOptions{Headers: headers, IsCollection: true, Filters: filters})
+	limit, _ := cmd.Flags().GetInt("limit")
+	return cmdkit.FetchAndPrint(cmd, solutionBaseURL, &cmdkit.FetchAndPrintOptions{Headers: headers, IsCollection: true, Filters: filters, Limit: limit})
 }
 
 func getSolutionNames(prefix string) (names []string) {
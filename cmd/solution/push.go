@@ -37,7 +37,7 @@ Important details on solution tags:
   fsoc solution push --bump --wait=60
   fsoc solution push -d mysolution --stable --wait
   fsoc solution push --solution-bundle=mysolution-1.22.3.zip --tag=stable`,
-	Run:              pushSolution,
+	RunE:             pushSolution,
 	TraverseChildren: true,
 }
 
@@ -78,6 +78,6 @@ func getSolutionPushCmd() *cobra.Command {
 	return solutionPushCmd
 }
 
-func pushSolution(cmd *cobra.Command, args []string) {
-	uploadSolution(cmd, true)
+func pushSolution(cmd *cobra.Command, args []string) error {
+	return uploadSolution(cmd, true)
 }
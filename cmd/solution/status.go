@@ -15,9 +15,11 @@
 package solution
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"reflect"
+	"sync"
 
 	"github.com/apex/log"
 	"github.com/spf13/cobra"
@@ -63,11 +65,7 @@ var solutionStatusCmd = &cobra.Command{
 	Long:  `This command provides the ability to see the current installation and upload status of a solution.`,
 	Example: `  fsoc solution status spacefleet
   fsoc solution status spacefleet --status-type=install`,
-	Run: func(cmd *cobra.Command, args []string) {
-		if err := getSolutionStatus(cmd, args); err != nil {
-			log.Fatalf(err.Error())
-		}
-	},
+	RunE:             getSolutionStatus,
 	TraverseChildren: true,
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		config.SetActiveProfile(cmd, args, false)
@@ -91,63 +89,66 @@ func getSolutionStatusCmd() *cobra.Command {
 	return solutionStatusCmd
 }
 
-func getObjects(url string, headers map[string]string) StatusItem {
+func getObjects(url string, headers map[string]string) (StatusItem, error) {
 	var res ResponseBlob
 	var emptyData StatusItem
 
 	err := api.JSONGet(url, &res, &api.Options{Headers: headers})
 
 	if err != nil {
-		log.Fatalf("Error fetching solution object %q: %v", url, err)
+		return emptyData, fmt.Errorf("error fetching solution object %q: %w", url, err)
 	}
 
 	if len(res.Items) > 0 {
-		return res.Items[0]
+		return res.Items[0], nil
 	} else {
-		return emptyData
+		return emptyData, nil
 	}
 }
 
-func getExtensibilitySolutionObject(url string, headers map[string]string) ExtensibilitySolutionObjectData {
+func getExtensibilitySolutionObject(url string, headers map[string]string) (ExtensibilitySolutionObjectData, error) {
 	var res GetExtensibilitySolutionObjectByIdResponse
 
 	err := api.JSONGet(url, &res, &api.Options{Headers: headers})
 
 	if err != nil {
-		log.Fatalf("Error fetching extensibility:solution object %q: %v", url, err)
+		return res.Data, fmt.Errorf("error fetching extensibility:solution object %q: %w", url, err)
 	}
 
-	return res.Data
+	return res.Data, nil
 
 }
 
-func fetchValuesAndPrint(operation string, solutionInstallObjectQuery string, solutionReleaseObjectQuery string, successfulSolutionInstallObjectQuery string, solutionID string, requestHeaders map[string]string, cmd *cobra.Command) {
+func fetchValuesAndPrint(operation string, solutionInstallObjectQuery string, solutionReleaseObjectQuery string, successfulSolutionInstallObjectQuery string, solutionID string, requestHeaders map[string]string, cmd *cobra.Command) error {
 	// finalize solution name (incl. the solution object name which includes the tag value)
 	var solutionInstallationMessagePrefix string
 	solutionVersion, _ := cmd.Flags().GetString("solution-version")
 
-	uploadStatusChan := make(chan StatusItem)
-	installStatusChan := make(chan StatusItem)
-	solutionStatusChan := make(chan ExtensibilitySolutionObjectData)
-	successfulSolutionInstallStatusChan := make(chan StatusItem)
-
+	var uploadStatusItem, installStatusItem, successfulInstallStatusItem StatusItem
+	var solutionStatusItem ExtensibilitySolutionObjectData
+	errs := make([]error, 4)
+	var wg sync.WaitGroup
+	wg.Add(4)
 	go func() {
-		uploadStatusChan <- getObjects(fmt.Sprintf(getSolutionReleaseUrl(), solutionReleaseObjectQuery), requestHeaders)
+		defer wg.Done()
+		uploadStatusItem, errs[0] = getObjects(fmt.Sprintf(getSolutionReleaseUrl(), solutionReleaseObjectQuery), requestHeaders)
 	}()
 	go func() {
-		installStatusChan <- getObjects(fmt.Sprintf(getSolutionInstallUrl(), solutionInstallObjectQuery), requestHeaders)
+		defer wg.Done()
+		installStatusItem, errs[1] = getObjects(fmt.Sprintf(getSolutionInstallUrl(), solutionInstallObjectQuery), requestHeaders)
 	}()
 	go func() {
-		solutionStatusChan <- getExtensibilitySolutionObject(getSolutionObjectUrl(solutionID), requestHeaders)
+		defer wg.Done()
+		solutionStatusItem, errs[2] = getExtensibilitySolutionObject(getSolutionObjectUrl(solutionID), requestHeaders)
 	}()
 	go func() {
-		successfulSolutionInstallStatusChan <- getObjects(fmt.Sprintf(getSolutionInstallUrl(), successfulSolutionInstallObjectQuery), requestHeaders)
+		defer wg.Done()
+		successfulInstallStatusItem, errs[3] = getObjects(fmt.Sprintf(getSolutionInstallUrl(), successfulSolutionInstallObjectQuery), requestHeaders)
 	}()
-
-	uploadStatusItem := <-uploadStatusChan
-	installStatusItem := <-installStatusChan
-	solutionStatusItem := <-solutionStatusChan
-	successfulInstallStatusItem := <-successfulSolutionInstallStatusChan
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
 
 	installStatusData := installStatusItem.StatusData
 	successfulInstallStatusData := successfulInstallStatusItem.StatusData
@@ -206,6 +207,7 @@ func fetchValuesAndPrint(operation string, solutionInstallObjectQuery string, so
 		Lines:   [][]string{values},
 		Detail:  true,
 	})
+	return nil
 }
 
 func getSolutionStatus(cmd *cobra.Command, args []string) error {
@@ -253,9 +255,7 @@ func getSolutionStatus(cmd *cobra.Command, args []string) error {
 
 	log.Infof(`solution name and version query: %s`, solutionInstallObjectQuery)
 
-	fetchValuesAndPrint(statusTypeToFetch, solutionInstallObjectQuery, solutionReleaseObjectQuery, successfulSolutionInstallObjectQuery, solutionID, headers, cmd)
-
-	return nil
+	return fetchValuesAndPrint(statusTypeToFetch, solutionInstallObjectQuery, solutionReleaseObjectQuery, successfulSolutionInstallObjectQuery, solutionID, headers, cmd)
 }
 
 func (s ExtensibilitySolutionObjectData) IsEmpty() bool {
@@ -35,7 +35,7 @@ var solutionSubscribeCmd = &cobra.Command{
 	Short:            "Subscribe to a solution",
 	Long:             `This command allows the current tenant specified in the profile to subscribe to a solution.`,
 	Example:          `	fsoc solution subscribe spacefleet`,
-	Run:              subscribeToSolution,
+	RunE:             subscribeToSolution,
 	TraverseChildren: true,
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		config.SetActiveProfile(cmd, args, false)
@@ -54,11 +54,11 @@ func getSubscribeSolutionCmd() *cobra.Command {
 
 }
 
-func subscribeToSolution(cmd *cobra.Command, args []string) {
-	manageSubscription(cmd, args, true)
+func subscribeToSolution(cmd *cobra.Command, args []string) error {
+	return manageSubscription(cmd, args, true)
 }
 
-func manageSubscription(cmd *cobra.Command, args []string, isSubscribed bool) {
+func manageSubscription(cmd *cobra.Command, args []string, isSubscribed bool) error {
 	name := getSolutionNameFromArgs(cmd, args, "name")
 	tag, _ := cmd.Flags().GetString("tag")
 
@@ -80,10 +80,10 @@ func manageSubscription(cmd *cobra.Command, args []string, isSubscribed bool) {
 	if !isSubscribed {
 		isSystemSolution, err := isSystemSolution(objectUrl)
 		if err != nil {
-			log.Fatalf("Failed to get solution status: %v", err)
+			return fmt.Errorf("failed to get solution status: %w", err)
 		}
 		if isSystemSolution {
-			log.Fatalf("Cannot unsubscribe tenant from solution %s because it is a system solution", name)
+			return fmt.Errorf("cannot unsubscribe tenant from solution %s because it is a system solution", name)
 		}
 	}
 
@@ -92,7 +92,7 @@ func manageSubscription(cmd *cobra.Command, args []string, isSubscribed bool) {
 	subscribe := subscriptionStruct{IsSubscribed: isSubscribed}
 	err := api.JSONPatch(objectUrl, &subscribe, &res, &api.Options{Headers: getHeaders()})
 	if err != nil {
-		log.Fatalf("Solution command failed: %v", err)
+		return fmt.Errorf("solution command failed: %w", err)
 	}
 
 	// display status message
@@ -103,6 +103,7 @@ func manageSubscription(cmd *cobra.Command, args []string, isSubscribed bool) {
 		message = fmt.Sprintf("Tenant %s has successfully unsubscribed from solution %s\n", tenant, name)
 	}
 	output.PrintCmdStatus(cmd, message)
+	return nil
 }
 
 func locateSolutionUrl(name string, tag string) string {
@@ -140,7 +141,7 @@ func isSystemSolution(objUrl string) (bool, error) {
 
 	err := api.JSONGet(objUrl, &solData, &api.Options{Headers: getHeaders()})
 	if err != nil {
-		return false, fmt.Errorf("failed to get solution info: %v", err)
+		return false, fmt.Errorf("failed to get solution info: %w", err)
 	}
 
 	return solData.Data.IsSystem, nil
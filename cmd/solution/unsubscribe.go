@@ -26,7 +26,7 @@ var solutionUnsubscribeCmd = &cobra.Command{
 	Short:            "Unsubscribe from a solution",
 	Long:             `This command allows the current tenant specified in the profile to unsubscribe from a solution.`,
 	Example:          `  fsoc solution unsubscribe spacefleet`,
-	Run:              unsubscribeFromSolution,
+	RunE:             unsubscribeFromSolution,
 	TraverseChildren: true,
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		config.SetActiveProfile(cmd, args, false)
@@ -45,6 +45,6 @@ func getUnsubscribeSolutionCmd() *cobra.Command {
 
 }
 
-func unsubscribeFromSolution(cmd *cobra.Command, args []string) {
-	manageSubscription(cmd, args, false)
+func unsubscribeFromSolution(cmd *cobra.Command, args []string) error {
+	return manageSubscription(cmd, args, false)
 }
@@ -60,17 +60,20 @@ func WithSolutionInstallVersion(version string) uploadOption {
 	}
 }
 
-func bumpSolutionVersionInManifest(cmd *cobra.Command, manifest *Manifest, manifestPath string) {
+func bumpSolutionVersionInManifest(cmd *cobra.Command, manifest *Manifest, manifestPath string) error {
 	if err := bumpManifestPatchVersion(manifest); err != nil {
-		log.Fatal(err.Error())
+		return err
 	}
 	if err := writeSolutionManifest(manifestPath, manifest); err != nil {
-		log.Fatalf("Failed to update solution manifest in %q after version bump: %v", manifestPath, err)
+		return fmt.Errorf("failed to update solution manifest in %q after version bump: %w", manifestPath, err)
 	}
 	output.PrintCmdStatus(cmd, fmt.Sprintf("Solution version updated to %v\n", manifest.SolutionVersion))
+	return nil
 }
 
-func uploadSolution(cmd *cobra.Command, push bool, options ...uploadOption) {
+// uploadSolution uploads the solution for deployment (push) or for validation only. The returned error
+// wraps the API call's error, if any, so that it can be classified (see api.ErrorKindOf).
+func uploadSolution(cmd *cobra.Command, push bool, options ...uploadOption) error {
 	opts := uploadOptions{}
 	for _, option := range options {
 		option(&opts)
@@ -127,25 +130,27 @@ func uploadSolution(cmd *cobra.Command, push bool, options ...uploadOption) {
 		if solutionRootDirectory == "" {
 			solutionRootDirectory, err = os.Getwd()
 			if err != nil {
-				log.Fatal(err.Error())
+				return err
 			}
 		} else {
 			solutionRootDirectory, err = filepath.Abs(solutionRootDirectory)
 			if err != nil {
-				log.Fatal(err.Error())
+				return err
 			}
 		}
 		if !isSolutionPackageRoot(solutionRootDirectory) {
-			log.Fatalf("No solution manifest found in %q; please use -d or --solution-bundle flag", solutionRootDirectory)
+			return fmt.Errorf("no solution manifest found in %q; please use -d or --solution-bundle flag", solutionRootDirectory)
 		}
 
 		// get manifest, bump version if needed
 		manifest, err = getSolutionManifest(solutionRootDirectory)
 		if err != nil {
-			log.Fatalf("Failed to read the solution manifest from %q: %v", solutionRootDirectory, err)
+			return fmt.Errorf("failed to read the solution manifest from %q: %w", solutionRootDirectory, err)
 		}
 		if bumpFlag {
-			bumpSolutionVersionInManifest(cmd, manifest, solutionRootDirectory)
+			if err := bumpSolutionVersionInManifest(cmd, manifest, solutionRootDirectory); err != nil {
+				return err
+			}
 		}
 
 		// isolate if needed (update tag values to reflect env var and/or env file settings)
@@ -153,7 +158,7 @@ func uploadSolution(cmd *cobra.Command, push bool, options ...uploadOption) {
 		solutionTagFlag = tag
 		requestedSolutionTag = tag
 		if err != nil {
-			log.Fatalf("Failed to isolate solution with tag: %v", err)
+			return fmt.Errorf("failed to isolate solution with tag: %w", err)
 		}
 		if solutionIsolateDirectory != solutionRootDirectory { // if isolated, post-process
 			// set root directory to the isolated version's root
@@ -162,7 +167,7 @@ func uploadSolution(cmd *cobra.Command, push bool, options ...uploadOption) {
 			// re-read manifest, to get the isolated name
 			manifest, err = getSolutionManifest(solutionRootDirectory)
 			if err != nil {
-				log.Fatalf("Failed to read the solution manifest from %q: %v", solutionRootDirectory, err)
+				return fmt.Errorf("failed to read the solution manifest from %q: %w", solutionRootDirectory, err)
 			}
 
 			// update tag to use supported values
@@ -210,18 +215,18 @@ func uploadSolution(cmd *cobra.Command, push bool, options ...uploadOption) {
 	// read zip file into a buffer
 	file, err := os.Open(solutionBundlePath)
 	if err != nil {
-		log.Fatalf("Failed to open file %q: %v", solutionBundlePath, err)
+		return fmt.Errorf("failed to open file %q: %w", solutionBundlePath, err)
 	}
 	defer file.Close()
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 	fw, err := writer.CreateFormFile("file", solutionBundlePath)
 	if err != nil {
-		log.Fatalf("Failed to create form file: %v", err)
+		return fmt.Errorf("failed to create form file: %w", err)
 	}
 	_, err = io.Copy(fw, file)
 	if err != nil {
-		log.Fatalf("Failed to copy file %q into file writer: %v", solutionBundlePath, err)
+		return fmt.Errorf("failed to copy file %q into file writer: %w", solutionBundlePath, err)
 	}
 	writer.Close()
 
@@ -240,12 +245,12 @@ func uploadSolution(cmd *cobra.Command, push bool, options ...uploadOption) {
 	var res Result
 	err = api.HTTPPost(getSolutionPushUrl(), body.Bytes(), &res, &api.Options{Headers: headers})
 	if err != nil {
-		log.Fatalf("Solution %s command failed: %v", operation, err)
+		return fmt.Errorf("solution %s command failed: %w", operation, err)
 	}
 	if !push && !res.Valid {
 		message := getSolutionValidationErrorsString(res.Errors.Total, res.Errors)
 		output.PrintCmdStatus(cmd, message)
-		log.Fatalf("%d error(s) found while validating the solution", res.Errors.Total)
+		return fmt.Errorf("%d error(s) found while validating the solution", res.Errors.Total)
 	}
 
 	// display result
@@ -276,7 +281,7 @@ func uploadSolution(cmd *cobra.Command, push bool, options ...uploadOption) {
 			time.Sleep(time.Second * time.Duration(i))
 		}
 		if err != nil {
-			log.Fatalf("Solution command failed: %v", err)
+			return fmt.Errorf("solution command failed: %w", err)
 		}
 
 	}
@@ -303,18 +308,26 @@ func uploadSolution(cmd *cobra.Command, push bool, options ...uploadOption) {
 		for statusData.SolutionVersion != solutionVersion {
 			if waitFlag > 0 {
 				if time.Since(waitStartTime).Seconds() > float64(waitFlag) {
-					log.Fatalf("Failed to validate %s was installed: timed out", solutionDisplayText)
+					return fmt.Errorf("failed to validate %s was installed: timed out", solutionDisplayText)
 				}
 			}
-			status := getObjects(fmt.Sprintf(getSolutionInstallUrl(), query), headers)
+			status, err := getObjects(fmt.Sprintf(getSolutionInstallUrl(), query), headers)
+			if err != nil {
+				return err
+			}
 			statusData = status.StatusData
-			time.Sleep(3 * time.Second)
+			select {
+			case <-time.After(3 * time.Second):
+			case <-cmd.Context().Done(): // interrupted or timed out (see --timeout)
+				return fmt.Errorf("stopped waiting for %s to be installed: %w", solutionDisplayText, cmd.Context().Err())
+			}
 		}
 		if !statusData.SuccessfulInstall {
-			log.Fatalf("Failed to install %s: %s", solutionDisplayText, statusData.InstallMessage)
+			return fmt.Errorf("failed to install %s: %s", solutionDisplayText, statusData.InstallMessage)
 		}
 		output.PrintCmdStatus(cmd, fmt.Sprintf("Installed %v successfully.\n", solutionDisplayText))
 	}
+	return nil
 }
 
 func getSolutionValidationErrorsString(total int, errors Errors) string {
@@ -44,7 +44,7 @@ var solutionValidateCmd = &cobra.Command{
   fsoc solution validate --stable
   fsoc solution validate -d mysolution --tag dev
   fsoc solution validate --solution-bundle=mysolution-1.22.3.zip --tag stable`,
-	Run:              validateSolution,
+	RunE:             validateSolution,
 	TraverseChildren: true,
 }
 
@@ -78,6 +78,6 @@ func getSolutionValidateCmd() *cobra.Command {
 	return solutionValidateCmd
 }
 
-func validateSolution(cmd *cobra.Command, args []string) {
-	uploadSolution(cmd, false)
+func validateSolution(cmd *cobra.Command, args []string) error {
+	return uploadSolution(cmd, false)
 }
@@ -103,10 +103,18 @@ func zapSolution(cmd *cobra.Command, args []string) {
 	solutionInstallObjectQuery = fmt.Sprintf("?order=%s&filter=%s&max=1", url.QueryEscape("desc"), url.QueryEscape(solutionInstallObjectFilterQuery))
 
 	go func() {
-		solutionInstallObjectChan <- getObjects(fmt.Sprintf(getSolutionInstallUrl(), solutionInstallObjectQuery), headers)
+		solutionInstallObject, err := getObjects(fmt.Sprintf(getSolutionInstallUrl(), solutionInstallObjectQuery), headers)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		solutionInstallObjectChan <- solutionInstallObject
 	}()
 	go func() {
-		solutionObjectChan <- getExtensibilitySolutionObject(getSolutionObjectUrl(solutionId), headers)
+		solutionObject, err := getExtensibilitySolutionObject(getSolutionObjectUrl(solutionId), headers)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		solutionObjectChan <- solutionObject
 	}()
 
 	solutionInstallObject := <-solutionInstallObjectChan
@@ -152,7 +160,9 @@ func zapSolution(cmd *cobra.Command, args []string) {
 	solutionZipPath = solutionArchive.Name()
 	defer os.RemoveAll(solutionZipPath)
 
-	uploadSolution(cmd, true, WithSolutionName(solutionName), WithSolutionZipPath(solutionZipPath), WithSolutionInstallVersion(updatedManifestVersion))
+	if err := uploadSolution(cmd, true, WithSolutionName(solutionName), WithSolutionZipPath(solutionZipPath), WithSolutionInstallVersion(updatedManifestVersion)); err != nil {
+		log.Fatalf("%v", err)
+	}
 
 	output.PrintCmdStatus(cmd, fmt.Sprintf("Solution with name: %s and tag: %s zapped\n", solutionName, solutionTag))
 }
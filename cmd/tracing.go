@@ -0,0 +1,76 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/cmd/version"
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/platform/api"
+	"github.com/cisco-open/fsoc/tracing"
+)
+
+// commandSpan traces the execution of the command, if the profile enables tracing
+var commandSpan *tracing.Span
+
+// startTracing sets up tracing per the profile's settings and starts the command's span, which
+// becomes the parent of the spans of the platform API calls made by the command
+func startTracing(cmd *cobra.Command, cfg *config.Context) {
+	if cfg == nil || cfg.TracingOptions.Exporter == "" {
+		return
+	}
+	httpClient, err := api.NewHTTPClient(cfg) // the profile's proxy, CA and client certificate apply to the export, too
+	if err != nil {
+		log.Warnf("Tracing is disabled: %v", err)
+		return
+	}
+	err = tracing.Setup(tracing.Options{
+		Exporter:           cfg.TracingOptions.Exporter,
+		Endpoint:           cfg.TracingOptions.Endpoint,
+		HTTPClient:         httpClient,
+		File:               cfg.TracingOptions.File,
+		ServiceVersion:     version.GetVersionShort(),
+		ResourceAttributes: tracing.Attributes{"fsoc.profile": cfg.Name},
+	})
+	if err != nil {
+		log.Warnf("Tracing is disabled: %v", err)
+		return
+	}
+
+	ctx, span := tracing.Start(api.DefaultContext(), cmd.CommandPath(), tracing.SpanKindInternal, tracing.Attributes{
+		"fsoc.command": cmd.CommandPath(),
+		"fsoc.profile": cfg.Name,
+	})
+	commandSpan = span
+	cmd.SetContext(ctx)
+	api.SetDefaultContext(ctx)
+	log.WithField("traceparent", span.Traceparent()).Info("Tracing the command")
+}
+
+// finishTracing ends the command's span, marking it failed if err is not nil, and exports the
+// spans. It is safe to call more than once (only the first call has effect).
+func finishTracing(err error) {
+	if commandSpan == nil {
+		return
+	}
+	commandSpan.SetError(err)
+	commandSpan.End()
+	commandSpan = nil
+	if err := tracing.Shutdown(); err != nil {
+		log.Warnf("Failed to export the command's trace: %v", err)
+	}
+}
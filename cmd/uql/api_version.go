@@ -23,8 +23,8 @@ import (
 )
 
 // UQL API version type, supporting a limited set of values.
-// Implements the StringEnumer interface defined by the fsoc config package in order
-// to support parsing apiver from an fsoc config file.
+// Implements the Validator and StringEnumer interfaces defined by the fsoc config package in order
+// to support parsing apiver from an fsoc config file and checking/completing it in "config set".
 type ApiVersion string
 
 // constants for direct use
@@ -55,6 +55,10 @@ func (a *ApiVersion) ValidateAndSet(v any) error {
 	return nil
 }
 
+func (a *ApiVersion) ValidValues() []string {
+	return supportedApiVersions
+}
+
 func (a *ApiVersion) String() string {
 	if a == nil || string(*a) == "" {
 		return string(ApiVersionDefault)
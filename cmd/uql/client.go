@@ -14,6 +14,12 @@
 
 package uql
 
+import (
+	"context"
+
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
 type UqlClient interface {
 	// ExecuteQuery sends an execute request to the UQL service
 	ExecuteQuery(query *Query) (*Response, error)
@@ -43,6 +49,14 @@ func WithClientApiVersion(version ApiVersion) UqlClientOption {
 	}
 }
 
+// WithClientContext makes the client pass the Go context to its platform API calls, e.g., the command's
+// context, which selects the profile when the command is run for multiple profiles (see api.WithClient)
+func WithClientContext(ctx context.Context) UqlClientOption {
+	return func(c *defaultClient) {
+		c.backend = &defaultBackend{apiOptions: &api.Options{Context: ctx}}
+	}
+}
+
 func (c defaultClient) ExecuteQuery(query *Query) (*Response, error) {
 	apiVersion := ApiVersion("")
 	if c.apiVersion != nil {
@@ -15,8 +15,8 @@
 package uql
 
 import (
+	"context"
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/apex/log"
@@ -25,6 +25,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
+	"github.com/cisco-open/fsoc/config"
 	fsoc "github.com/cisco-open/fsoc/output"
 )
 
@@ -60,6 +61,7 @@ If the "raw" flag is provided, the actual response from the backend API is displ
 	Args:             cobra.ExactArgs(1),
 	RunE:             uqlQuery,
 	TraverseChildren: true,
+	Annotations:      map[string]string{config.AnnotationForMultipleProfiles: ""},
 }
 
 type format int
@@ -98,14 +100,13 @@ func uqlQuery(cmd *cobra.Command, args []string) error {
 		return err
 	}
 	queryStr := args[0]
-	response, err := runQuery(queryStr)
+	response, err := runQuery(cmd.Context(), queryStr)
 	if err != nil {
 		if problem, ok := err.(uqlProblem); ok {
 			printProblemDescription(cmd, problem, queryStr)
-			os.Exit(1)
-		} else {
-			log.Fatal(err.Error())
+			return fmt.Errorf("the UQL query failed: %v", problem.title)
 		}
+		return err
 	}
 	if response.HasErrors() {
 		log.Error("Execution of query encountered errors. Returned data are not complete!")
@@ -143,10 +144,14 @@ func outputFormat(output string, useRaw bool) (format, error) {
 	}
 }
 
-func runQuery(query string) (*Response, error) {
+func runQuery(ctx context.Context, query string) (*Response, error) {
 	log.Info("fetch data")
 
-	resp, err := Client.ExecuteQuery(&Query{Str: query})
+	client := Client
+	if ctx != nil {
+		client = NewClient(WithClientContext(ctx))
+	}
+	resp, err := client.ExecuteQuery(&Query{Str: query})
 	if err != nil {
 		return nil, err
 	}
@@ -164,13 +169,13 @@ func printResponse(cmd *cobra.Command, response *Response, output format) error
 		if err != nil {
 			return err
 		}
-		return fsoc.PrintJson(cmd, json)
+		fsoc.PrintCmdOutput(cmd, json)
 	case yamlFormat:
 		json, err := transformForJsonOutput(response)
 		if err != nil {
 			return err
 		}
-		return fsoc.PrintYaml(cmd, json)
+		fsoc.PrintCmdOutput(cmd, json)
 	case rawFormat:
 		fsoc.PrintCmdOutput(cmd, string(*response.raw))
 	}
@@ -0,0 +1,169 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package whoami
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+// whoamiCmd represents the whoami command
+var whoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Display the principal, tenant and access token details of the current profile",
+	Long: `Display who fsoc acts as when using the current profile: the principal, tenant, token issuer, scopes
+and when the access token expires. The details are decoded from the profile's access token (its signature
+is not verified locally); then, the token is validated by making a cheap call to the platform, without
+logging in or refreshing it. The command fails if the platform rejects the token.
+
+Use --print-access-token to print only the access token, e.g., to pass it to other tools. In this mode,
+fsoc refreshes the token first (logging in if needed), so that it remains valid for as long as possible.`,
+	Example: `
+  fsoc whoami
+  fsoc whoami -o json
+  fsoc whoami --no-validate
+  curl -H "Authorization: Bearer $(fsoc whoami --print-access-token)" https://mytenant.observe.appdynamics.com/...`,
+	Args:             cobra.NoArgs,
+	RunE:             whoami,
+	TraverseChildren: true,
+}
+
+// whoamiInfo is the output of the whoami command
+type whoamiInfo struct {
+	Profile    string `json:"profile"`
+	AuthMethod string `json:"authMethod"`
+	URL        string `json:"url"`
+	Tenant     string `json:"tenant,omitempty"`
+	*api.TokenInfo
+	ExpiresIn  string `json:"expiresIn,omitempty"`
+	Validation string `json:"validation,omitempty"` // "valid", "valid-no-access", "expired", "invalid" or empty if not validated
+}
+
+func init() {
+	whoamiCmd.Flags().Bool("print-access-token", false, "print only the access token, refreshing it first")
+	whoamiCmd.Flags().Bool("no-validate", false, "do not validate the access token with the platform")
+}
+
+func NewSubCmd() *cobra.Command {
+	return whoamiCmd
+}
+
+func whoami(cmd *cobra.Command, args []string) error {
+	if printToken, _ := cmd.Flags().GetBool("print-access-token"); printToken {
+		token, err := api.AccessToken(true)
+		if err != nil {
+			return fmt.Errorf("failed to obtain an access token: %w", err)
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), token)
+		return nil
+	}
+
+	cfg := config.GetCurrentContext()
+	info := whoamiInfo{
+		Profile:    cfg.Name,
+		AuthMethod: cfg.AuthMethod,
+		URL:        cfg.URL,
+		Tenant:     cfg.Tenant,
+		TokenInfo:  &api.TokenInfo{Principal: cfg.User},
+	}
+	if cfg.Token != "" {
+		tokenInfo, err := api.DescribeToken(cfg.Token)
+		if err != nil {
+			log.Warnf("Cannot decode the access token: %v", err)
+		} else {
+			info.TokenInfo = tokenInfo
+			if info.Principal == "" {
+				info.Principal = cfg.User
+			}
+			if tokenInfo.ExpiresAt != nil {
+				info.ExpiresIn = humanizeExpiry(time.Until(*tokenInfo.ExpiresAt))
+			}
+		}
+	}
+
+	// validate token with the platform
+	var validationErr error
+	if noValidate, _ := cmd.Flags().GetBool("no-validate"); !noValidate {
+		info.Validation, validationErr = validationStatus(api.ValidateToken(), info.TokenInfo.ExpiresAt, time.Now())
+	}
+
+	output.PrintCmdOutputCustom(cmd, info, &output.Table{
+		Headers: []string{"Profile", "Auth Method", "URL", "Tenant", "Principal", "Issuer", "Scopes", "Expires", "Validation"},
+		Lines: [][]string{{
+			info.Profile,
+			info.AuthMethod,
+			info.URL,
+			info.Tenant,
+			info.Principal,
+			info.Issuer,
+			strings.Join(info.Scopes, " "),
+			expiryDisplay(info),
+			info.Validation,
+		}},
+		Detail: true,
+	})
+
+	if validationErr != nil {
+		var loginErr *api.LoginError
+		if errors.As(validationErr, &loginErr) {
+			return validationErr
+		}
+		return fmt.Errorf("the platform did not accept the access token: %w", validationErr)
+	}
+	return nil
+}
+
+// validationStatus interprets the result of validating the access token with the platform, returning
+// the validation status to display and the error to fail with, if any. Since the platform may reject
+// expired tokens with 403, a permission error means that the token is valid but cannot be used for
+// the validation call only if the token has not expired (expiresAt is nil if the expiry is unknown).
+func validationStatus(validationErr error, expiresAt *time.Time, now time.Time) (string, error) {
+	expired := expiresAt != nil && !now.Before(*expiresAt)
+	switch {
+	case validationErr == nil:
+		return "valid", nil
+	case expired:
+		return "expired", &api.LoginError{Err: fmt.Errorf("the access token expired %v ago; please use 'fsoc login': %w", now.Sub(*expiresAt).Round(time.Second), validationErr)}
+	case api.ErrorKindOf(validationErr) == api.ErrorKindPermission:
+		log.Infof("The access token was accepted, but the principal cannot access the validation call: %v", validationErr)
+		return "valid-no-access", nil // token accepted, but not for the validation call
+	default:
+		return "invalid", validationErr
+	}
+}
+
+func expiryDisplay(info whoamiInfo) string {
+	if info.ExpiresAt == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v (%v)", info.ExpiresAt.Local().Format(time.RFC1123), info.ExpiresIn)
+}
+
+// humanizeExpiry describes the remaining validity of a token, e.g., "in 59m" or "expired 2h ago"
+func humanizeExpiry(d time.Duration) string {
+	if d < 0 {
+		return fmt.Sprintf("expired %v ago", (-d).Round(time.Second))
+	}
+	return fmt.Sprintf("in %v", d.Round(time.Second))
+}
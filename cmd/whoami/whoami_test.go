@@ -0,0 +1,55 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package whoami
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+func TestValidationStatus(t *testing.T) {
+	now := time.Now()
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Hour)
+	forbidden := &api.HttpStatusError{Message: "Forbidden", StatusCode: http.StatusForbidden}
+	unauthorized := &api.HttpStatusError{Message: "Unauthorized", StatusCode: http.StatusUnauthorized}
+
+	status, err := validationStatus(nil, &future, now)
+	assert.Equal(t, "valid", status)
+	assert.Nil(t, err)
+
+	// a 403 for an unexpired (or unknown expiry) token is a permission issue of the validation call only
+	status, err = validationStatus(forbidden, &future, now)
+	assert.Equal(t, "valid-no-access", status)
+	assert.Nil(t, err)
+	status, err = validationStatus(forbidden, nil, now)
+	assert.Equal(t, "valid-no-access", status)
+	assert.Nil(t, err)
+
+	// a 403 for an expired token means the token was rejected
+	status, err = validationStatus(forbidden, &past, now)
+	assert.Equal(t, "expired", status)
+	assert.Equal(t, api.ErrorKindAuthentication, api.ErrorKindOf(err))
+	assert.Contains(t, err.Error(), "expired 1h0m0s ago")
+
+	status, err = validationStatus(unauthorized, &future, now)
+	assert.Equal(t, "invalid", status)
+	assert.Equal(t, api.ErrorKindAuthentication, api.ErrorKindOf(err))
+}
@@ -15,10 +15,11 @@
 package cmdkit
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
 	"strings"
 
-	"github.com/apex/log"
 	"github.com/spf13/cobra"
 
 	"github.com/cisco-open/fsoc/output"
@@ -26,12 +27,13 @@ import (
 )
 
 type FetchAndPrintOptions struct {
-	Method       *string           // default "GET"; "GET", "POST" and "PATCH" are currently supported
+	Method       *string           // default "GET"; any HTTP method is accepted (only "GET" for collections)
 	Headers      map[string]string // http headers to send with the request
 	Body         any               // body to send with the request (nil for no body)
 	ResponseType *reflect.Type     // structure type to parse response into (for schema validation & fields) (nil for none)
 	IsCollection bool              // set to true for GET to request a collection that may be paginated (see platform/api/collection.go)
 	Filters      []string
+	Limit        int // maximum number of collection items to fetch and display (0 for no limit); only for IsCollection
 }
 
 // FetchAndPrint consolidates the common sequence of fetching from the server and
@@ -41,8 +43,10 @@ type FetchAndPrintOptions struct {
 // If a cmd is not provided or it has no `output` flag, human output is assumed (table)
 // If a human format is requested/assumed but no table is provided, it displays YAML
 // If the object cannot be converted to the desired format, shows the object in Go's %+v format
-// In addition, if the fetch API command fails, this function prints the error and exits with failure.
-func FetchAndPrint(cmd *cobra.Command, path string, options *FetchAndPrintOptions) {
+// In addition, if the fetch API command fails, this function returns the error, for commands that
+// return errors (see cobra.Command.RunE). The error wraps the API call's error, so that it can be
+// classified (see api.ErrorKindOf).
+func FetchAndPrint(cmd *cobra.Command, path string, options *FetchAndPrintOptions) error {
 	// finalize override fields
 	method := "GET"
 	if options != nil && options.Method != nil {
@@ -52,9 +56,12 @@ func FetchAndPrint(cmd *cobra.Command, path string, options *FetchAndPrintOption
 	if options != nil {
 		body = options.Body
 	}
-	var httpOptions *api.Options = nil
+	httpOptions := &api.Options{}
+	if cmd != nil {
+		httpOptions.Context = cmd.Context() // may select the profile, see config.AnnotationForMultipleProfiles
+	}
 	if options != nil {
-		httpOptions = &api.Options{Headers: options.Headers}
+		httpOptions.Headers = options.Headers
 	}
 	var res any
 	if options != nil && options.ResponseType != nil {
@@ -78,22 +85,63 @@ func FetchAndPrint(cmd *cobra.Command, path string, options *FetchAndPrintOption
 
 	if options != nil && options.IsCollection {
 		if method != "GET" {
-			log.Fatalf("bug: cannot request %q for a collection at %q, only GET is supported for collections", method, path)
+			return fmt.Errorf("bug: cannot request %q for a collection at %q, only GET is supported for collections", method, path)
 		}
-		var result api.CollectionResult[any]
-		err := api.JSONGetCollection[any](path, &result, httpOptions)
+		result, err := fetchCollection(cmd, path, options.Limit, httpOptions)
 		if err != nil {
-			log.Fatalf("Platform API call failed: %v", err)
+			// display the items retrieved before the failure, if any, before failing
+			var partialErr *api.PartialCollectionError
+			if result != nil && errors.As(err, &partialErr) && len(result.Items) > 0 {
+				output.PrintCmdOutput(cmd, *result)
+			}
+			return fmt.Errorf("platform API call failed: %w", err)
+		}
+		if result == nil {
+			return nil // already displayed page by page
 		}
-		res = result
+		res = *result
 
 	} else {
 		err = api.JSONRequest(method, path, body, &res, httpOptions)
 	}
 	if err != nil {
-		log.Fatalf("Platform API call failed: %v", err)
+		return fmt.Errorf("platform API call failed: %w", err)
 	}
 
 	// print command output data
 	output.PrintCmdOutput(cmd, res)
+	return nil
+}
+
+// fetchCollection retrieves a collection page by page, up to limit items (0 for no limit).
+// If the user-selected output format supports streaming (see output.IsStreamingFormat), each page
+// is displayed as soon as it is retrieved and the returned result is nil. Otherwise, the items are
+// accumulated and returned; if a non-first page fails, the items retrieved before the failure
+// are returned together with the error.
+func fetchCollection(cmd *cobra.Command, path string, limit int, httpOptions *api.Options) (*api.CollectionResult[any], error) {
+	streaming := output.IsStreamingFormat(cmd)
+	result := api.CollectionResult[any]{Items: []any{}}
+	count := 0
+
+	err := api.JSONGetCollectionPages[any](path, func(items []any) error {
+		if limit > 0 && count+len(items) > limit {
+			items = items[:limit-count]
+		}
+		count += len(items)
+		if streaming {
+			output.PrintCmdOutput(cmd, api.CollectionResult[any]{Items: items, Total: len(items)})
+		} else {
+			result.Items = append(result.Items, items...)
+		}
+		if limit > 0 && count >= limit {
+			return api.ErrStopIteration
+		}
+		return nil
+	}, httpOptions)
+	result.Total = len(result.Items)
+
+	if streaming {
+		return nil, err
+	}
+	return &result, err
 }
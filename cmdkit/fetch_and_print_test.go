@@ -0,0 +1,113 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmdkit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+// useCollectionServer serves a collection of 3 pages with 2 items each at /items and makes it
+// available through the current profile (other paths are not found); it returns the page numbers requested
+func useCollectionServer(t *testing.T) *[]int {
+	requested := []int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/items" {
+			http.NotFound(w, r)
+			return
+		}
+		page := 1
+		if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+			page, _ = strconv.Atoi(cursor)
+		}
+		requested = append(requested, page)
+		if page < 3 {
+			w.Header().Set("Link", fmt.Sprintf(`</items?cursor=%d>; rel="next"`, page+1))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"items":[{"id":"%d-a"},{"id":"%d-b"}],"total":6}`, page, page)
+	}))
+	t.Cleanup(server.Close)
+
+	config.SetEphemeralContext(&config.Context{Name: "test", AuthMethod: config.AuthMethodNone, URL: server.URL})
+	t.Cleanup(func() { config.SetEphemeralContext(nil) })
+	return &requested
+}
+
+func newOutputCommand(format string) (*cobra.Command, *bytes.Buffer) {
+	cmd := &cobra.Command{}
+	cmd.Flags().String("output", format, "")
+	out := &bytes.Buffer{}
+	cmd.SetOut(out)
+	return cmd, out
+}
+
+func TestFetchAndPrintCollectionLimit(t *testing.T) {
+	requested := useCollectionServer(t)
+	cmd, out := newOutputCommand("json")
+
+	require.NoError(t, FetchAndPrint(cmd, "items", &FetchAndPrintOptions{IsCollection: true, Limit: 3}))
+
+	var result struct {
+		Items []map[string]any `json:"items"`
+		Total int              `json:"total"`
+	}
+	require.Nil(t, json.Unmarshal(out.Bytes(), &result))
+	assert.Equal(t, 3, result.Total)
+	assert.Equal(t, "2-a", result.Items[2]["id"])
+	assert.Equal(t, []int{1, 2}, *requested) // no pages are requested after the limit is reached
+}
+
+func TestFetchAndPrintCollectionStreaming(t *testing.T) {
+	requested := useCollectionServer(t)
+	cmd, out := newOutputCommand("ndjson")
+
+	require.NoError(t, FetchAndPrint(cmd, "items", &FetchAndPrintOptions{IsCollection: true, Limit: 5}))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Equal(t, 5, len(lines))
+	assert.JSONEq(t, `{"id":"1-a"}`, lines[0])
+	assert.JSONEq(t, `{"id":"3-a"}`, lines[4])
+	assert.Equal(t, []int{1, 2, 3}, *requested)
+
+	// without a limit, all items are displayed
+	cmd, out = newOutputCommand("ndjson")
+	require.NoError(t, FetchAndPrint(cmd, "items", &FetchAndPrintOptions{IsCollection: true}))
+	assert.Equal(t, 6, len(strings.Split(strings.TrimSpace(out.String()), "\n")))
+}
+
+func TestFetchAndPrintFailure(t *testing.T) {
+	useCollectionServer(t)
+	cmd, out := newOutputCommand("json")
+
+	err := FetchAndPrint(cmd, "missing", nil)
+
+	require.NotNil(t, err)
+	assert.Equal(t, api.ErrorKindNotFound, api.ErrorKindOf(err))
+	assert.Empty(t, out.String())
+}
@@ -0,0 +1,394 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+// Profile bundles: portable YAML or JSON files with one or more profiles, for sharing profiles
+// between config files (e.g., with a new teammate). Bundles have the same layout as config files,
+// so a config file can also be imported as a bundle. Secrets are redacted unless requested otherwise.
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// RedactedValue is the placeholder for secrets omitted from exported profiles
+const RedactedValue = "REDACTED"
+
+// Import conflict policies, for profiles that already exist in the config file
+const (
+	ImportConflictFail      = "fail"      // fail the import, without changing the config file
+	ImportConflictRename    = "rename"    // import the profile under a new name, e.g., prod-2
+	ImportConflictOverwrite = "overwrite" // replace the existing profile
+)
+
+// ProfileBundle contains exported profiles
+type ProfileBundle struct {
+	SchemaVersion int               `json:"schema_version" yaml:"schema_version"` // see CurrentSchemaVersion
+	Contexts      []ExportedContext `json:"contexts" yaml:"contexts"`
+}
+
+// ExportedContext is a profile in a bundle, optionally along with the contents of its secret file
+type ExportedContext struct {
+	Context            `yaml:",inline"`
+	SecretFileContents string `json:"secret_file_contents,omitempty" yaml:"secret_file_contents,omitempty"`
+}
+
+// MarshalYAML encodes the profile without the settings that are not set, keeping the settings' order
+func (e ExportedContext) MarshalYAML() (interface{}, error) {
+	var node yaml.Node
+	if err := node.Encode(&e.Context); err != nil {
+		return nil, err
+	}
+	pruneEmptyNode(&node)
+	if e.SecretFileContents != "" {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "secret_file_contents"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: e.SecretFileContents})
+	}
+	return &node, nil
+}
+
+// MarshalJSON encodes the profile without the settings that are not set
+func (e ExportedContext) MarshalJSON() ([]byte, error) {
+	settings, err := settingsOf(&e.Context)
+	if err != nil {
+		return nil, err
+	}
+	if e.SecretFileContents != "" {
+		settings["secret_file_contents"] = e.SecretFileContents
+	}
+	return json.Marshal(settings)
+}
+
+// pruneEmptyNode removes the mapping entries with empty values from a YAML node, see pruneEmpty
+func pruneEmptyNode(node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	content := []*yaml.Node{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		value := node.Content[i+1]
+		pruneEmptyNode(value)
+		if (value.Kind == yaml.ScalarNode && value.Tag == "!!str" && value.Value == "") || (value.Kind == yaml.MappingNode && len(value.Content) == 0) {
+			continue
+		}
+		content = append(content, node.Content[i], value)
+	}
+	node.Content = content
+}
+
+// ImportResult describes how a profile from a bundle was imported
+type ImportResult struct {
+	Name       string   // name of the profile in the bundle
+	ImportedAs string   // name of the profile in the config file
+	Replaced   bool     // true if an existing profile was overwritten
+	SecretFile string   // path of the secret file written from the bundle, if any
+	Warnings   []string // settings that need attention, e.g., redacted secrets
+}
+
+// GetImportConflictPolicies returns the list of supported import conflict policies
+func GetImportConflictPolicies() []string {
+	return []string{ImportConflictFail, ImportConflictRename, ImportConflictOverwrite}
+}
+
+// ExportProfiles returns a bundle with the named profiles. A profile that inherits from a profile that is
+// not exported is exported with its effective settings, without the parent. Unless includeSecrets is
+// set, tokens are removed (replaced with RedactedValue, if provided by the user) and the contents of
+// secret files are not included.
+func ExportProfiles(names []string, includeSecrets bool) (*ProfileBundle, error) {
+	bundle := &ProfileBundle{SchemaVersion: CurrentSchemaVersion, Contexts: []ExportedContext{}}
+	contexts := getConfig().Contexts
+	for _, name := range names {
+		// get the profile, incl. its secrets only if needed (they may require a passphrase)
+		var ctx *Context
+		var err error
+		if includeSecrets {
+			ctx, err = GetContext(name)
+		} else {
+			ctx, err = GetStoredContext(name)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// keep the inheritance if the parent is exported too
+		if ctx.Parent != "" {
+			if slices.Contains(names, ctx.Parent) {
+				inherited, err := inheritedSettings(contexts, ctx.Name, ctx.Parent)
+				if err != nil {
+					return nil, fmt.Errorf("failed to export profile %q: %w", name, err)
+				}
+				if ctx, err = ownSettings(ctx, inherited); err != nil {
+					return nil, fmt.Errorf("failed to export profile %q: %w", name, err)
+				}
+			} else {
+				ctx.Parent = ""
+				ctx.Overrides = nil
+			}
+		}
+
+		exported := ExportedContext{Context: *ctx}
+		if includeSecrets {
+			if ctx.SecretFile != "" {
+				data, err := os.ReadFile(ctx.SecretFile)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read the secret file of profile %q: %w", name, err)
+				}
+				exported.SecretFileContents = string(data)
+			}
+		} else {
+			redactSecrets(&exported)
+		}
+		if len(exported.SubsystemConfigs) == 0 {
+			exported.SubsystemConfigs = nil
+		}
+		bundle.Contexts = append(bundle.Contexts, exported)
+	}
+	return bundle, nil
+}
+
+// redactSecrets removes the tokens obtained by logging in and replaces user-provided secrets with placeholders.
+// The credential helper command is removed too, as it is specific to the user's machine and runs on import.
+func redactSecrets(exported *ExportedContext) {
+	exported.CredentialHelper = ""
+	if exported.CredentialStore == CredentialStoreHelper {
+		exported.CredentialStore = ""
+	}
+	if exported.Token != "" && exported.AuthMethod == AuthMethodJWT {
+		exported.Token = RedactedValue // provided by the user, who needs to provide it again
+	} else {
+		exported.Token = ""
+	}
+	exported.RefreshToken = ""
+	exported.TokenExpiry = ""
+	if exported.SecretFile != "" {
+		exported.SecretFileContents = RedactedValue
+	}
+}
+
+// ReadProfileBundle parses a bundle (or a config file) in YAML or JSON and upgrades its profiles to the
+// current schema version. The source is used only in error messages.
+func ReadProfileBundle(data []byte, source string) (*ProfileBundle, error) {
+	var bundle ProfileBundle
+	if err := yaml.Unmarshal(data, &bundle); err != nil { // nb: JSON is valid YAML
+		return nil, fmt.Errorf("failed to parse profiles from %q: %w", source, err)
+	}
+	if bundle.SchemaVersion > CurrentSchemaVersion {
+		return nil, &ErrNewerSchema{source, bundle.SchemaVersion}
+	}
+	if len(bundle.Contexts) == 0 {
+		return nil, fmt.Errorf("no profiles found in %q", source)
+	}
+
+	// upgrade the profiles
+	c := configFileContents{SchemaVersion: bundle.SchemaVersion}
+	for _, exported := range bundle.Contexts {
+		c.Contexts = append(c.Contexts, exported.Context)
+	}
+	migrateContents(&c)
+	names := map[string]bool{}
+	for i := range bundle.Contexts {
+		bundle.Contexts[i].Context = c.Contexts[i]
+		name := bundle.Contexts[i].Name
+		if name == "" {
+			return nil, fmt.Errorf("profile #%d in %q has no name", i+1, source)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("profile %q appears more than once in %q", name, source)
+		}
+		if strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+			return nil, fmt.Errorf("invalid profile name %q in %q: profile names cannot contain path separators or \"..\"", name, source)
+		}
+		if bundle.Contexts[i].SecretFileContents != "" && bundle.Contexts[i].SecretFile == "" {
+			return nil, fmt.Errorf("profile %q in %q has secret_file_contents but no secret_file", name, source)
+		}
+		names[name] = true
+	}
+	bundle.SchemaVersion = c.SchemaVersion
+	return &bundle, nil
+}
+
+// ImportProfiles merges the bundle's profiles into the config file, resolving conflicts with existing
+// profiles per the conflict policy. Each imported profile is checked with validate, with the settings
+// it inherits, if any; nothing is imported if any profile fails. Secret file contents included in the
+// bundle are written into files next to the config file. Credential helper commands are not imported unless
+// allowCredentialHelper is set, since they would run when the profile is used. If dryRun is set, the config
+// file is not changed.
+func ImportProfiles(bundle *ProfileBundle, conflict string, validate func(*Context) error, allowCredentialHelper bool, dryRun bool) ([]ImportResult, error) {
+	if !slices.Contains(GetImportConflictPolicies(), conflict) {
+		return nil, fmt.Errorf(`invalid conflict policy %q; must be one of {"%v"}`, conflict, strings.Join(GetImportConflictPolicies(), `", "`))
+	}
+
+	unlock := lockConfigFile()
+	defer unlock()
+	if err := syncWithConfigFile(); err != nil && !os.IsNotExist(err) {
+		log.Infof("Importing profiles without re-reading the config file: %v", err)
+	}
+	cfg := getConfig()
+
+	// determine the names of the imported profiles
+	existing := map[string]bool{}
+	for _, c := range cfg.Contexts {
+		existing[c.Name] = true
+	}
+	renames := map[string]string{}
+	results := []ImportResult{}
+	for _, exported := range bundle.Contexts {
+		result := ImportResult{Name: exported.Name, ImportedAs: exported.Name}
+		if existing[exported.Name] {
+			switch conflict {
+			case ImportConflictFail:
+				return nil, fmt.Errorf("profile %q already exists; use a conflict policy to rename or overwrite it", exported.Name)
+			case ImportConflictRename:
+				for i := 2; existing[result.ImportedAs] || isImportedName(bundle, result.ImportedAs); i++ {
+					result.ImportedAs = fmt.Sprintf("%v-%d", exported.Name, i)
+				}
+				renames[exported.Name] = result.ImportedAs
+			case ImportConflictOverwrite:
+				result.Replaced = true
+			}
+		}
+		existing[result.ImportedAs] = true
+		results = append(results, result)
+	}
+
+	// merge the profiles into the config file's profiles
+	contexts := slices.Clone(cfg.Contexts)
+	previous := map[string]*Context{}
+	for i, exported := range bundle.Contexts {
+		ctx := exported.Context
+		ctx.Name = results[i].ImportedAs
+		if renamed, found := renames[ctx.Parent]; found {
+			ctx.Parent = renamed
+		}
+		results[i].Warnings = importWarnings(&exported, ctx.Name, allowCredentialHelper)
+		if ctx.Token == RedactedValue {
+			ctx.Token = ""
+		}
+		if !allowCredentialHelper {
+			ctx.CredentialHelper = ""
+			if ctx.CredentialStore == CredentialStoreHelper {
+				ctx.CredentialStore = ""
+			}
+		}
+		if idx := slices.IndexFunc(contexts, func(c Context) bool { return c.Name == ctx.Name }); idx >= 0 {
+			prev := contexts[idx]
+			previous[ctx.Name] = &prev
+			contexts[idx] = ctx
+		} else {
+			contexts = append(contexts, ctx)
+		}
+	}
+
+	// validate the imported profiles with their inherited settings, which also checks their parents
+	for _, result := range results {
+		settings, _, err := resolveSettings(contexts, result.ImportedAs, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid profile %q: %w", result.Name, err)
+		}
+		resolved, err := contextFromSettings(settings)
+		if err != nil {
+			return nil, fmt.Errorf("invalid profile %q: %w", result.Name, err)
+		}
+		if err := validate(resolved); err != nil {
+			return nil, fmt.Errorf("invalid profile %q: %w", result.Name, err)
+		}
+	}
+	if dryRun {
+		return results, nil
+	}
+
+	// write the secret files and keep the secrets in the profiles' credential stores
+	for i, exported := range bundle.Contexts {
+		ctx := findContext(contexts, results[i].ImportedAs)
+		if exported.SecretFileContents != "" && exported.SecretFileContents != RedactedValue {
+			path, err := writeImportedSecretFile(ctx.Name, exported.SecretFile, exported.SecretFileContents)
+			if err != nil {
+				return nil, err
+			}
+			ctx.SecretFile = path
+			results[i].SecretFile = path
+		}
+		if err := saveSecrets(ctx, previous[ctx.Name]); err != nil {
+			return nil, err
+		}
+	}
+
+	// update the config file, making the first imported profile current if there was no profile
+	update := map[string]interface{}{"contexts": contexts}
+	if len(cfg.Contexts) == 0 {
+		update["current_context"] = results[0].ImportedAs
+	}
+	updateConfigFile(update)
+	for _, result := range results {
+		log.WithFields(log.Fields{"profile": result.Name, "imported_as": result.ImportedAs, "replaced": result.Replaced}).Info("Imported profile")
+	}
+	return results, nil
+}
+
+func isImportedName(bundle *ProfileBundle, name string) bool {
+	return slices.IndexFunc(bundle.Contexts, func(c ExportedContext) bool { return c.Name == name }) >= 0
+}
+
+// importWarnings describes the secrets that were redacted from the exported profile and the credential helper
+// command, which is either dropped or runs when the profile is used (see ImportProfiles)
+func importWarnings(exported *ExportedContext, name string, allowCredentialHelper bool) []string {
+	warnings := []string{}
+	if exported.CredentialHelper != "" || exported.CredentialStore == CredentialStoreHelper {
+		if allowCredentialHelper {
+			warnings = append(warnings, fmt.Sprintf("the profile runs the credential helper command %q from the bundle; make sure that you trust it", exported.CredentialHelper))
+		} else {
+			warnings = append(warnings, fmt.Sprintf("the credential helper command %q was not imported; review it and set it with \"fsoc config set credential-store=helper credential-helper=COMMAND --profile %v\"", exported.CredentialHelper, name))
+		}
+	}
+	if exported.Token == RedactedValue {
+		warnings = append(warnings, fmt.Sprintf("the token was redacted; set it with \"fsoc config set token=TOKEN --profile %v\"", name))
+	}
+	if exported.SecretFileContents == RedactedValue {
+		warnings = append(warnings, fmt.Sprintf("the secret file was not included; provide it at %q or set it with \"fsoc config set secret-file=FILE --profile %v\"", exported.SecretFile, name))
+	}
+	return warnings
+}
+
+// writeImportedSecretFile saves the contents of an imported profile's secret file into a directory next
+// to the config file, returning the file's path
+func writeImportedSecretFile(profile string, originalPath string, contents string) (string, error) {
+	configFile := viper.ConfigFileUsed()
+	if configFile == "" {
+		home, _ := os.UserHomeDir()
+		configFile = strings.Replace(DefaultConfigFile, "~", home, 1)
+	}
+	dir := configFile + ".secrets"
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create directory for secret files: %w", err)
+	}
+	path, err := filepath.Abs(filepath.Join(dir, url.PathEscape(profile)+"-"+filepath.Base(originalPath)))
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		return "", fmt.Errorf("failed to write the secret file of profile %q: %w", profile, err)
+	}
+	return path, nil
+}
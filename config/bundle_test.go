@@ -0,0 +1,210 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func noValidation(*Context) error { return nil }
+
+func TestExportProfiles(t *testing.T) {
+	defer viper.Reset()
+	setupConfigFile(t, inheritanceConfig)
+
+	// secrets are redacted, inheritance is kept only if the parent is exported too
+	bundle, err := ExportProfiles([]string{"team", "dev"}, false)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(bundle.Contexts))
+	team, dev := bundle.Contexts[0], bundle.Contexts[1]
+	assert.Equal(t, "", team.Parent)
+	assert.Equal(t, AuthMethodServicePrincipal, team.AuthMethod)
+	assert.Equal(t, "123", team.Tenant)
+	assert.Equal(t, "", team.Token)
+	assert.Equal(t, RedactedValue, team.SecretFileContents)
+	assert.Equal(t, "team", dev.Parent)
+	assert.Equal(t, "", dev.URL) // inherited
+	assert.Equal(t, "", dev.Token)
+
+	// the bundle contains only the settings that are set
+	data, err := yaml.Marshal(bundle)
+	require.Nil(t, err)
+	assert.NotContains(t, string(data), `""`)
+	data, err = json.Marshal(bundle)
+	require.Nil(t, err)
+	assert.NotContains(t, string(data), `""`)
+	assert.NotContains(t, string(data), `{}`)
+
+	// secrets are included on request
+	secretFile := filepath.Join(t.TempDir(), "creds.json")
+	require.Nil(t, os.WriteFile(secretFile, []byte(`{"secret":"s3cret"}`), 0600))
+	ctx, err := GetContext("base")
+	require.Nil(t, err)
+	ctx.SecretFile = secretFile
+	require.Nil(t, UpsertContext(ctx))
+	bundle, err = ExportProfiles([]string{"base"}, true)
+	require.Nil(t, err)
+	assert.Equal(t, "base-token", bundle.Contexts[0].Token)
+	assert.Equal(t, `{"secret":"s3cret"}`, bundle.Contexts[0].SecretFileContents)
+
+	_, err = ExportProfiles([]string{"missing"}, false)
+	assert.True(t, errors.Is(err, ErrProfileNotFound))
+}
+
+func TestReadProfileBundle(t *testing.T) {
+	bundle, err := ReadProfileBundle([]byte(`{"contexts":[{"name":"old","auth_method":"oauth","server":"old.saas.observer.com"}]}`), "old.json")
+	require.Nil(t, err)
+	assert.Equal(t, CurrentSchemaVersion, bundle.SchemaVersion)
+	assert.Equal(t, "https://old.saas.observer.com", bundle.Contexts[0].URL)
+
+	_, err = ReadProfileBundle([]byte("schema_version: 1000\ncontexts:\n  - name: new\n"), "new.yaml")
+	var newer *ErrNewerSchema
+	assert.True(t, errors.As(err, &newer))
+	_, err = ReadProfileBundle([]byte("contexts: []\n"), "empty.yaml")
+	assert.ErrorContains(t, err, "no profiles")
+	_, err = ReadProfileBundle([]byte("contexts:\n  - name: a\n  - name: a\n"), "dup.yaml")
+	assert.ErrorContains(t, err, "more than once")
+	_, err = ReadProfileBundle([]byte("contexts:\n  - name: ../../x\n"), "bad.yaml")
+	assert.ErrorContains(t, err, "invalid profile name")
+	_, err = ReadProfileBundle([]byte("contexts:\n  - name: a\n    secret_file_contents: secret\n"), "bad.yaml")
+	assert.ErrorContains(t, err, "no secret_file")
+}
+
+func TestImportProfiles(t *testing.T) {
+	defer viper.Reset()
+	fileName := setupConfigFile(t, inheritanceConfig)
+	bundle, err := ReadProfileBundle([]byte(`
+contexts:
+    - name: base
+      auth_method: oauth
+      url: https://other.saas.observer.com
+    - name: child
+      parent: base
+      tenant: "456"
+    - name: jwt
+      auth_method: jwt
+      token: REDACTED
+    - name: ci
+      auth_method: service-principal
+      secret_file: /home/someone/ci.json
+      secret_file_contents: '{"secret":"s3cret"}'
+`), "bundle.yaml")
+	require.Nil(t, err)
+
+	// conflicts fail the import by default, without changing the file
+	before, err := os.ReadFile(fileName)
+	require.Nil(t, err)
+	_, err = ImportProfiles(bundle, ImportConflictFail, noValidation, false, false)
+	assert.ErrorContains(t, err, `"base" already exists`)
+	_, err = ImportProfiles(bundle, ImportConflictRename, func(ctx *Context) error {
+		if ctx.Name == "ci" {
+			return errors.New("bad profile")
+		}
+		return nil
+	}, false, false)
+	assert.ErrorContains(t, err, "bad profile")
+	after, err := os.ReadFile(fileName)
+	require.Nil(t, err)
+	assert.Equal(t, string(before), string(after))
+
+	// rename, keeping the inheritance within the bundle
+	results, err := ImportProfiles(bundle, ImportConflictRename, noValidation, false, false)
+	require.Nil(t, err)
+	assert.Equal(t, "base-2", results[0].ImportedAs)
+	assert.Equal(t, "child", results[1].ImportedAs)
+	assert.Equal(t, 1, len(results[2].Warnings))
+	child, err := GetContext("child")
+	require.Nil(t, err)
+	assert.Equal(t, "base-2", child.Parent)
+	assert.Equal(t, "https://other.saas.observer.com", child.URL)
+	jwt, err := GetContext("jwt")
+	require.Nil(t, err)
+	assert.Equal(t, "", jwt.Token)
+	ci, err := GetContext("ci")
+	require.Nil(t, err)
+	assert.Equal(t, fileName+".secrets/ci-ci.json", ci.SecretFile)
+	data, err := os.ReadFile(ci.SecretFile)
+	require.Nil(t, err)
+	assert.Equal(t, `{"secret":"s3cret"}`, string(data))
+	base, err := GetContext("base")
+	require.Nil(t, err)
+	assert.Equal(t, AuthMethodServicePrincipal, base.AuthMethod) // unchanged
+
+	// overwrite
+	results, err = ImportProfiles(bundle, ImportConflictOverwrite, noValidation, false, false)
+	require.Nil(t, err)
+	assert.True(t, results[0].Replaced)
+	base, err = GetContext("base")
+	require.Nil(t, err)
+	assert.Equal(t, AuthMethodOAuth, base.AuthMethod)
+	assert.Equal(t, 7, len(ListAllContexts())) // base, team, dev, base-2, child, jwt, ci
+
+	_, err = ImportProfiles(bundle, "merge", noValidation, false, false)
+	assert.ErrorContains(t, err, "invalid conflict policy")
+}
+
+func TestImportCredentialHelper(t *testing.T) {
+	defer viper.Reset()
+	setupConfigFile(t, inheritanceConfig)
+	bundle, err := ReadProfileBundle([]byte(`
+contexts:
+    - name: helper
+      auth_method: oauth
+      url: https://other.saas.observer.com
+      credential_store: helper
+      credential_helper: some-command
+`), "bundle.yaml")
+	require.Nil(t, err)
+
+	// credential helpers are not imported by default
+	results, err := ImportProfiles(bundle, ImportConflictFail, noValidation, false, false)
+	require.Nil(t, err)
+	assert.Contains(t, results[0].Warnings[0], "was not imported")
+	stored := findContext(getConfig().Contexts, "helper")
+	require.NotNil(t, stored)
+	assert.Equal(t, "", stored.CredentialStore)
+	assert.Equal(t, "", stored.CredentialHelper)
+
+	// ... unless allowed
+	results, err = ImportProfiles(bundle, ImportConflictOverwrite, noValidation, true, true)
+	require.Nil(t, err)
+	assert.Contains(t, results[0].Warnings[0], "make sure that you trust it")
+
+	// nor exported without secrets
+	exported := ExportedContext{Context: *stored}
+	exported.CredentialStore = CredentialStoreHelper
+	exported.CredentialHelper = "some-command"
+	redactSecrets(&exported)
+	assert.Equal(t, "", exported.CredentialStore)
+	assert.Equal(t, "", exported.CredentialHelper)
+}
+
+func TestWriteImportedSecretFile(t *testing.T) {
+	defer viper.Reset()
+	fileName := setupConfigFile(t, inheritanceConfig)
+
+	path, err := writeImportedSecretFile("../../x", "/home/someone/ci.json", "secret")
+	require.Nil(t, err)
+	assert.Equal(t, fileName+".secrets", filepath.Dir(path))
+}
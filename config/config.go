@@ -23,6 +23,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/apex/log"
 	"github.com/spf13/afero"
@@ -37,7 +38,16 @@ const (
 
 var activeProfile string
 
+// viperMutex guards the config file's contents held by viper, which may be read and updated
+// concurrently, e.g., when a command runs for multiple profiles and logins save their tokens
+var viperMutex sync.RWMutex
+
 func getContext(name string) *Context {
+	// use the profile defined by environment variables, if selected
+	if ctx := getEphemeralContext(name); ctx != nil {
+		return ctx
+	}
+
 	// read config file
 	cfg := getConfig()
 	if len(cfg.Contexts) == 0 {
@@ -47,6 +57,15 @@ func getContext(name string) *Context {
 	// locate & return the named context
 	for _, c := range cfg.Contexts {
 		if c.Name == name {
+			// apply the settings inherited from the parent profile(s), if any
+			if c.Parent != "" {
+				if resolved, _, err := ResolveContext(name); err != nil {
+					log.Warnf("Failed to apply the settings inherited by profile %q: %v; using only the profile's own settings", name, err)
+				} else {
+					c = *resolved
+				}
+			}
+
 			// ensure a subsystem config map exists, even if empty
 			if c.SubsystemConfigs == nil {
 				c.SubsystemConfigs = map[string]map[string]any{}
@@ -58,46 +77,34 @@ func getContext(name string) *Context {
 	return nil
 }
 
-func checkUpgradeScheme(c *configFileContents) {
-	needReWrite := false
-	newContexts := make([]Context, len(c.Contexts))
-	for i, context := range c.Contexts {
-		if context.Server != "" {
-			context.URL = "https://" + context.Server
-			log.WithFields(log.Fields{
-				"context": context.Name,
-				"server":  context.Server,
-				"url":     context.URL,
-			}).Warn("The \"server\" config attribute is deprecated; replacing it with \"url\" now.")
-			context.Server = ""
-			needReWrite = true
-		}
-		newContexts[i] = context
-	}
-	if needReWrite {
-		updateConfigFile(map[string]interface{}{
-			"contexts": newContexts,
-		})
-		c.Contexts = newContexts
-		log.Warnf("Config file updated to upgrade settings schema.")
-	}
-}
-
 func getConfig() configFileContents {
 	// read config file with all contexts
 	var c configFileContents
+	viperMutex.RLock()
 	err := viper.Unmarshal(&c)
+	viperMutex.RUnlock()
 	if err != nil {
 		log.Fatalf("unable to read config: %v", err)
 	}
 
-	// check if scheme needs to be upgraded; do it and update file if so
-	checkUpgradeScheme(&c)
+	// upgrade the contents to the current schema version, if needed (see migrate.go)
+	checkSchemaVersion(&c)
 
 	return c
 }
 
 func updateConfigFile(keyValues map[string]interface{}) {
+	// refuse to downgrade the file's schema, and back up the file if upgrading its schema
+	if _, err := upgradeConfigFile(keyValues); err != nil {
+		log.Fatalf("%v", err)
+	}
+	writeConfigFile(keyValues)
+}
+
+func writeConfigFile(keyValues map[string]interface{}) {
+	viperMutex.Lock()
+	defer viperMutex.Unlock()
+
 	// update values
 	for key, value := range keyValues {
 		viper.Set(key, value)
@@ -156,6 +163,21 @@ func updateContext(ctx *Context) {
 		log.Fatalf("bug: context name cannot be empty when updating context")
 	}
 
+	// the profile defined by environment variables is updated in memory only, never saved
+	if ephemeralContext != nil && ctx.Name == ephemeralContext.Name {
+		ephemeralContext = cloneContext(ctx)
+		log.WithField("profile", ctx.Name).Info("Updated context in memory only (defined by environment variables)")
+		return
+	}
+
+	// other fsoc processes (e.g., parallel jobs) may update the config file concurrently, so apply
+	// the change to the file's latest contents, under a lock
+	unlock := lockConfigFile()
+	defer unlock()
+	if err := syncWithConfigFile(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Infof("Updating the context without re-reading the config file: %v", err)
+	}
+
 	cfg := getConfig()
 	for idx, c := range cfg.Contexts {
 		if c.Name == ctx.Name {
@@ -175,10 +197,31 @@ func updateContext(ctx *Context) {
 	}
 
 	// copy context if needed
+	var previous *Context
+	if contextExists {
+		prev := *ctxPtr
+		previous = &prev
+	}
 	if ctx != ctxPtr {
 		*ctxPtr = *ctx // copy, in case ctx is not what GetCurrentContext() had returned
 	}
 
+	// keep only the profile's own settings, if it inherits from a parent profile
+	if ctxPtr.Parent != "" {
+		if inherited, err := inheritedSettings(cfg.Contexts, ctxPtr.Name, ctxPtr.Parent); err != nil {
+			log.Warnf("Saving all settings of profile %q, as its inherited settings cannot be determined: %v", ctxPtr.Name, err)
+		} else if own, err := ownSettings(ctxPtr, inherited); err != nil {
+			log.Fatalf("failed to update profile %q: %v", ctxPtr.Name, err)
+		} else {
+			*ctxPtr = *own
+		}
+	}
+
+	// keep secrets in the profile's credential store rather than in the config file (unless inline)
+	if err := saveSecrets(ctxPtr, previous); err != nil {
+		log.Fatalf("%v", err)
+	}
+
 	update := map[string]interface{}{"contexts": cfg.Contexts}
 	if !contextExists && len(cfg.Contexts) == 1 { // just created the first context, set it as current
 		update["current_context"] = ctx.Name
@@ -245,6 +288,8 @@ func GetCurrentProfileName() string {
 	// use the profile from command line or the config file's current
 	if activeProfile != "" {
 		profile = activeProfile
+	} else if ephemeralContext != nil {
+		profile = ephemeralContext.Name
 	} else {
 		// get profile that is current for the config file
 		cfg := getConfig()
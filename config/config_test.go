@@ -15,8 +15,10 @@
 package config
 
 import (
+	"context"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
@@ -39,6 +41,7 @@ current_context: default
 	fo.Close()
 	assert.Nil(t, err, "Failed to write temp config file")
 	defer os.Remove(fileName)
+	defer os.Remove(fileName + ".v0.bak")
 	err = viper.ReadInConfig()
 	assert.Nil(t, err, "Failed to read config file")
 
@@ -53,3 +56,164 @@ current_context: default
 	assert.Equal(t, "", newContexts[0].Server)
 	assert.Equal(t, "https://mytenant.saas.observer.com", newContexts[0].URL)
 }
+
+func TestClearTokens(t *testing.T) {
+	viper.Reset() // drop values set by other tests
+	defer viper.Reset()
+	fileName := t.TempDir() + "/config.yaml"
+	viper.SetConfigFile(fileName)
+	viper.SetConfigType("yaml")
+	contents := `
+contexts:
+    - name: default
+      auth_method: oauth
+      url: https://mytenant.saas.observer.com
+      token: access-token
+      refresh_token: refresh-token
+    - name: other
+      auth_method: service-principal
+      url: https://mytenant.saas.observer.com
+current_context: default
+`
+	assert.Nil(t, os.WriteFile(fileName, []byte(contents), 0600))
+	assert.Nil(t, viper.ReadInConfig())
+
+	previous, err := ClearTokens("default")
+	assert.Nil(t, err)
+	assert.Equal(t, "access-token", previous.Token)
+	assert.Equal(t, "refresh-token", previous.RefreshToken)
+
+	assert.Nil(t, viper.ReadInConfig())
+	ctx, err := GetContext("default")
+	assert.Nil(t, err)
+	assert.Equal(t, "", ctx.Token)
+	assert.Equal(t, "", ctx.RefreshToken)
+	assert.Equal(t, "https://mytenant.saas.observer.com", ctx.URL)
+
+	previous, err = ClearTokens("other")
+	assert.Nil(t, err)
+	assert.Equal(t, "", previous.Token)
+
+	_, err = ClearTokens("missing")
+	assert.ErrorIs(t, err, ErrProfileNotFound)
+}
+
+func TestReloadContext(t *testing.T) {
+	viper.Reset() // drop values set by other tests
+	defer viper.Reset()
+	fileName := t.TempDir() + "/config.yaml"
+	viper.SetConfigFile(fileName)
+	viper.SetConfigType("yaml")
+	contents := `
+contexts:
+    - name: default
+      auth_method: service-principal
+      token: old-token
+current_context: default
+`
+	assert.Nil(t, os.WriteFile(fileName, []byte(contents), 0600))
+	assert.Nil(t, viper.ReadInConfig())
+	assert.Nil(t, UpsertContext(&Context{Name: "default", AuthMethod: "service-principal", Token: "my-token"})) // in-memory state now overrides the file
+
+	// another process refreshes the token
+	contents = `
+contexts:
+    - name: default
+      auth_method: service-principal
+      token: new-token
+      token_expiry: "2030-01-01T00:00:00Z"
+current_context: default
+`
+	assert.Nil(t, os.WriteFile(fileName, []byte(contents), 0600))
+	assert.Equal(t, "my-token", GetCurrentContext().Token)
+
+	ctx, err := ReloadContext("default")
+	assert.Nil(t, err)
+	assert.Equal(t, "new-token", ctx.Token)
+	assert.Equal(t, "2030-01-01T00:00:00Z", ctx.TokenExpiry)
+	assert.Equal(t, "new-token", GetCurrentContext().Token)
+
+	_, err = ReloadContext("missing")
+	assert.ErrorIs(t, err, ErrProfileNotFound)
+}
+
+func TestLockProfile(t *testing.T) {
+	viper.Reset() // drop values set by other tests
+	defer viper.Reset()
+	fileName := t.TempDir() + "/config.yaml"
+	viper.SetConfigFile(fileName)
+
+	unlock, err := LockProfile(context.Background(), "ci", time.Second)
+	assert.Nil(t, err)
+
+	// held by "another process"
+	_, err = LockProfile(context.Background(), "ci", 200*time.Millisecond)
+	assert.NotNil(t, err)
+
+	// other profiles are not affected
+	unlockOther, err := LockProfile(context.Background(), "other", 200*time.Millisecond)
+	assert.Nil(t, err)
+	unlockOther()
+
+	// released while waiting
+	held := unlock
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		held()
+	}()
+	unlock, err = LockProfile(context.Background(), "ci", 5*time.Second)
+	assert.Nil(t, err)
+
+	// stale locks are broken
+	lockFile := fileName + ".ci.lock"
+	old := time.Now().Add(-staleLockAge - time.Minute)
+	assert.Nil(t, os.Chtimes(lockFile, old, old))
+	unlock, err = LockProfile(context.Background(), "ci", 200*time.Millisecond)
+	assert.Nil(t, err)
+	unlock()
+	_, err = os.Stat(lockFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestUpdateContextKeepsConcurrentChanges(t *testing.T) {
+	viper.Reset() // drop values set by other tests
+	defer viper.Reset()
+	fileName := t.TempDir() + "/config.yaml"
+	viper.SetConfigFile(fileName)
+	viper.SetConfigType("yaml")
+	contents := `
+contexts:
+    - name: a
+      auth_method: service-principal
+    - name: b
+      auth_method: service-principal
+current_context: a
+`
+	assert.Nil(t, os.WriteFile(fileName, []byte(contents), 0600))
+	assert.Nil(t, viper.ReadInConfig())
+
+	// another process logs in with profile b
+	contents = `
+contexts:
+    - name: a
+      auth_method: service-principal
+    - name: b
+      auth_method: service-principal
+      token: token-b
+current_context: a
+`
+	assert.Nil(t, os.WriteFile(fileName, []byte(contents), 0600))
+
+	// this process logs in with profile a
+	assert.Nil(t, UpsertContext(&Context{Name: "a", AuthMethod: "service-principal", Token: "token-a"}))
+
+	assert.Nil(t, viper.ReadInConfig())
+	a, err := GetContext("a")
+	assert.Nil(t, err)
+	assert.Equal(t, "token-a", a.Token)
+	b, err := GetContext("b")
+	assert.Nil(t, err)
+	assert.Equal(t, "token-b", b.Token)
+	_, err = os.Stat(fileName + ".lock")
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,341 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/apex/log"
+	"github.com/spf13/viper"
+)
+
+// Supported credential stores, which define where a profile's secrets (access and refresh tokens) are kept
+const (
+	// In the config file itself, in clear text (default)
+	CredentialStoreInline = "inline"
+	// In a separate file, encrypted with a key derived from a passphrase
+	CredentialStoreEncryptedFile = "encrypted-file"
+	// In an external helper command, similar to git credential helpers
+	CredentialStoreHelper = "helper"
+)
+
+// Credentials are the secrets of a profile that can be kept outside of the config file
+type Credentials struct {
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// CredentialStore keeps the credentials of profiles outside of the config file
+type CredentialStore interface {
+	// Get returns the credentials stored for the profile; empty credentials if none are stored
+	Get(profile string) (*Credentials, error)
+	// Store saves the credentials for the profile, replacing any stored before
+	Store(profile string, creds *Credentials) error
+	// Erase removes the credentials for the profile, if any
+	Erase(profile string) error
+	// Location describes where the credentials are kept, for display
+	Location() string
+}
+
+// credentialCache keeps credentials retrieved from or saved to stores during this
+// execution, so that each store is accessed at most once per profile
+var credentialCache = struct {
+	sync.Mutex
+	entries map[string]Credentials
+}{entries: map[string]Credentials{}}
+
+// GetCredentialStores returns the list of supported credential store names
+func GetCredentialStores() []string {
+	return []string{CredentialStoreInline, CredentialStoreEncryptedFile, CredentialStoreHelper}
+}
+
+// SecretsLocation returns a human-readable description of where the profile's secrets are kept
+func SecretsLocation(ctx *Context) string {
+	if getEphemeralContext(ctx.Name) != nil {
+		return "in memory (from environment variables, not saved)"
+	}
+	if isInlineStore(ctx) {
+		return "inline (in the config file)"
+	}
+	store, err := newCredentialStore(ctx)
+	if err != nil {
+		return fmt.Sprintf("invalid credential store: %v", err)
+	}
+	return store.Location()
+}
+
+func isInlineStore(ctx *Context) bool {
+	return ctx.CredentialStore == "" || ctx.CredentialStore == CredentialStoreInline
+}
+
+// newCredentialStore creates the (non-inline) credential store configured for the context
+func newCredentialStore(ctx *Context) (CredentialStore, error) {
+	switch ctx.CredentialStore {
+	case CredentialStoreEncryptedFile:
+		return &encryptedFileStore{path: credentialsFilePath()}, nil
+	case CredentialStoreHelper:
+		if strings.TrimSpace(ctx.CredentialHelper) == "" {
+			return nil, fmt.Errorf(`the "helper" credential store requires a credential-helper command`)
+		}
+		return &helperStore{command: ctx.CredentialHelper}, nil
+	default:
+		return nil, fmt.Errorf("unknown credential store %q, must be one of %q", ctx.CredentialStore, GetCredentialStores())
+	}
+}
+
+// credentialsFilePath returns the path of the encrypted credentials file, which is kept next to the config file
+func credentialsFilePath() string {
+	configFile := viper.ConfigFileUsed()
+	if configFile == "" {
+		home, _ := os.UserHomeDir()
+		configFile = strings.Replace(DefaultConfigFile, "~", home, 1)
+	}
+	return configFile + ".credentials"
+}
+
+func cacheKey(store CredentialStore, profile string) string {
+	return store.Location() + "\n" + profile
+}
+
+// loadSecrets populates the context's secrets from its credential store, unless they are kept inline.
+// Failures are logged, leaving the secrets empty (which, e.g., leads to a new login)
+func loadSecrets(ctx *Context) {
+	if isInlineStore(ctx) {
+		return
+	}
+	store, err := newCredentialStore(ctx)
+	if err != nil {
+		log.Warnf("Cannot retrieve secrets for profile %q: %v", ctx.Name, err)
+		return
+	}
+
+	key := cacheKey(store, ctx.Name)
+	credentialCache.Lock()
+	defer credentialCache.Unlock()
+	creds, found := credentialCache.entries[key]
+	if !found {
+		c, err := store.Get(ctx.Name)
+		if err != nil {
+			log.Warnf("Cannot retrieve secrets for profile %q from %v: %v", ctx.Name, store.Location(), err)
+			return
+		}
+		creds = *c
+		credentialCache.entries[key] = creds
+	}
+
+	// secrets still inline (e.g., just after switching the store) are used until the profile is saved
+	if creds.Token != "" || creds.RefreshToken != "" {
+		ctx.Token = creds.Token
+		ctx.RefreshToken = creds.RefreshToken
+	}
+}
+
+// forgetSecrets drops the context's cached secrets, so that the next loadSecrets retrieves them
+// from the credential store again
+func forgetSecrets(ctx *Context) {
+	if isInlineStore(ctx) {
+		return
+	}
+	store, err := newCredentialStore(ctx)
+	if err != nil {
+		return
+	}
+	credentialCache.Lock()
+	defer credentialCache.Unlock()
+	delete(credentialCache.entries, cacheKey(store, ctx.Name))
+}
+
+// saveSecrets moves the context's secrets into its credential store, clearing them from the context
+// so that they are not written into the config file. If the profile's credential store has changed,
+// the secrets are removed from the previous store.
+func saveSecrets(ctx *Context, previous *Context) error {
+	if previous != nil && previous.CredentialStore != ctx.CredentialStore && !isInlineStore(previous) {
+		if store, err := newCredentialStore(previous); err == nil {
+			if err := eraseSecrets(store, previous.Name); err != nil {
+				log.Warnf("Failed to remove secrets for profile %q from %v: %v", previous.Name, store.Location(), err)
+			}
+		}
+	}
+	if isInlineStore(ctx) {
+		return nil
+	}
+
+	store, err := newCredentialStore(ctx)
+	if err != nil {
+		return err
+	}
+	creds := Credentials{Token: ctx.Token, RefreshToken: ctx.RefreshToken}
+
+	credentialCache.Lock()
+	defer credentialCache.Unlock()
+	key := cacheKey(store, ctx.Name)
+	if cached, found := credentialCache.entries[key]; !found || cached != creds {
+		if creds.Token == "" && creds.RefreshToken == "" {
+			err = store.Erase(ctx.Name)
+		} else {
+			err = store.Store(ctx.Name, &creds)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save secrets for profile %q to %v: %w", ctx.Name, store.Location(), err)
+		}
+		credentialCache.entries[key] = creds
+	}
+
+	ctx.Token = ""
+	ctx.RefreshToken = ""
+	return nil
+}
+
+func eraseSecrets(store CredentialStore, profile string) error {
+	credentialCache.Lock()
+	defer credentialCache.Unlock()
+	delete(credentialCache.entries, cacheKey(store, profile))
+	return store.Erase(profile)
+}
+
+// helperStore keeps credentials in an external helper command. The helper is invoked with the
+// action ("get", "store" or "erase") appended as its last argument, and receives key=value lines on
+// its standard input: "config" (the config file path), "profile" and, for "store", "token" and
+// "refresh_token". For "get", the helper prints the "token" and "refresh_token" key=value lines
+// on its standard output (nothing if no credentials are stored). This is similar to the git
+// credential helper protocol.
+type helperStore struct {
+	command string
+}
+
+func (h *helperStore) Location() string {
+	return fmt.Sprintf("credential helper %q", h.command)
+}
+
+func (h *helperStore) Get(profile string) (*Credentials, error) {
+	out, err := h.run("get", profile, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Credentials{Token: out["token"], RefreshToken: out["refresh_token"]}, nil
+}
+
+func (h *helperStore) Store(profile string, creds *Credentials) error {
+	_, err := h.run("store", profile, creds)
+	return err
+}
+
+func (h *helperStore) Erase(profile string) error {
+	_, err := h.run("erase", profile, nil)
+	return err
+}
+
+func (h *helperStore) run(action string, profile string, creds *Credentials) (map[string]string, error) {
+	args, err := SplitCommandLine(h.command)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credential helper command %q: %w", h.command, err)
+	}
+	args = append(args, action)
+
+	configFile, _ := filepath.Abs(viper.ConfigFileUsed())
+	var in bytes.Buffer
+	fmt.Fprintf(&in, "config=%v\nprofile=%v\n", configFile, profile)
+	if creds != nil {
+		fmt.Fprintf(&in, "token=%v\nrefresh_token=%v\n", creds.Token, creds.RefreshToken)
+	}
+	in.WriteString("\n")
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = &in
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("credential helper %q failed to %v: %w", h.command, action, err)
+	}
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024) // tokens can be long
+	for scanner.Scan() {
+		if key, value, found := strings.Cut(scanner.Text(), "="); found {
+			values[key] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse the output of credential helper %q: %w", h.command, err)
+	}
+	return values, nil
+}
+
+// SplitCommandLine splits a command line into the command and its arguments, separated by
+// whitespace. As in POSIX shells, single quotes preserve the quoted text as is, double quotes
+// preserve it except for backslash escapes of \" and \\, and a backslash outside of quotes
+// escapes the next character, so that paths and arguments with spaces can be quoted, e.g.,
+// "/opt/my tools/helper" --vault 'team vault'. Other shell features (e.g., variables) are not supported.
+func SplitCommandLine(command string) ([]string, error) {
+	args := []string{}
+	var arg strings.Builder
+	inArg := false // nb: needed for empty quoted arguments
+	var quote rune
+	escaped := false
+	for _, r := range command {
+		switch {
+		case escaped:
+			if quote == '"' && r != '"' && r != '\\' {
+				arg.WriteRune('\\') // within double quotes, only \" and \\ are escapes
+			}
+			arg.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("missing closing %c quote", quote)
+	}
+	if escaped {
+		return nil, errors.New("missing character after the final backslash")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	if len(args) == 0 {
+		return nil, errors.New("missing command")
+	}
+	return args, nil
+}
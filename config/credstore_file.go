@@ -0,0 +1,257 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+const (
+	FSOC_CREDENTIALS_PASSPHRASE_ENVVAR = "FSOC_CREDENTIALS_PASSPHRASE"
+
+	credentialsKDF        = "pbkdf2-sha256"
+	credentialsIterations = 600_000 // per OWASP recommendation for PBKDF2-HMAC-SHA256
+	credentialsSaltSize   = 16
+	credentialsKeySize    = 32 // AES-256
+)
+
+// encryptedFileContents is the format of the encrypted credentials file. The
+// ciphertext is the AES-GCM encryption of a JSON map of profile name to Credentials.
+type encryptedFileContents struct {
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// encryptedFileStore keeps the credentials of all profiles in a file encrypted with a
+// key derived from a passphrase, taken from the FSOC_CREDENTIALS_PASSPHRASE environment
+// variable or, if not set, prompted for on the terminal
+type encryptedFileStore struct {
+	path string
+}
+
+// passphrase and derived keys are kept for the duration of the execution, as key derivation is slow by design
+var encryptionKeys = struct {
+	sync.Mutex
+	passphrase []byte
+	keys       map[string][]byte // salt -> key
+}{keys: map[string][]byte{}}
+
+func (s *encryptedFileStore) Location() string {
+	return fmt.Sprintf("encrypted file %v", s.path)
+}
+
+func (s *encryptedFileStore) Get(profile string) (*Credentials, error) {
+	all, _, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	creds := all[profile]
+	return &creds, nil
+}
+
+func (s *encryptedFileStore) Store(profile string, creds *Credentials) error {
+	all, salt, err := s.read()
+	if err != nil {
+		return err
+	}
+	all[profile] = *creds
+	return s.write(all, salt)
+}
+
+func (s *encryptedFileStore) Erase(profile string) error {
+	all, salt, err := s.read()
+	if err != nil {
+		return err
+	}
+	if _, found := all[profile]; !found {
+		return nil
+	}
+	delete(all, profile)
+	return s.write(all, salt)
+}
+
+// read decrypts the credentials file, returning its contents and salt; a missing file has no credentials
+func (s *encryptedFileStore) read() (map[string]Credentials, []byte, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]Credentials{}, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+
+	var contents encryptedFileContents
+	if err := json.Unmarshal(data, &contents); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse credentials file %q: %w", s.path, err)
+	}
+	if contents.KDF != credentialsKDF || contents.Iterations <= 0 {
+		return nil, nil, fmt.Errorf("unsupported key derivation %q (%v iterations) in credentials file %q", contents.KDF, contents.Iterations, s.path)
+	}
+
+	key, err := deriveKey(contents.Salt, contents.Iterations)
+	if err != nil {
+		return nil, nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	plaintext, err := gcm.Open(nil, contents.Nonce, contents.Ciphertext, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt credentials file %q (wrong passphrase?)", s.path)
+	}
+
+	all := map[string]Credentials{}
+	if err := json.Unmarshal(plaintext, &all); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse decrypted credentials: %w", err)
+	}
+	return all, contents.Salt, nil
+}
+
+// write encrypts and saves the credentials file, using a new salt if none is provided
+func (s *encryptedFileStore) write(all map[string]Credentials, salt []byte) error {
+	if salt == nil {
+		salt = make([]byte, credentialsSaltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+	}
+	key, err := deriveKey(salt, credentialsIterations)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	plaintext, err := json.Marshal(all)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(encryptedFileContents{
+		KDF:        credentialsKDF,
+		Iterations: credentialsIterations,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plaintext, nil),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write credentials file: %w", err)
+	}
+	return nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// deriveKey derives the encryption key for the given salt from the passphrase
+func deriveKey(salt []byte, iterations int) ([]byte, error) {
+	encryptionKeys.Lock()
+	defer encryptionKeys.Unlock()
+
+	cacheKey := fmt.Sprintf("%x/%v", salt, iterations)
+	if key, found := encryptionKeys.keys[cacheKey]; found {
+		return key, nil
+	}
+	if encryptionKeys.passphrase == nil {
+		passphrase, err := getPassphrase()
+		if err != nil {
+			return nil, err
+		}
+		encryptionKeys.passphrase = passphrase
+	}
+	key := pbkdf2SHA256(encryptionKeys.passphrase, salt, iterations, credentialsKeySize)
+	encryptionKeys.keys[cacheKey] = key
+	return key, nil
+}
+
+// getPassphrase obtains the credentials file passphrase from the environment or, if not set, from the terminal
+func getPassphrase() ([]byte, error) {
+	if p := os.Getenv(FSOC_CREDENTIALS_PASSPHRASE_ENVVAR); p != "" {
+		return []byte(p), nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("no passphrase for the encrypted credentials file; please set the %v environment variable", FSOC_CREDENTIALS_PASSPHRASE_ENVVAR)
+	}
+	fmt.Fprint(os.Stderr, "Passphrase for the fsoc credentials file: ")
+	p, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	if len(p) == 0 {
+		return nil, fmt.Errorf("empty passphrase for the encrypted credentials file")
+	}
+	return p, nil
+}
+
+// pbkdf2SHA256 implements PBKDF2 (RFC 8018) with HMAC-SHA256 as the pseudorandom function
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	numBlocks := (keyLen + hashLen - 1) / hashLen
+
+	var blockIndex [4]byte
+	dk := make([]byte, 0, numBlocks*hashLen)
+	u := make([]byte, hashLen)
+	for block := 1; block <= numBlocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(blockIndex[:], uint32(block))
+		prf.Write(blockIndex[:])
+		dk = prf.Sum(dk)
+		t := dk[len(dk)-hashLen:]
+		copy(u, t)
+
+		for n := 2; n <= iterations; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for i := range u {
+				t[i] ^= u[i]
+			}
+		}
+	}
+	return dk[:keyLen]
+}
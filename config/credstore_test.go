@@ -0,0 +1,147 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPBKDF2SHA256(t *testing.T) {
+	// test vectors from RFC 7914, section 11
+	key := pbkdf2SHA256([]byte("passwd"), []byte("salt"), 1, 64)
+	assert.Equal(t, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc"+
+		"49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783", hex.EncodeToString(key))
+	key = pbkdf2SHA256([]byte("Password"), []byte("NaCl"), 80000, 64)
+	assert.Equal(t, "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56"+
+		"a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d", hex.EncodeToString(key))
+
+	// RFC 6070 inputs with HMAC-SHA256, exercising multiple iterations and output blocks
+	for _, v := range []struct {
+		password, salt string
+		iterations     int
+		expected       string
+	}{
+		{"password", "salt", 2, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+		{"password", "salt", 4096, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+		{"passwordPASSWORDpassword", "saltSALTsaltSALTsaltSALTsaltSALTsalt", 4096, "348c89dbcbd32b2f32d814b8116e84cf2b17347ebc1800181c4e2a1fb8dd53e1c635518c7dac47e9"},
+	} {
+		key := pbkdf2SHA256([]byte(v.password), []byte(v.salt), v.iterations, len(v.expected)/2)
+		assert.Equal(t, v.expected, hex.EncodeToString(key), "c=%d", v.iterations)
+	}
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	for command, expected := range map[string][]string{
+		"my-helper --vault fsoc":                      {"my-helper", "--vault", "fsoc"},
+		"  my-helper\t--vault  fsoc ":                 {"my-helper", "--vault", "fsoc"},
+		`"/opt/my tools/helper" --vault 'team vault'`: {"/opt/my tools/helper", "--vault", "team vault"},
+		`/opt/my\ tools/helper ''`:                    {"/opt/my tools/helper", ""},
+		`helper "say \"hi\" \n" 'a\b'`:                {"helper", `say "hi" \n`, `a\b`},
+	} {
+		args, err := SplitCommandLine(command)
+		assert.Nil(t, err, command)
+		assert.Equal(t, expected, args, command)
+	}
+	for _, command := range []string{"", "   ", `helper 'vault`, `helper "vault`, `helper \`} {
+		_, err := SplitCommandLine(command)
+		assert.NotNil(t, err, command)
+	}
+}
+
+func TestEncryptedFileStore(t *testing.T) {
+	t.Setenv(FSOC_CREDENTIALS_PASSPHRASE_ENVVAR, "correct horse battery staple")
+	path := filepath.Join(t.TempDir(), "fsoc.credentials")
+	store := &encryptedFileStore{path: path}
+
+	// empty store
+	creds, err := store.Get("default")
+	assert.Nil(t, err)
+	assert.Equal(t, Credentials{}, *creds)
+
+	// store, read back and check that the file doesn't contain the secrets in clear
+	assert.Nil(t, store.Store("default", &Credentials{Token: "access-token-1", RefreshToken: "refresh-token-1"}))
+	assert.Nil(t, store.Store("other", &Credentials{Token: "access-token-2"}))
+	creds, err = store.Get("default")
+	assert.Nil(t, err)
+	assert.Equal(t, "access-token-1", creds.Token)
+	assert.Equal(t, "refresh-token-1", creds.RefreshToken)
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), "access-token")
+
+	// erase
+	assert.Nil(t, store.Erase("default"))
+	creds, err = store.Get("default")
+	assert.Nil(t, err)
+	assert.Equal(t, "", creds.Token)
+	creds, err = store.Get("other")
+	assert.Nil(t, err)
+	assert.Equal(t, "access-token-2", creds.Token)
+
+	// wrong passphrase
+	encryptionKeys.Lock()
+	encryptionKeys.passphrase = []byte("wrong")
+	encryptionKeys.keys = map[string][]byte{}
+	encryptionKeys.Unlock()
+	defer func() {
+		encryptionKeys.Lock()
+		encryptionKeys.passphrase = nil
+		encryptionKeys.keys = map[string][]byte{}
+		encryptionKeys.Unlock()
+	}()
+	_, err = store.Get("other")
+	assert.NotNil(t, err)
+}
+
+func TestHelperStore(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "helper.sh")
+	helper := `#!/bin/sh
+store="` + dir + `/store"
+case "$1" in
+  get) cat "$store" 2>/dev/null || true ;;
+  store) grep -e '^token=' -e '^refresh_token=' > "$store" ;;
+  erase) rm -f "$store" ;;
+esac
+`
+	assert.Nil(t, os.WriteFile(script, []byte(helper), 0700))
+	store := &helperStore{command: "/bin/sh " + script}
+
+	creds, err := store.Get("default")
+	assert.Nil(t, err)
+	assert.Equal(t, Credentials{}, *creds)
+
+	assert.Nil(t, store.Store("default", &Credentials{Token: "tok", RefreshToken: "ref"}))
+	creds, err = store.Get("default")
+	assert.Nil(t, err)
+	assert.Equal(t, Credentials{Token: "tok", RefreshToken: "ref"}, *creds)
+
+	assert.Nil(t, store.Erase("default"))
+	creds, err = store.Get("default")
+	assert.Nil(t, err)
+	assert.Equal(t, Credentials{}, *creds)
+
+	// failing helper
+	store = &helperStore{command: "/bin/sh -c false"}
+	_, err = store.Get("default")
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "failed to get"))
+}
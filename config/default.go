@@ -25,6 +25,10 @@ func GetDefaultContextName() string {
 
 // SetDefaultContextName sets the default context name in the config file and updates the file
 func SetDefaultContextName(name string) error {
+	if getEphemeralContext(name) != nil {
+		return fmt.Errorf("cannot make profile %q the current profile, as it is defined by environment variables", name)
+	}
+
 	// look up selected context
 	contextExists := false
 	cfg := getConfig()
@@ -0,0 +1,227 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+// Ephemeral profile: when there is no config file (e.g., in CI), fsoc can use a profile defined
+// entirely by environment variables. The profile is kept in memory only; tokens obtained by logging
+// in are reused for the duration of the command but are never written to disk.
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	// EnvProfileName is the name of the ephemeral profile, unless FSOC_PROFILE is set
+	EnvProfileName = "env"
+
+	envVarPrefix          = "FSOC_"
+	envVarSubsystemPrefix = "FSOC_SUBSYSTEM_"
+	envVarHeaderPrefix    = "FSOC_HEADER_"
+)
+
+// nonEnvSettings are the profile settings that cannot be set with environment variables: the profile's
+// state, settings that apply only to profiles kept in a config file, deprecated settings and maps,
+// which have their own environment variables (see EnvVars)
+var nonEnvSettings = []string{"name", "parent", "user", "refresh_token", "token_expiry", "credential_store", "credential_helper", "server", "csv_file", "headers", "subsystems"}
+
+// EnvVar describes an environment variable that defines a setting of the ephemeral profile
+type EnvVar struct {
+	Name    string // environment variable, e.g., FSOC_URL
+	Setting string // path of the setting in the config file, e.g., url or retry.max-retries
+}
+
+// ephemeralContext is the profile defined by environment variables, if in use (see SetEphemeralContext)
+var ephemeralContext *Context
+
+// EnvVars returns the environment variables that define the ephemeral profile's settings, incl. the
+// settings of the subsystems that registered a config template. In addition, FSOC_HEADER_NAME sets
+// the default header NAME (with underscores replaced by dashes, e.g., FSOC_HEADER_X_TEAM for X-Team).
+func EnvVars() []EnvVar {
+	vars := []EnvVar{}
+	for _, setting := range settingPaths(reflect.TypeOf(Context{}), "") {
+		if top, _, _ := strings.Cut(setting, "."); slices.Contains(nonEnvSettings, top) {
+			continue
+		}
+		vars = append(vars, EnvVar{Name: envVarName(envVarPrefix, setting), Setting: setting})
+	}
+
+	subsystems := GetRegisteredSubsystems()
+	slices.Sort(subsystems)
+	for _, subsystem := range subsystems {
+		settings, err := GetSubsystemSettings(subsystem)
+		if err != nil {
+			continue
+		}
+		for _, setting := range settings {
+			vars = append(vars, EnvVar{
+				Name:    envVarName(envVarSubsystemPrefix+strings.ToUpper(subsystem)+"_", setting.Name),
+				Setting: "subsystems." + subsystem + "." + setting.Name,
+			})
+		}
+	}
+	return vars
+}
+
+// ContextFromEnv returns the ephemeral profile defined by environment variables, or nil if they don't
+// define one. At least one of FSOC_AUTH_METHOD and FSOC_URL must be set to define a profile.
+func ContextFromEnv() (*Context, error) {
+	if os.Getenv(envVarPrefix+"AUTH_METHOD") == "" && os.Getenv(envVarPrefix+"URL") == "" {
+		return nil, nil
+	}
+
+	// collect the settings
+	settings := map[string]any{}
+	subsystems := map[string]map[string]any{}
+	for _, v := range EnvVars() {
+		value := os.Getenv(v.Name)
+		if value == "" {
+			continue
+		}
+		if strings.HasPrefix(v.Setting, "subsystems.") {
+			segments := strings.SplitN(v.Setting, ".", 3)
+			converted, err := ValidateSubsystemSetting(segments[1], segments[2], value)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %w", v.Name, err)
+			}
+			if subsystems[segments[1]] == nil {
+				subsystems[segments[1]] = map[string]any{}
+			}
+			subsystems[segments[1]][segments[2]] = converted
+			continue
+		}
+		setSettingPath(settings, v.Setting, value)
+	}
+	knownVars := map[string]bool{}
+	for _, v := range EnvVars() {
+		knownVars[v.Name] = true
+	}
+	headers := map[string]string{}
+	for _, env := range os.Environ() {
+		name, value, _ := strings.Cut(env, "=")
+		if strings.HasPrefix(name, envVarHeaderPrefix) && value != "" {
+			header := http.CanonicalHeaderKey(strings.ReplaceAll(strings.TrimPrefix(name, envVarHeaderPrefix), "_", "-"))
+			if err := ValidateHeaderName(header); err != nil {
+				return nil, fmt.Errorf("%v: %w", name, err)
+			}
+			headers[header] = value
+		} else if strings.HasPrefix(name, envVarSubsystemPrefix) && !knownVars[name] {
+			log.Warnf("Ignoring environment variable %v, which doesn't match any subsystem setting", name)
+		}
+	}
+
+	// build the profile, converting the values to the settings' types
+	ctx := &Context{}
+	if err := mapstructure.WeakDecode(settings, ctx); err != nil {
+		return nil, fmt.Errorf("invalid profile settings in environment variables: %w", err)
+	}
+	ctx.Name = os.Getenv(FSOC_PROFILE_ENVVAR)
+	if ctx.Name == "" {
+		ctx.Name = EnvProfileName
+	}
+	ctx.SubsystemConfigs = subsystems
+	if len(headers) > 0 {
+		ctx.HeaderOptions.Global = headers
+	}
+	if ctx.AuthMethod == "" {
+		return nil, fmt.Errorf("%vAUTH_METHOD must be set to define a profile with environment variables", envVarPrefix)
+	}
+	return ctx, nil
+}
+
+// SetEphemeralContext makes the given profile the current one, instead of the config file's profiles.
+// The profile is kept in memory only: updates to it (e.g., tokens obtained by logging in) are not saved.
+// Use nil to revert to the config file's profiles.
+func SetEphemeralContext(ctx *Context) {
+	if ctx == nil {
+		ephemeralContext = nil
+		return
+	}
+	ephemeralContext = cloneContext(ctx)
+	activeProfile = ctx.Name
+}
+
+// IsEphemeral returns true if the current profile is defined by environment variables rather than by a config file
+func IsEphemeral() bool {
+	return ephemeralContext != nil
+}
+
+// getEphemeralContext returns a copy of the ephemeral profile if it is the named profile, nil otherwise
+func getEphemeralContext(name string) *Context {
+	if ephemeralContext == nil || ephemeralContext.Name != name {
+		return nil
+	}
+	return cloneContext(ephemeralContext)
+}
+
+// cloneContext returns a deep copy of the context, so that the copy's maps can be modified
+func cloneContext(ctx *Context) *Context {
+	settings, err := settingsOf(ctx)
+	if err == nil {
+		var clone *Context
+		if clone, err = contextFromSettings(settings); err == nil {
+			if clone.SubsystemConfigs == nil {
+				clone.SubsystemConfigs = map[string]map[string]any{}
+			}
+			return clone
+		}
+	}
+	log.Fatalf("(bug) failed to copy profile %q: %v", ctx.Name, err)
+	return nil
+}
+
+// settingPaths returns the paths of the leaf settings of a config structure, per their mapstructure names
+func settingPaths(typ reflect.Type, prefix string) []string {
+	paths := []string{}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			paths = append(paths, settingPaths(field.Type, prefix+name+".")...)
+		} else {
+			paths = append(paths, prefix+name)
+		}
+	}
+	return paths
+}
+
+// envVarName returns the environment variable for a setting path, e.g., FSOC_RETRY_MAX_RETRIES for retry.max-retries
+func envVarName(prefix string, setting string) string {
+	return prefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(setting))
+}
+
+// setSettingPath sets a value in a tree of settings, creating the intermediate maps as needed
+func setSettingPath(settings map[string]any, path string, value any) {
+	segments := strings.Split(path, ".")
+	for _, segment := range segments[:len(segments)-1] {
+		next, ok := settings[segment].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			settings[segment] = next
+		}
+		settings = next
+	}
+	settings[segments[len(segments)-1]] = value
+}
@@ -0,0 +1,135 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testSubsystemConfig struct {
+	ApiVersion string `mapstructure:"apiver"`
+	PageSize   int    `mapstructure:"page-size"`
+}
+
+func registerTestSubsystem(t *testing.T) {
+	subsystemConfigs["testsub"] = &testSubsystemConfig{}
+	t.Cleanup(func() { delete(subsystemConfigs, "testsub") })
+}
+
+func TestEnvVars(t *testing.T) {
+	registerTestSubsystem(t)
+	vars := map[string]string{}
+	for _, v := range EnvVars() {
+		vars[v.Name] = v.Setting
+	}
+	assert.Equal(t, "url", vars["FSOC_URL"])
+	assert.Equal(t, "auth_method", vars["FSOC_AUTH_METHOD"])
+	assert.Equal(t, "secret_file", vars["FSOC_SECRET_FILE"])
+	assert.Equal(t, "retry.max-retries", vars["FSOC_RETRY_MAX_RETRIES"])
+	assert.Equal(t, "transport.insecure-skip-verify", vars["FSOC_TRANSPORT_INSECURE_SKIP_VERIFY"])
+	assert.Equal(t, "subsystems.testsub.apiver", vars["FSOC_SUBSYSTEM_TESTSUB_APIVER"])
+	assert.Equal(t, "subsystems.testsub.page-size", vars["FSOC_SUBSYSTEM_TESTSUB_PAGE_SIZE"])
+	for _, name := range []string{"FSOC_NAME", "FSOC_REFRESH_TOKEN", "FSOC_CREDENTIAL_STORE", "FSOC_SERVER", "FSOC_HEADERS_GLOBAL"} {
+		assert.NotContains(t, vars, name)
+	}
+}
+
+func TestContextFromEnv(t *testing.T) {
+	registerTestSubsystem(t)
+	t.Setenv(FSOC_PROFILE_ENVVAR, "")
+
+	// no profile without the auth method or URL
+	t.Setenv("FSOC_TENANT", "t-123")
+	ctx, err := ContextFromEnv()
+	assert.Nil(t, err)
+	assert.Nil(t, ctx)
+
+	t.Setenv("FSOC_URL", "https://mytenant.observe.appdynamics.com")
+	_, err = ContextFromEnv()
+	assert.ErrorContains(t, err, "FSOC_AUTH_METHOD")
+
+	t.Setenv("FSOC_AUTH_METHOD", AuthMethodServicePrincipal)
+	t.Setenv("FSOC_RETRY_MAX_RETRIES", "0")
+	t.Setenv("FSOC_TRANSPORT_INSECURE_SKIP_VERIFY", "true")
+	t.Setenv("FSOC_SUBSYSTEM_TESTSUB_APIVER", "v2")
+	t.Setenv("FSOC_SUBSYSTEM_TESTSUB_PAGE_SIZE", "25")
+	t.Setenv("FSOC_HEADER_X_TEAM", "blue")
+	ctx, err = ContextFromEnv()
+	require.Nil(t, err)
+	assert.Equal(t, EnvProfileName, ctx.Name)
+	assert.Equal(t, AuthMethodServicePrincipal, ctx.AuthMethod)
+	assert.Equal(t, "https://mytenant.observe.appdynamics.com", ctx.URL)
+	assert.Equal(t, "t-123", ctx.Tenant)
+	require.NotNil(t, ctx.RetryOptions.MaxRetries)
+	assert.Equal(t, 0, *ctx.RetryOptions.MaxRetries)
+	assert.True(t, ctx.TransportOptions.InsecureSkipVerify)
+	assert.Equal(t, map[string]map[string]any{"testsub": {"apiver": "v2", "page-size": 25}}, ctx.SubsystemConfigs)
+	assert.Equal(t, map[string]string{"X-Team": "blue"}, ctx.HeaderOptions.Global)
+
+	t.Setenv("FSOC_SUBSYSTEM_TESTSUB_PAGE_SIZE", "all")
+	_, err = ContextFromEnv()
+	assert.ErrorContains(t, err, "FSOC_SUBSYSTEM_TESTSUB_PAGE_SIZE")
+	t.Setenv("FSOC_SUBSYSTEM_TESTSUB_PAGE_SIZE", "")
+
+	t.Setenv(FSOC_PROFILE_ENVVAR, "ci")
+	t.Setenv("FSOC_RETRY_MAX_RETRIES", "many")
+	_, err = ContextFromEnv()
+	assert.ErrorContains(t, err, "max-retries")
+	t.Setenv("FSOC_RETRY_MAX_RETRIES", "")
+	ctx, err = ContextFromEnv()
+	require.Nil(t, err)
+	assert.Equal(t, "ci", ctx.Name)
+
+	t.Setenv("FSOC_HEADER_AUTHORIZATION", "Basic abc")
+	_, err = ContextFromEnv()
+	assert.ErrorContains(t, err, "FSOC_HEADER_AUTHORIZATION")
+}
+
+func TestEphemeralContext(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	fileName := filepath.Join(t.TempDir(), "config.yaml")
+	viper.SetConfigFile(fileName)
+
+	SetEphemeralContext(&Context{Name: EnvProfileName, AuthMethod: AuthMethodOAuth, URL: "https://mytenant.observe.appdynamics.com"})
+	defer SetEphemeralContext(nil)
+	assert.True(t, IsEphemeral())
+	assert.Equal(t, EnvProfileName, GetCurrentProfileName())
+	assert.Equal(t, []string{EnvProfileName}, ListAllContexts())
+
+	// tokens are kept in memory only
+	ctx := GetCurrentContext()
+	require.NotNil(t, ctx)
+	ctx.Token = "access-token"
+	ctx.RefreshToken = "refresh-token"
+	require.Nil(t, UpsertContext(ctx))
+	_, err := os.Stat(fileName)
+	assert.True(t, os.IsNotExist(err))
+	reloaded, err := ReloadContext(EnvProfileName)
+	require.Nil(t, err)
+	assert.Equal(t, "access-token", reloaded.Token)
+	assert.Equal(t, "refresh-token", reloaded.RefreshToken)
+
+	// the profile cannot be managed as a config file's profile
+	assert.NotNil(t, DeleteContext(EnvProfileName))
+	assert.NotNil(t, SetDefaultContextName(EnvProfileName))
+	assert.Contains(t, SecretsLocation(ctx), "in memory")
+}
@@ -52,9 +52,18 @@ type ErrSubsystemParsingError struct {
 }
 
 func (e *ErrSubsystemParsingError) Error() string {
-	// convert potentially multiline error output to a single line, replacing '\n' with '|' for better logging
+	return fmt.Sprintf("failed to parse configuration for subsystem %q: %v", e.SubsystemName, parsingErrorText(e.ParsingError))
+}
+
+func (e *ErrSubsystemParsingError) Unwrap() error {
+	return e.ParsingError
+}
+
+// parsingErrorText converts potentially multiline mapstructure error output to a single line,
+// replacing '\n' with '|' for better logging
+func parsingErrorText(parsingError error) string {
 	errText := "(unknown)"
-	if me, ok := e.ParsingError.(*mapstructure.Error); ok {
+	if me, ok := parsingError.(*mapstructure.Error); ok {
 		errlist := me.WrappedErrors()
 		errTexts := []string{}
 		if len(errlist) > 0 {
@@ -64,29 +73,55 @@ func (e *ErrSubsystemParsingError) Error() string {
 			errText = strings.Join(errTexts, " | ")
 		}
 	} else { // note that not all mapstructure-returned errors need to be mapstructure.Error
-		errText = e.ParsingError.Error()
+		errText = parsingError.Error()
 	}
-
-	return fmt.Sprintf("failed to parse configuration for subsystem %q: %v", e.SubsystemName, errText)
-}
-
-func (e *ErrSubsystemParsingError) Unwrap() error {
-	return e.ParsingError
+	return errText
 }
 
 type ErrSubsystemNotFound struct {
 	SubsystemName string
+	Suggestions   []string // similarly named subsystems, if any
 }
 
 func (e *ErrSubsystemNotFound) Error() string {
-	return fmt.Sprintf("unknown subsystem %q", e.SubsystemName)
+	return fmt.Sprintf("unknown subsystem %q", e.SubsystemName) + didYouMean(e.Suggestions)
 }
 
 type ErrSubsystemSettingNotFound struct {
 	SubsystemName string
 	SettingName   string
+	Suggestions   []string // similarly named settings, if any
+	ValidSettings []string // all settings of the subsystem
 }
 
 func (e *ErrSubsystemSettingNotFound) Error() string {
-	return fmt.Sprintf("unknown setting %q for subsystem %q", e.SettingName, e.SubsystemName)
+	text := fmt.Sprintf("unknown setting %q for subsystem %q", e.SettingName, e.SubsystemName)
+	if len(e.Suggestions) == 0 && len(e.ValidSettings) > 0 {
+		return text + fmt.Sprintf(`; valid setting(s): "%v"`, strings.Join(e.ValidSettings, `", "`))
+	}
+	return text + didYouMean(e.Suggestions)
+}
+
+type ErrSubsystemSettingValue struct {
+	SubsystemName string
+	SettingName   string
+	Value         any
+	Err           error
+	Suggestions   []string // similar valid values, if any
+}
+
+func (e *ErrSubsystemSettingValue) Error() string {
+	return fmt.Sprintf("invalid value %q for setting %q of subsystem %q: %v", fmt.Sprint(e.Value), e.SettingName, e.SubsystemName, e.Err) + didYouMean(e.Suggestions)
+}
+
+func (e *ErrSubsystemSettingValue) Unwrap() error {
+	return e.Err
+}
+
+// didYouMean returns the suggestions to append to an error message, or an empty string if there are none
+func didYouMean(suggestions []string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(`; did you mean "%v"?`, strings.Join(suggestions, `" or "`))
 }
@@ -0,0 +1,122 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/exp/constraints"
+	"golang.org/x/exp/maps"
+)
+
+// headerNameRegexp matches the HTTP header names that can be configured as default headers: RFC 9110
+// tokens, except for '.', which separates the parts of the setting names (e.g., knowledge.header.NAME)
+var headerNameRegexp = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+^_`|~-]+$")
+
+// reservedHeaders are managed by fsoc and cannot be configured as default headers
+var reservedHeaders = []string{"Authorization", "Content-Type", "Content-Length", "Host"}
+
+// ValidateHeaderName checks that a header name can be configured as a default header
+func ValidateHeaderName(name string) error {
+	if !headerNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid header name %q", name)
+	}
+	if slices.Contains(reservedHeaders, http.CanonicalHeaderKey(name)) {
+		return fmt.Errorf("the %q header is managed by fsoc and cannot be set as a default header", http.CanonicalHeaderKey(name))
+	}
+	return nil
+}
+
+// SetHeader sets a default header for all requests (if subsystem is empty) or for the requests
+// made by the given subsystem only. An empty value deletes the header.
+func (o *HeaderOptions) SetHeader(subsystem string, name string, value string) error {
+	if err := ValidateHeaderName(name); err != nil {
+		return err
+	}
+
+	// find the headers to update, creating them if needed
+	headers := o.Global
+	if subsystem != "" {
+		headers = o.Subsystems[subsystem]
+	}
+	if headers == nil {
+		if value == "" {
+			return nil // nothing to delete
+		}
+		headers = map[string]string{}
+		if subsystem == "" {
+			o.Global = headers
+		} else {
+			if o.Subsystems == nil {
+				o.Subsystems = map[string]map[string]string{}
+			}
+			o.Subsystems[subsystem] = headers
+		}
+	}
+
+	// update (or delete) the header, replacing any existing header that differs only in case
+	for existing := range headers {
+		if strings.EqualFold(existing, name) {
+			delete(headers, existing)
+		}
+	}
+	if value != "" {
+		headers[name] = value
+	}
+
+	// drop empty maps, so they don't appear in the config file
+	if len(headers) == 0 {
+		if subsystem == "" {
+			o.Global = nil
+		} else {
+			delete(o.Subsystems, subsystem)
+		}
+	}
+	if len(o.Subsystems) == 0 {
+		o.Subsystems = nil
+	}
+	return nil
+}
+
+// Headers returns the default headers for a request made by the given subsystem (or by no particular
+// subsystem, if empty), keyed by canonical header name. Subsystem-specific headers take precedence
+// over global ones. References to environment variables in the values, in the form $VAR or ${VAR},
+// are expanded; headers whose values expand to empty strings are omitted.
+func (o *HeaderOptions) Headers(subsystem string) map[string]string {
+	headers := map[string]string{}
+	for _, source := range []map[string]string{o.Global, o.Subsystems[subsystem]} {
+		for name, value := range source {
+			headers[http.CanonicalHeaderKey(name)] = os.ExpandEnv(value)
+		}
+	}
+	for name, value := range headers {
+		if value == "" {
+			delete(headers, name)
+		}
+	}
+	return headers
+}
+
+// sortedKeys returns the keys of a map in ascending order
+func sortedKeys[K constraints.Ordered, V any](m map[K]V) []K {
+	keys := maps.Keys(m)
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,59 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetHeader(t *testing.T) {
+	o := HeaderOptions{}
+	assert.Nil(t, o.SetHeader("", "X-Team", "observability"))
+	assert.Nil(t, o.SetHeader("knowledge", "X-Api-Key", "${API_KEY}"))
+	assert.Nil(t, o.SetHeader("knowledge", "x-api-key", "$API_KEY")) // replaces the header regardless of case
+	assert.Equal(t, map[string]string{"X-Team": "observability"}, o.Global)
+	assert.Equal(t, map[string]map[string]string{"knowledge": {"x-api-key": "$API_KEY"}}, o.Subsystems)
+	assert.Equal(t, "header.X-Team=observability knowledge.header.x-api-key=$API_KEY", o.String())
+
+	// empty values delete headers, dropping empty maps
+	assert.Nil(t, o.SetHeader("knowledge", "X-Api-Key", ""))
+	assert.Nil(t, o.SetHeader("uql", "X-Other", ""))
+	assert.Nil(t, o.Subsystems)
+	assert.Nil(t, o.SetHeader("", "X-Team", ""))
+	assert.Nil(t, o.Global)
+	assert.Equal(t, "", o.String())
+
+	// invalid and reserved header names
+	assert.NotNil(t, o.SetHeader("", "X Team", "value"))
+	assert.NotNil(t, o.SetHeader("", "", "value"))
+	assert.NotNil(t, o.SetHeader("uql", "authorization", "Bearer x"))
+	assert.NotNil(t, o.SetHeader("", "Content-Type", "text/plain"))
+}
+
+func TestHeaders(t *testing.T) {
+	t.Setenv("FSOC_TEST_API_KEY", "secret")
+	o := HeaderOptions{
+		Global: map[string]string{"x-team": "observability", "X-Layer": "global", "X-Missing": "${FSOC_TEST_UNDEFINED}"},
+		Subsystems: map[string]map[string]string{
+			"knowledge": {"X-Api-Key": "${FSOC_TEST_API_KEY}", "x-layer": "knowledge"},
+		},
+	}
+	assert.Equal(t, map[string]string{"X-Team": "observability", "X-Layer": "global"}, o.Headers(""))
+	assert.Equal(t, map[string]string{"X-Team": "observability", "X-Layer": "global"}, o.Headers("uql"))
+	assert.Equal(t, map[string]string{"X-Team": "observability", "X-Layer": "knowledge", "X-Api-Key": "secret"}, o.Headers("knowledge"))
+	assert.Equal(t, map[string]string{}, (&HeaderOptions{}).Headers("knowledge"))
+}
@@ -0,0 +1,319 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+// Profile inheritance: a profile may name a parent profile, whose settings it inherits unless it
+// sets them itself. Settings are merged deeply, so a profile can override individual subsystem
+// settings, headers, transport options, etc., while inheriting the rest. The config file keeps
+// only the profile's own settings, i.e., those that differ from what it would inherit. As empty and
+// false values are omitted from the config file, the profile lists the inherited settings it sets to
+// such values as overrides.
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// nonInheritedSettings are the settings (by their name in the config file) that belong to
+// the profile itself and are never inherited
+var nonInheritedSettings = []string{"name", "parent", "overrides", "user", "token", "refresh_token", "token_expiry"}
+
+// ResolveContext returns the named profile with the settings it inherits from its parent profiles,
+// along with the name of the profile that provides each setting, keyed by the setting's path in
+// the config file (e.g., "url" or "subsystems.knowledge.apiver").
+func ResolveContext(name string) (*Context, map[string]string, error) {
+	settings, origins, err := resolveSettings(getConfig().Contexts, name, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, err := contextFromSettings(settings)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve profile %q: %w", name, err)
+	}
+	return ctx, origins, nil
+}
+
+// SetParent makes the profile inherit from the parent profile, returning the profile's new effective
+// settings: its own settings merged over those inherited from the parent. The profile's non-inherited
+// settings (e.g., tokens) are preserved. If parent is empty, the profile stops inheriting, keeping its
+// current effective settings as its own. The config file is not updated (see UpsertContext).
+func SetParent(ctx *Context, parent string) (*Context, error) {
+	if parent == "" {
+		standalone := *ctx
+		standalone.Parent = ""
+		standalone.Overrides = nil
+		return &standalone, nil
+	}
+
+	// start from the profile's own settings, as stored, if the profile exists (dropping any settings
+	// inherited from its current parent) and keep its current non-inherited settings
+	contexts := getConfig().Contexts
+	own := ctx
+	if stored := findContext(contexts, ctx.Name); stored != nil {
+		own = stored
+	}
+	settings, err := settingsOf(own)
+	if err != nil {
+		return nil, err
+	}
+	current, err := settingsOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, setting := range nonInheritedSettings {
+		if v, found := current[setting]; found {
+			settings[setting] = v
+		} else {
+			delete(settings, setting)
+		}
+	}
+	settings["parent"] = parent
+
+	// merge over the inherited settings, which also validates the parent
+	inherited, err := inheritedSettings(contexts, ctx.Name, parent)
+	if err != nil {
+		return nil, err
+	}
+	merged := mergeSettings(inherited, settings)
+	removeSettings(merged, ctx.Overrides)
+	resolved, err := contextFromSettings(merged)
+	if err != nil {
+		return nil, err
+	}
+	if resolved.SubsystemConfigs == nil {
+		resolved.SubsystemConfigs = map[string]map[string]any{}
+	}
+	return resolved, nil
+}
+
+// resolveSettings returns the effective settings of the named profile and their origins; chain
+// contains the names of the profiles that inherit from it, for detecting cycles
+func resolveSettings(contexts []Context, name string, chain []string) (map[string]any, map[string]string, error) {
+	chain = append(chain, name)
+	if slices.Index(chain, name) < len(chain)-1 {
+		return nil, nil, fmt.Errorf("profile inheritance cycle: %v", strings.Join(chain, " -> "))
+	}
+	ctx := findContext(contexts, name)
+	if ctx == nil {
+		if len(chain) > 1 {
+			return nil, nil, fmt.Errorf("parent %q of profile %q: %w", name, chain[len(chain)-2], ErrProfileNotFound)
+		}
+		return nil, nil, fmt.Errorf("%q: %w", name, ErrProfileNotFound)
+	}
+
+	own, err := settingsOf(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read profile %q: %w", name, err)
+	}
+	origins := map[string]string{}
+	setOrigins(origins, "", own, name)
+	for _, path := range ctx.Overrides {
+		origins[path] = name
+	}
+	if ctx.Parent == "" {
+		return own, origins, nil
+	}
+
+	// merge own settings over the inherited ones
+	inherited, inheritedOrigins, err := resolveSettings(contexts, ctx.Parent, chain)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, setting := range nonInheritedSettings {
+		delete(inherited, setting)
+	}
+	for path, origin := range inheritedOrigins {
+		setting, _, _ := strings.Cut(path, ".")
+		if _, overridden := origins[path]; !overridden && !slices.Contains(nonInheritedSettings, setting) {
+			origins[path] = origin
+		}
+	}
+	merged := mergeSettings(inherited, own)
+	removeSettings(merged, ctx.Overrides)
+	return merged, origins, nil
+}
+
+// inheritedSettings returns the settings a profile with the given parent inherits, failing if
+// the parent cannot be resolved (incl. if it would inherit from the profile, creating a cycle)
+func inheritedSettings(contexts []Context, name string, parent string) (map[string]any, error) {
+	settings, _, err := resolveSettings(contexts, parent, []string{name})
+	if err != nil {
+		return nil, err
+	}
+	for _, setting := range nonInheritedSettings {
+		delete(settings, setting)
+	}
+	return settings, nil
+}
+
+// ownSettings returns a copy of ctx without the settings that have the same values as the inherited ones,
+// listing the inherited settings that ctx sets to empty or false values as its overrides
+func ownSettings(ctx *Context, inherited map[string]any) (*Context, error) {
+	settings, err := settingsOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	overrides := overriddenSettings(settings, inherited, "")
+	removeInherited(settings, inherited)
+	own, err := contextFromSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+	own.Overrides = overrides
+	return own, nil
+}
+
+func findContext(contexts []Context, name string) *Context {
+	for i := range contexts {
+		if contexts[i].Name == name {
+			return &contexts[i]
+		}
+	}
+	return nil
+}
+
+// settingsOf returns the tree of the context's settings, as in the config file, omitting empty values
+func settingsOf(ctx *Context) (map[string]any, error) {
+	data, err := yaml.Marshal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	settings := map[string]any{}
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return nil, err
+	}
+	pruneEmpty(settings)
+	return settings, nil
+}
+
+func contextFromSettings(settings map[string]any) (*Context, error) {
+	data, err := yaml.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+	var ctx Context
+	if err := yaml.Unmarshal(data, &ctx); err != nil {
+		return nil, err
+	}
+	return &ctx, nil
+}
+
+// pruneEmpty removes empty strings and maps, which mean that the setting is not set
+func pruneEmpty(settings map[string]any) {
+	for k, v := range settings {
+		switch v := v.(type) {
+		case nil:
+			delete(settings, k)
+		case string:
+			if v == "" {
+				delete(settings, k)
+			}
+		case map[string]any:
+			pruneEmpty(v)
+			if len(v) == 0 {
+				delete(settings, k)
+			}
+		}
+	}
+}
+
+// mergeSettings returns a deep merge of the override settings over the base ones
+func mergeSettings(base map[string]any, override map[string]any) map[string]any {
+	merged := map[string]any{}
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		baseMap, baseIsMap := merged[k].(map[string]any)
+		overrideMap, overrideIsMap := v.(map[string]any)
+		if baseIsMap && overrideIsMap {
+			merged[k] = mergeSettings(baseMap, overrideMap)
+		} else {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// removeInherited removes the settings that have the same values as the inherited ones
+func removeInherited(settings map[string]any, inherited map[string]any) {
+	for k, v := range settings {
+		inheritedValue, found := inherited[k]
+		if !found {
+			continue
+		}
+		settingsMap, isMap := v.(map[string]any)
+		inheritedMap, inheritedIsMap := inheritedValue.(map[string]any)
+		if isMap && inheritedIsMap {
+			removeInherited(settingsMap, inheritedMap)
+			if len(settingsMap) == 0 {
+				delete(settings, k)
+			}
+		} else if reflect.DeepEqual(v, inheritedValue) {
+			delete(settings, k)
+		}
+	}
+}
+
+// overriddenSettings returns the paths of the inherited leaf settings that are missing from the settings,
+// i.e., that are set to empty or false values, which are omitted from the config file
+func overriddenSettings(settings map[string]any, inherited map[string]any, prefix string) []string {
+	var paths []string
+	for k, v := range inherited {
+		inheritedMap, inheritedIsMap := v.(map[string]any)
+		value, found := settings[k]
+		if !found && inheritedIsMap {
+			paths = append(paths, overriddenSettings(map[string]any{}, inheritedMap, prefix+k+".")...)
+		} else if !found {
+			paths = append(paths, prefix+k)
+		} else if settingsMap, isMap := value.(map[string]any); isMap && inheritedIsMap {
+			paths = append(paths, overriddenSettings(settingsMap, inheritedMap, prefix+k+".")...)
+		}
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// removeSettings removes the settings with the given paths (e.g., "transport.insecure-skip-verify"), so
+// that they have empty or false values
+func removeSettings(settings map[string]any, paths []string) {
+	for _, path := range paths {
+		m := settings
+		keys := strings.Split(path, ".")
+		for _, k := range keys[:len(keys)-1] {
+			if m, _ = m[k].(map[string]any); m == nil {
+				break
+			}
+		}
+		if m != nil {
+			delete(m, keys[len(keys)-1])
+		}
+	}
+}
+
+// setOrigins records the profile as the origin of each leaf setting
+func setOrigins(origins map[string]string, prefix string, settings map[string]any, profile string) {
+	for k, v := range settings {
+		if m, ok := v.(map[string]any); ok {
+			setOrigins(origins, prefix+k+".", m, profile)
+		} else {
+			origins[prefix+k] = profile
+		}
+	}
+}
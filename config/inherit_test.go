@@ -0,0 +1,231 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const inheritanceConfig = `
+schema_version: 2
+contexts:
+    - name: base
+      auth_method: service-principal
+      url: https://mytenant.saas.observer.com
+      secret_file: /tmp/creds.json
+      token: base-token
+      retry:
+        max-retries: 5
+      subsystems:
+        knowledge:
+            apiver: v2
+            other: base
+    - name: team
+      parent: base
+      tenant: "123"
+      subsystems:
+        knowledge:
+            other: team
+        uql:
+            apiver: v1
+    - name: dev
+      parent: team
+      auth_method: oauth
+      token: dev-token
+current_context: dev
+`
+
+func TestResolveContext(t *testing.T) {
+	defer viper.Reset()
+	setupConfigFile(t, inheritanceConfig)
+
+	ctx, err := GetContext("dev")
+	require.Nil(t, err)
+	assert.Equal(t, "team", ctx.Parent)
+	assert.Equal(t, "oauth", ctx.AuthMethod)
+	assert.Equal(t, "https://mytenant.saas.observer.com", ctx.URL)
+	assert.Equal(t, "123", ctx.Tenant)
+	assert.Equal(t, "dev-token", ctx.Token)
+	assert.Equal(t, 5, *ctx.RetryOptions.MaxRetries)
+	assert.Equal(t, map[string]map[string]any{
+		"knowledge": {"apiver": "v2", "other": "team"},
+		"uql":       {"apiver": "v1"},
+	}, ctx.SubsystemConfigs)
+
+	_, origins, err := ResolveContext("dev")
+	require.Nil(t, err)
+	assert.Equal(t, "dev", origins["auth_method"])
+	assert.Equal(t, "base", origins["url"])
+	assert.Equal(t, "team", origins["tenant"])
+	assert.Equal(t, "base", origins["retry.max-retries"])
+	assert.Equal(t, "base", origins["subsystems.knowledge.apiver"])
+	assert.Equal(t, "team", origins["subsystems.knowledge.other"])
+	assert.Equal(t, "dev", origins["token"])
+
+	// tokens are never inherited
+	team, err := GetContext("team")
+	require.Nil(t, err)
+	assert.Equal(t, "", team.Token)
+	assert.Equal(t, "service-principal", team.AuthMethod)
+}
+
+func TestInheritanceErrors(t *testing.T) {
+	defer viper.Reset()
+	setupConfigFile(t, `
+contexts:
+    - name: a
+      parent: b
+      url: https://a.saas.observer.com
+    - name: b
+      parent: a
+    - name: orphan
+      parent: missing
+      url: https://orphan.saas.observer.com
+`)
+
+	_, _, err := ResolveContext("a")
+	assert.ErrorContains(t, err, "cycle: a -> b -> a")
+	_, _, err = ResolveContext("orphan")
+	assert.ErrorIs(t, err, ErrProfileNotFound)
+
+	// the profile's own settings are still usable
+	ctx, err := GetContext("orphan")
+	require.Nil(t, err)
+	assert.Equal(t, "https://orphan.saas.observer.com", ctx.URL)
+
+	_, err = SetParent(&Context{Name: "c"}, "c")
+	assert.ErrorContains(t, err, "cycle: c -> c")
+	_, err = SetParent(&Context{Name: "b"}, "a")
+	assert.ErrorContains(t, err, "cycle")
+}
+
+func TestUpdateInheritingContext(t *testing.T) {
+	defer viper.Reset()
+	fileName := setupConfigFile(t, inheritanceConfig)
+
+	// only the settings that differ from the inherited ones are saved
+	ctx, err := GetContext("team")
+	require.Nil(t, err)
+	ctx.Tenant = "456"
+	ctx.SubsystemConfigs["knowledge"]["apiver"] = "v3"
+	ctx.Token = "team-token"
+	require.Nil(t, UpsertContext(ctx))
+
+	stored := findContext(getConfig().Contexts, "team")
+	require.NotNil(t, stored)
+	assert.Equal(t, "", stored.URL)
+	assert.Equal(t, "", stored.AuthMethod)
+	assert.Nil(t, stored.RetryOptions.MaxRetries)
+	assert.Equal(t, "456", stored.Tenant)
+	assert.Equal(t, "team-token", stored.Token)
+	assert.Equal(t, map[string]map[string]any{
+		"knowledge": {"apiver": "v3", "other": "team"},
+		"uql":       {"apiver": "v1"},
+	}, stored.SubsystemConfigs)
+
+	// changes to the parent are inherited
+	base, err := GetContext("base")
+	require.Nil(t, err)
+	base.URL = "https://other.saas.observer.com"
+	require.Nil(t, UpsertContext(base))
+	ctx, err = GetContext("dev")
+	require.Nil(t, err)
+	assert.Equal(t, "https://other.saas.observer.com", ctx.URL)
+	assert.Equal(t, "456", ctx.Tenant)
+
+	// parents cannot be deleted
+	assert.ErrorContains(t, DeleteContext("team"), `profile "dev" inherits from it`)
+
+	data, err := os.ReadFile(fileName)
+	require.Nil(t, err)
+	assert.Contains(t, string(data), "parent: team")
+}
+
+func TestOverrideInheritedSettings(t *testing.T) {
+	defer viper.Reset()
+	setupConfigFile(t, inheritanceConfig)
+	base, err := GetContext("base")
+	require.Nil(t, err)
+	base.TransportOptions.InsecureSkipVerify = true
+	require.Nil(t, UpsertContext(base))
+
+	// empty and false values override the inherited ones
+	ctx, err := GetContext("team")
+	require.Nil(t, err)
+	require.True(t, ctx.TransportOptions.InsecureSkipVerify)
+	ctx.TransportOptions.InsecureSkipVerify = false
+	ctx.SecretFile = ""
+	require.Nil(t, UpsertContext(ctx))
+	stored := findContext(getConfig().Contexts, "team")
+	require.NotNil(t, stored)
+	assert.Equal(t, []string{"secret_file", "transport.insecure-skip-verify"}, stored.Overrides)
+
+	ctx, origins, err := ResolveContext("dev")
+	require.Nil(t, err)
+	assert.False(t, ctx.TransportOptions.InsecureSkipVerify)
+	assert.Equal(t, "", ctx.SecretFile)
+	assert.Equal(t, "https://mytenant.saas.observer.com", ctx.URL)
+	assert.Equal(t, "team", origins["transport.insecure-skip-verify"])
+
+	// setting the value again removes the override
+	ctx, err = GetContext("team")
+	require.Nil(t, err)
+	ctx.TransportOptions.InsecureSkipVerify = true
+	require.Nil(t, UpsertContext(ctx))
+	stored = findContext(getConfig().Contexts, "team")
+	assert.Equal(t, []string{"secret_file"}, stored.Overrides)
+	assert.False(t, stored.TransportOptions.InsecureSkipVerify) // inherited
+	ctx, err = GetContext("dev")
+	require.Nil(t, err)
+	assert.True(t, ctx.TransportOptions.InsecureSkipVerify)
+}
+
+func TestSetParent(t *testing.T) {
+	defer viper.Reset()
+	setupConfigFile(t, inheritanceConfig)
+
+	// switching parents drops the settings inherited from the previous parent
+	dev, err := GetContext("dev")
+	require.Nil(t, err)
+	dev, err = SetParent(dev, "base")
+	require.Nil(t, err)
+	assert.Equal(t, "base", dev.Parent)
+	assert.Equal(t, "", dev.Tenant)
+	assert.Equal(t, "oauth", dev.AuthMethod)
+	assert.Equal(t, "dev-token", dev.Token)
+	assert.Equal(t, map[string]any{"apiver": "v2", "other": "base"}, dev.SubsystemConfigs["knowledge"])
+
+	// new profiles inherit everything but the tokens
+	ctx, err := SetParent(&Context{Name: "new", SubsystemConfigs: map[string]map[string]any{}}, "team")
+	require.Nil(t, err)
+	assert.Equal(t, "service-principal", ctx.AuthMethod)
+	assert.Equal(t, "123", ctx.Tenant)
+	assert.Equal(t, "", ctx.Token)
+
+	// removing the parent keeps the effective settings
+	team, err := GetContext("team")
+	require.Nil(t, err)
+	team, err = SetParent(team, "")
+	require.Nil(t, err)
+	require.Nil(t, UpsertContext(team))
+	stored := findContext(getConfig().Contexts, "team")
+	assert.Equal(t, "", stored.Parent)
+	assert.Equal(t, "https://mytenant.saas.observer.com", stored.URL)
+}
@@ -0,0 +1,176 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/spf13/viper"
+)
+
+const (
+	// lockPollInterval is how often a held profile lock is checked for release
+	lockPollInterval = 100 * time.Millisecond
+	// configLockTimeout is how long an update of the config file waits for other fsoc processes' updates
+	configLockTimeout = 10 * time.Second
+)
+
+var (
+	// lockRefreshInterval is how often the holder of a lock updates the lock file's modification time,
+	// so that the lock is not considered stale for as long as it is held (e.g., during a browser login)
+	lockRefreshInterval = 30 * time.Second
+	// staleLockAge is the time since a lock was last refreshed after which it is assumed to be left
+	// over from a crashed process
+	staleLockAge = 2 * time.Minute
+)
+
+// LockProfile acquires an advisory lock for the named profile, shared by all fsoc processes that use
+// the same config file, e.g., parallel CI jobs. It is used to serialize token refreshes, so that only
+// one process logs in while the others wait and then reuse the refreshed token. The lock is a file
+// next to the config file, which is refreshed periodically while the lock is held; locks that
+// haven't been refreshed for a couple of minutes are left over from crashed processes and are broken.
+// LockProfile waits up to the timeout, unless the Go context ends first, and returns a function that
+// releases the lock.
+func LockProfile(ctx context.Context, name string, timeout time.Duration) (func(), error) {
+	configFile := viper.ConfigFileUsed()
+	if configFile == "" || getEphemeralContext(name) != nil {
+		return func() {}, nil // no config file to share
+	}
+	return lockFile(ctx, configFile+"."+url.PathEscape(name)+".lock", timeout)
+}
+
+// lockConfigFile acquires an advisory lock for updating the config file, so that fsoc processes
+// updating it concurrently don't overwrite each other's changes. If the lock cannot be acquired,
+// a warning is logged and the update proceeds without it.
+func lockConfigFile() func() {
+	configFile := viper.ConfigFileUsed()
+	if configFile == "" {
+		return func() {}
+	}
+	unlock, err := lockFile(context.Background(), configFile+".lock", configLockTimeout)
+	if err != nil {
+		log.Warnf("Updating the config file without coordinating with other fsoc processes: %v", err)
+		return func() {}
+	}
+	return unlock
+}
+
+// lockFile creates the lock file, waiting up to the timeout while it exists (unless the Go context ends
+// first), and returns a function that removes it. The lock file is refreshed until it is removed (see
+// refreshLock). The lock file identifies its holder, so that a holder whose stale lock was broken
+// doesn't remove the lock of the next holder.
+func lockFile(ctx context.Context, lockFile string, timeout time.Duration) (func(), error) {
+	holder := lockHolderID()
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.OpenFile(lockFile, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_, _ = fmt.Fprintf(f, "%s\n", holder)
+			_ = f.Close()
+			done := make(chan struct{})
+			go refreshLock(lockFile, lockRefreshInterval, done)
+			var once sync.Once
+			return func() {
+				once.Do(func() {
+					close(done)
+					releaseLock(lockFile, holder)
+				})
+			}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock %q: %w", lockFile, err)
+		}
+
+		// break the lock if its holder appears to have died, i.e., it no longer refreshes the lock
+		if info, statErr := os.Stat(lockFile); statErr == nil && time.Since(info.ModTime()) > staleLockAge {
+			breakStaleLock(lockFile, holder)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock %q held by another fsoc process", lockFile)
+		}
+		select {
+		case <-time.After(lockPollInterval):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("stopped waiting for lock %q: %w", lockFile, ctx.Err())
+		}
+	}
+}
+
+// lockHolderID returns a unique ID for a lock holder: the process ID and a random suffix, since
+// a process may hold several locks
+func lockHolderID() string {
+	suffix := make([]byte, 8)
+	_, _ = rand.Read(suffix)
+	return fmt.Sprintf("%d-%v", os.Getpid(), hex.EncodeToString(suffix))
+}
+
+// breakStaleLock removes a stale lock atomically: it is first renamed to a name unique to the waiter
+// (holder), so that among waiters that found the lock stale, only one takes it over, and then checked
+// again, in case it was a fresh lock taken in the meantime, which is put back
+func breakStaleLock(lockFile string, holder string) {
+	broken := lockFile + "." + holder + ".stale"
+	if err := os.Rename(lockFile, broken); err != nil {
+		return // already broken by another waiter
+	}
+	info, err := os.Stat(broken)
+	if err == nil && time.Since(info.ModTime()) <= staleLockAge {
+		_ = os.Link(broken, lockFile) // fresh lock, put it back unless the lock has been taken again
+	} else {
+		log.Warnf("Breaking stale lock %q", lockFile)
+	}
+	_ = os.Remove(broken)
+}
+
+// releaseLock removes the lock file if it is still held by the holder, i.e., it wasn't broken as stale
+func releaseLock(lockFile string, holder string) {
+	data, err := os.ReadFile(lockFile)
+	if err == nil && !bytes.Equal(bytes.TrimSpace(data), []byte(holder)) {
+		log.Warnf("Lock %q was taken over by another fsoc process", lockFile)
+		return
+	}
+	if err := os.Remove(lockFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warnf("Failed to release lock %q: %v", lockFile, err)
+	}
+}
+
+// refreshLock updates the modification time of a held lock file every interval until done is
+// closed, so that other processes don't consider the lock stale while it is in use
+func refreshLock(lockFile string, interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			if err := os.Chtimes(lockFile, now, now); err != nil {
+				log.Warnf("Failed to refresh lock %q: %v", lockFile, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockFileRefresh(t *testing.T) {
+	savedRefresh, savedStale := lockRefreshInterval, staleLockAge
+	lockRefreshInterval, staleLockAge = 20*time.Millisecond, 200*time.Millisecond
+	defer func() { lockRefreshInterval, staleLockAge = savedRefresh, savedStale }()
+	path := filepath.Join(t.TempDir(), "profile.lock")
+
+	// a lock held for longer than the stale age is not broken while its holder refreshes it
+	unlock, err := lockFile(context.Background(), path, time.Second)
+	require.Nil(t, err)
+	time.Sleep(2 * staleLockAge)
+	_, err = lockFile(context.Background(), path, 100*time.Millisecond)
+	assert.ErrorContains(t, err, "timed out")
+
+	// once released, it can be acquired again
+	unlock()
+	unlock() // releasing again is harmless
+	unlock, err = lockFile(context.Background(), path, 100*time.Millisecond)
+	require.Nil(t, err)
+	unlock()
+
+	// a lock that isn't refreshed, e.g., left over from a crashed process, is broken
+	require.Nil(t, os.WriteFile(path, []byte("12345\n"), 0600))
+	old := time.Now().Add(-2 * staleLockAge)
+	require.Nil(t, os.Chtimes(path, old, old))
+	unlock, err = lockFile(context.Background(), path, 100*time.Millisecond)
+	require.Nil(t, err)
+	unlock()
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestLockFileCancel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profile.lock")
+	unlock, err := lockFile(context.Background(), path, time.Second)
+	require.Nil(t, err)
+	defer unlock()
+
+	// waiting for the lock ends with the Go context, well before the timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = lockFile(ctx, path, time.Minute)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
+
+func TestLockFileBreakStaleConcurrently(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profile.lock")
+	require.Nil(t, os.WriteFile(path, []byte("12345\n"), 0600))
+	old := time.Now().Add(-2 * staleLockAge)
+	require.Nil(t, os.Chtimes(path, old, old))
+
+	// waiters that find the same stale lock don't end up holding the lock together
+	var holders, maxHolders atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock, err := lockFile(context.Background(), path, 10*time.Second)
+			if !assert.Nil(t, err) {
+				return
+			}
+			if n := holders.Add(1); n > 1 {
+				maxHolders.Store(n)
+			} else {
+				maxHolders.CompareAndSwap(0, 1)
+			}
+			time.Sleep(10 * time.Millisecond)
+			holders.Add(-1)
+			unlock()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), maxHolders.Load())
+	_, err := os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	leftovers, _ := filepath.Glob(path + ".*")
+	assert.Empty(t, leftovers)
+}
@@ -15,10 +15,12 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	"github.com/apex/log"
+	"github.com/spf13/viper"
 )
 
 // ListAllContexts returns a list of all context names
@@ -30,6 +32,9 @@ func ListAllContexts() []string {
 // used for the command line autocompletion
 func ListContexts(prefix string) []string {
 	config := getConfig()
+	if ephemeralContext != nil {
+		config.Contexts = append(config.Contexts, *ephemeralContext)
+	}
 	var ret []string
 	for _, c := range config.Contexts {
 		name := c.Name
@@ -48,11 +53,47 @@ func ListContexts(prefix string) []string {
 func GetCurrentContext() *Context {
 	profileName := GetCurrentProfileName()
 	c := getContext(profileName)
+	if c != nil {
+		loadSecrets(c)
+	}
 	return c
 }
 
-// GetContext
+type currentContextKey struct{}
+
+// WithCurrentContext returns a copy of the Go context that carries the profile, so that CurrentContext
+// returns it instead of the profile selected for the fsoc invocation, e.g., when a command is run for
+// each of multiple profiles
+func WithCurrentContext(ctx context.Context, profile *Context) context.Context {
+	return context.WithValue(ctx, currentContextKey{}, profile)
+}
+
+// CurrentContext returns the profile carried by the Go context (see WithCurrentContext), if any,
+// or else the current profile (see GetCurrentContext). The Go context may be nil.
+func CurrentContext(ctx context.Context) *Context {
+	if ctx != nil {
+		if profile, ok := ctx.Value(currentContextKey{}).(*Context); ok {
+			return profile
+		}
+	}
+	return GetCurrentContext()
+}
+
+// GetContext returns the named context, including its secrets (see GetStoredContext)
 func GetContext(name string) (*Context, error) {
+	ctx, err := GetStoredContext(name)
+	if err != nil {
+		return nil, err
+	}
+	loadSecrets(ctx)
+
+	return ctx, nil
+}
+
+// GetStoredContext returns the named context as stored in the config file, without retrieving
+// the secrets kept in the profile's credential store (unless they are stored inline).
+// It is intended for displaying profiles without accessing their credential stores.
+func GetStoredContext(name string) (*Context, error) {
 	ctx := getContext(name)
 	if ctx == nil {
 		return nil, fmt.Errorf("%q: %w", name, ErrProfileNotFound)
@@ -61,6 +102,49 @@ func GetContext(name string) (*Context, error) {
 	return ctx, nil
 }
 
+// ReloadContext re-reads the named context from the config file, including its secrets, in order
+// to pick up changes made by other fsoc processes since the file was loaded, e.g., tokens refreshed by
+// parallel jobs sharing the same config file. The in-memory configuration is updated to match.
+func ReloadContext(name string) (*Context, error) {
+	if ctx := getEphemeralContext(name); ctx != nil {
+		return ctx, nil // not shared with other processes
+	}
+	if err := syncWithConfigFile(); err != nil {
+		return nil, err
+	}
+
+	ctx, err := GetStoredContext(name)
+	if err != nil {
+		return nil, err
+	}
+	forgetSecrets(ctx)
+	loadSecrets(ctx)
+	return ctx, nil
+}
+
+// syncWithConfigFile re-reads the contexts from the config file, replacing the in-memory ones
+func syncWithConfigFile() error {
+	configFile := viper.ConfigFileUsed()
+	if configFile == "" {
+		return nil
+	}
+	v := viper.New()
+	v.SetConfigFile(configFile)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read config file %q: %w", configFile, err)
+	}
+	var c configFileContents
+	if err := v.Unmarshal(&c); err != nil {
+		return fmt.Errorf("failed to parse config file %q: %w", configFile, err)
+	}
+	viperMutex.Lock()
+	defer viperMutex.Unlock()
+	viper.Set("contexts", c.Contexts)
+	viper.Set(schemaVersionKey, c.SchemaVersion)
+	return nil
+}
+
 // UpsertContext updates or adds a context and updates the file
 // The context pointer may or may not have been returned by GetContext()/GetCurrentContext()
 func UpsertContext(ctx *Context) error {
@@ -68,9 +152,33 @@ func UpsertContext(ctx *Context) error {
 	return nil
 }
 
+// ClearTokens removes the access and refresh tokens from the named profile, incl. from its
+// credential store, and updates the config file. It returns the context as it was before the
+// tokens were cleared, so that the caller can report (or revoke) what was cleared.
+func ClearTokens(name string) (*Context, error) {
+	ctx, err := GetContext(name)
+	if err != nil {
+		return nil, err
+	}
+	previous := *ctx
+	if ctx.Token == "" && ctx.RefreshToken == "" {
+		return &previous, nil // nothing to clear
+	}
+
+	ctx.Token = ""
+	ctx.RefreshToken = ""
+	ctx.TokenExpiry = ""
+	updateContext(ctx)
+	return &previous, nil
+}
+
 // DeleteContext deletes specified profile and updates the config file
 // If the deleted context is the default one, xxx
 func DeleteContext(name string) error {
+	if getEphemeralContext(name) != nil {
+		return fmt.Errorf("cannot delete profile %q, as it is defined by environment variables", name)
+	}
+
 	// find profile
 	cfg := getConfig()
 	profileIdx := -1
@@ -84,6 +192,22 @@ func DeleteContext(name string) error {
 		return fmt.Errorf("%q: %w", name, ErrProfileNotFound)
 	}
 
+	// refuse to delete a profile that others inherit from
+	for _, c := range cfg.Contexts {
+		if c.Parent == name {
+			return fmt.Errorf("cannot delete profile %q, as profile %q inherits from it; change or remove the parent of %q first", name, c.Name, c.Name)
+		}
+	}
+
+	// Delete the profile's secrets from its credential store, if any
+	if deleted := cfg.Contexts[profileIdx]; !isInlineStore(&deleted) {
+		if store, err := newCredentialStore(&deleted); err == nil {
+			if err := eraseSecrets(store, name); err != nil {
+				log.Warnf("Failed to remove secrets for profile %q from %v: %v", name, store.Location(), err)
+			}
+		}
+	}
+
 	// Delete context from config
 	newContexts := append(cfg.Contexts[:profileIdx], cfg.Contexts[profileIdx+1:]...)
 	update := map[string]interface{}{"contexts": newContexts}
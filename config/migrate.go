@@ -0,0 +1,237 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/apex/log"
+	"github.com/spf13/viper"
+)
+
+// CurrentSchemaVersion is the version of the config file schema used by this version of fsoc.
+// Config files without a schema version are version 0. When the schema changes in a way that
+// requires existing config files to be updated, add a migration to the migrations list and
+// increment the version.
+const CurrentSchemaVersion = 2
+
+const schemaVersionKey = "schema_version"
+
+// migration upgrades the contents of a config file from the previous schema version to its version
+type migration struct {
+	version     int
+	description string
+	apply       func(c *configFileContents) []string // returns descriptions of the changes made
+}
+
+// migrations are applied in order to config files with older schema versions
+var migrations = []migration{
+	{1, `replace the deprecated "server" setting with "url"`, migrateServerToURL},
+	{2, `replace the deprecated "csv_file" setting with "secret_file"`, migrateCsvFileToSecretFile},
+}
+
+func migrateServerToURL(c *configFileContents) []string {
+	changes := []string{}
+	for i := range c.Contexts {
+		ctx := &c.Contexts[i]
+		if ctx.Server == "" {
+			continue
+		}
+		ctx.URL = "https://" + ctx.Server
+		changes = append(changes, fmt.Sprintf("profile %q: server %q replaced with url %q", ctx.Name, ctx.Server, ctx.URL))
+		ctx.Server = ""
+	}
+	return changes
+}
+
+func migrateCsvFileToSecretFile(c *configFileContents) []string {
+	changes := []string{}
+	for i := range c.Contexts {
+		ctx := &c.Contexts[i]
+		if ctx.CsvFile == "" {
+			continue
+		}
+		if ctx.SecretFile == "" {
+			ctx.SecretFile = ctx.CsvFile
+			changes = append(changes, fmt.Sprintf("profile %q: csv_file %q replaced with secret_file", ctx.Name, ctx.CsvFile))
+		} else {
+			changes = append(changes, fmt.Sprintf("profile %q: csv_file %q removed, as secret_file %q is set", ctx.Name, ctx.CsvFile, ctx.SecretFile))
+		}
+		ctx.CsvFile = ""
+	}
+	return changes
+}
+
+// MigrationResult describes a migration of the config file's schema and the changes it makes
+type MigrationResult struct {
+	Version     int      // schema version the migration upgrades to
+	Description string   // what the migration does
+	Changes     []string // changes made to the config file's contents, empty if none were needed
+}
+
+// MigrationReport describes the upgrade of a config file to the current schema version
+type MigrationReport struct {
+	File        string            // path of the config file
+	FromVersion int               // schema version of the file before the upgrade
+	ToVersion   int               // schema version of the file after the upgrade
+	Migrations  []MigrationResult // migrations applied (or pending, for a dry run), in order
+	Backup      string            // path of the backup of the file, if the file was rewritten and had contents
+}
+
+// ErrNewerSchema indicates a config file written by a newer version of fsoc
+type ErrNewerSchema struct {
+	File    string
+	Version int
+}
+
+func (e *ErrNewerSchema) Error() string {
+	return fmt.Sprintf("config file %q has schema version %d, which is newer than the version supported by this version of fsoc (%d); please upgrade fsoc", e.File, e.Version, CurrentSchemaVersion)
+}
+
+// migrateContents applies the migrations needed to bring the contents to the current schema version
+func migrateContents(c *configFileContents) []MigrationResult {
+	results := []MigrationResult{}
+	for _, m := range migrations {
+		if m.version <= c.SchemaVersion {
+			continue
+		}
+		results = append(results, MigrationResult{
+			Version:     m.version,
+			Description: m.description,
+			Changes:     m.apply(c),
+		})
+		c.SchemaVersion = m.version
+	}
+	return results
+}
+
+// newerSchemaWarned avoids repeating the warning about a config file from a newer fsoc version
+var newerSchemaWarned bool
+
+// autoUpgrade enables rewriting the config file when its contents are upgraded on read, see SetAutoUpgrade
+var autoUpgrade = true
+
+// SetAutoUpgrade enables or disables the automatic upgrade of the config file when it is read. If disabled,
+// the contents are upgraded in memory only and the file is upgraded when it is next updated (e.g., to
+// examine the pending migrations with MigrateConfigFile).
+func SetAutoUpgrade(enabled bool) {
+	autoUpgrade = enabled
+}
+
+// checkSchemaVersion upgrades the contents read from the config file to the current schema version,
+// rewriting the file (after backing it up) if the upgrade changed the contents. Config files from newer
+// versions of fsoc are used as is, with a warning, but are never updated.
+func checkSchemaVersion(c *configFileContents) {
+	if c.SchemaVersion > CurrentSchemaVersion {
+		if !newerSchemaWarned {
+			log.Warnf("%v; the config file will not be updated", &ErrNewerSchema{viper.ConfigFileUsed(), c.SchemaVersion})
+			newerSchemaWarned = true
+		}
+		return
+	}
+
+	changed := false
+	for _, result := range migrateContents(c) {
+		for _, change := range result.Changes {
+			log.WithField("schema_version", result.Version).Infof("Upgrading config: %v", change)
+			changed = true
+		}
+	}
+	if changed && autoUpgrade {
+		updateConfigFile(map[string]interface{}{"contexts": c.Contexts})
+		log.Warnf("Config file updated to upgrade settings schema to version %d.", CurrentSchemaVersion)
+	}
+}
+
+// upgradeConfigFile prepares the config file's update, refusing to downgrade a file from a newer
+// version of fsoc and backing up a file from an older version before it is rewritten with the
+// current schema version. It returns the path of the backup, if one was made.
+func upgradeConfigFile(keyValues map[string]interface{}) (string, error) {
+	viperMutex.RLock()
+	defer viperMutex.RUnlock()
+	version := viper.GetInt(schemaVersionKey)
+	if version > CurrentSchemaVersion {
+		return "", &ErrNewerSchema{viper.ConfigFileUsed(), version}
+	}
+	keyValues[schemaVersionKey] = CurrentSchemaVersion
+	if version == CurrentSchemaVersion {
+		return "", nil
+	}
+
+	// ensure that the profiles are written in their upgraded form, even if not otherwise updated
+	if _, found := keyValues["contexts"]; !found {
+		var c configFileContents
+		if err := viper.Unmarshal(&c); err != nil {
+			return "", fmt.Errorf("unable to read config: %w", err)
+		}
+		migrateContents(&c)
+		keyValues["contexts"] = c.Contexts
+	}
+
+	// back up the file, keeping the oldest backup of the same schema version if it already exists
+	file := viper.ConfigFileUsed()
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && len(data) == 0) {
+		return "", nil // a new config file, nothing to back up
+	} else if err != nil {
+		return "", fmt.Errorf("failed to back up config file %q before upgrading it: %w", file, err)
+	}
+	backup := fmt.Sprintf("%v.v%d.bak", file, version)
+	if _, err := os.Stat(backup); err == nil {
+		return backup, nil
+	}
+	if err := os.WriteFile(backup, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to back up config file %q before upgrading it: %w", file, err)
+	}
+	log.WithFields(log.Fields{"backup": backup, "from_version": version, "to_version": CurrentSchemaVersion}).Warn("Upgrading the config file's schema; saved a backup of the file")
+	return backup, nil
+}
+
+// MigrateConfigFile upgrades the config file to the current schema version, backing it up first.
+// With dryRun, it only reports the pending migrations and the changes they would make.
+func MigrateConfigFile(dryRun bool) (*MigrationReport, error) {
+	unlock := lockConfigFile()
+	defer unlock()
+	if err := syncWithConfigFile(); err != nil {
+		return nil, err
+	}
+
+	// determine the pending migrations on a copy of the file's contents
+	var c configFileContents
+	if err := viper.Unmarshal(&c); err != nil {
+		return nil, fmt.Errorf("unable to read config: %w", err)
+	}
+	report := &MigrationReport{File: viper.ConfigFileUsed(), FromVersion: c.SchemaVersion}
+	if c.SchemaVersion > CurrentSchemaVersion {
+		return nil, &ErrNewerSchema{report.File, c.SchemaVersion}
+	}
+	report.Migrations = migrateContents(&c)
+	report.ToVersion = c.SchemaVersion
+	if dryRun || report.FromVersion == report.ToVersion {
+		return report, nil
+	}
+
+	// rewrite the file with the upgraded contents
+	keyValues := map[string]interface{}{"contexts": c.Contexts}
+	backup, err := upgradeConfigFile(keyValues)
+	if err != nil {
+		return nil, err
+	}
+	report.Backup = backup
+	writeConfigFile(keyValues)
+	return report, nil
+}
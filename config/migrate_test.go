@@ -0,0 +1,154 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const legacyConfig = `
+contexts:
+    - name: default
+      auth_method: service-principal
+      server: mytenant.saas.observer.com
+      csv_file: /tmp/creds.csv
+    - name: other
+      auth_method: none
+      url: https://other.saas.observer.com
+current_context: default
+`
+
+func setupConfigFile(t *testing.T, contents string) string {
+	viper.Reset() // drop values set by other tests
+	fileName := t.TempDir() + "/config.yaml"
+	viper.SetConfigFile(fileName)
+	viper.SetConfigType("yaml")
+	require.Nil(t, os.WriteFile(fileName, []byte(contents), 0600))
+	require.Nil(t, viper.ReadInConfig())
+	return fileName
+}
+
+func TestMigrationsOrder(t *testing.T) {
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.version)
+	}
+	assert.Equal(t, CurrentSchemaVersion, migrations[len(migrations)-1].version)
+}
+
+func TestMigrateConfigFileDryRun(t *testing.T) {
+	defer viper.Reset()
+	fileName := setupConfigFile(t, legacyConfig)
+	SetAutoUpgrade(false)
+	defer SetAutoUpgrade(true)
+
+	// reading the file upgrades the contents in memory only
+	ctx, err := GetContext("default")
+	require.Nil(t, err)
+	assert.Equal(t, "https://mytenant.saas.observer.com", ctx.URL)
+	assert.Equal(t, "/tmp/creds.csv", ctx.SecretFile)
+
+	report, err := MigrateConfigFile(true)
+	require.Nil(t, err)
+	assert.Equal(t, 0, report.FromVersion)
+	assert.Equal(t, CurrentSchemaVersion, report.ToVersion)
+	assert.Equal(t, "", report.Backup)
+	require.Equal(t, 2, len(report.Migrations))
+	assert.Equal(t, []string{`profile "default": server "mytenant.saas.observer.com" replaced with url "https://mytenant.saas.observer.com"`}, report.Migrations[0].Changes)
+	assert.Equal(t, []string{`profile "default": csv_file "/tmp/creds.csv" replaced with secret_file`}, report.Migrations[1].Changes)
+
+	data, err := os.ReadFile(fileName)
+	require.Nil(t, err)
+	assert.Equal(t, legacyConfig, string(data)) // not modified
+	_, err = os.Stat(fileName + ".v0.bak")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestMigrateConfigFile(t *testing.T) {
+	defer viper.Reset()
+	fileName := setupConfigFile(t, legacyConfig)
+	SetAutoUpgrade(false)
+	defer SetAutoUpgrade(true)
+
+	report, err := MigrateConfigFile(false)
+	require.Nil(t, err)
+	assert.Equal(t, fileName+".v0.bak", report.Backup)
+	backup, err := os.ReadFile(report.Backup)
+	require.Nil(t, err)
+	assert.Equal(t, legacyConfig, string(backup))
+
+	// the file is rewritten with the current schema version
+	v := viper.New()
+	v.SetConfigFile(fileName)
+	require.Nil(t, v.ReadInConfig())
+	var c configFileContents
+	require.Nil(t, v.Unmarshal(&c))
+	assert.Equal(t, CurrentSchemaVersion, c.SchemaVersion)
+	assert.Equal(t, "", c.Contexts[0].Server)
+	assert.Equal(t, "", c.Contexts[0].CsvFile)
+	assert.Equal(t, "/tmp/creds.csv", c.Contexts[0].SecretFile)
+	assert.Equal(t, "https://other.saas.observer.com", c.Contexts[1].URL)
+	assert.Equal(t, "default", c.CurrentContext)
+
+	// nothing left to migrate
+	report, err = MigrateConfigFile(true)
+	require.Nil(t, err)
+	assert.Equal(t, CurrentSchemaVersion, report.FromVersion)
+	assert.Equal(t, 0, len(report.Migrations))
+}
+
+func TestUpdateStampsSchemaVersion(t *testing.T) {
+	defer viper.Reset()
+	fileName := setupConfigFile(t, `
+contexts:
+    - name: default
+      auth_method: none
+      url: https://mytenant.saas.observer.com
+`)
+
+	// no migration changes, so the file is upgraded (and backed up) only when updated
+	assert.Nil(t, SetDefaultContextName("default"))
+	assert.Equal(t, CurrentSchemaVersion, viper.GetInt(schemaVersionKey))
+	_, err := os.Stat(fileName + ".v0.bak")
+	assert.Nil(t, err)
+}
+
+func TestNewerSchema(t *testing.T) {
+	defer viper.Reset()
+	fileName := setupConfigFile(t, `
+schema_version: 999
+contexts:
+    - name: default
+      auth_method: none
+      url: https://mytenant.saas.observer.com
+      future_setting: true
+`)
+
+	// usable, but never rewritten
+	ctx, err := GetContext("default")
+	require.Nil(t, err)
+	assert.Equal(t, "https://mytenant.saas.observer.com", ctx.URL)
+	_, err = upgradeConfigFile(map[string]interface{}{})
+	assert.ErrorAs(t, err, new(*ErrNewerSchema))
+	_, err = MigrateConfigFile(false)
+	assert.ErrorAs(t, err, new(*ErrNewerSchema))
+	data, err := os.ReadFile(fileName)
+	require.Nil(t, err)
+	assert.Contains(t, string(data), "future_setting")
+}
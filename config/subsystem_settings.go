@@ -0,0 +1,226 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// StringEnumer is implemented by subsystem setting types that accept only a fixed set of
+// string values (e.g., an API version). The values are used to suggest corrections for invalid
+// values and to complete the setting's values in the shell.
+type StringEnumer interface {
+	ValidValues() []string
+}
+
+// SubsystemSetting describes a subsystem-specific setting, as introspected from the subsystem's config template
+type SubsystemSetting struct {
+	Name   string       // setting name, from the field's mapstructure tag
+	Help   string       // description, from the field's fsoc-help tag
+	Type   reflect.Type // type of the field
+	Values []string     // valid values, for types that implement StringEnumer (nil otherwise)
+}
+
+// GetSubsystemSettings returns the settings of a subsystem that has registered a config template,
+// in the order of the template's fields
+func GetSubsystemSettings(subsystemName string) ([]SubsystemSetting, error) {
+	template, err := GetSubsytemConfigTemplate(subsystemName)
+	if err != nil {
+		return nil, err
+	}
+	typ := reflect.TypeOf(template)
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, nil
+	}
+
+	enumer := reflect.TypeOf((*StringEnumer)(nil)).Elem()
+	settings := []SubsystemSetting{}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		setting := SubsystemSetting{Name: name, Help: field.Tag.Get("fsoc-help"), Type: field.Type}
+
+		// get the valid values of enumerated types, whether the field is a value or a pointer
+		valueType := field.Type
+		if valueType.Kind() == reflect.Pointer {
+			valueType = valueType.Elem()
+		}
+		if reflect.PointerTo(valueType).Implements(enumer) {
+			setting.Values = reflect.New(valueType).Interface().(StringEnumer).ValidValues()
+		}
+		settings = append(settings, setting)
+	}
+	return settings, nil
+}
+
+// ValidateSubsystemSetting checks that a setting exists for the subsystem and that the value
+// can be parsed into it, in the same way as when the subsystem's config is parsed. It returns the
+// value to store in the profile: string values are converted for settings of basic types (e.g.,
+// "10" for an int setting). Unknown settings and invalid values of enumerated types are reported
+// with suggestions.
+func ValidateSubsystemSetting(subsystemName string, settingName string, value any) (any, error) {
+	settings, err := GetSubsystemSettings(subsystemName)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(settings))
+	for i, s := range settings {
+		names[i] = s.Name
+	}
+	index := slices.Index(names, settingName)
+	if index < 0 {
+		return nil, &ErrSubsystemSettingNotFound{
+			SubsystemName: subsystemName,
+			SettingName:   settingName,
+			Suggestions:   suggestNames(settingName, names),
+			ValidSettings: names,
+		}
+	}
+	setting := settings[index]
+	valueErr := func(err error) error {
+		return &ErrSubsystemSettingValue{SubsystemName: subsystemName, SettingName: settingName, Value: value, Err: err, Suggestions: suggestValues(value, setting.Values, err)}
+	}
+
+	// convert the value to the setting's type and check it by parsing it into a new config structure
+	converted, err := convertSettingValue(setting.Type, value)
+	if err != nil {
+		return nil, valueErr(err)
+	}
+	config := reflect.New(reflect.TypeOf(subsystemConfigs[subsystemName]).Elem()).Interface()
+	if err := newSubsystemDecoder(config).Decode(map[string]any{settingName: converted}); err != nil {
+		text := strings.TrimPrefix(parsingErrorText(err), "error decoding '"+settingName+"': ") // the setting is already named in the error
+		return nil, valueErr(errors.New(text))
+	}
+	return converted, nil
+}
+
+// suggestValues returns the valid values similar to an invalid string value, unless the validation error
+// already lists them (e.g., `valid value(s): "v1"`)
+func suggestValues(value any, values []string, err error) []string {
+	s, ok := value.(string)
+	if !ok {
+		return nil
+	}
+	suggestions := suggestNames(s, values)
+	if slices.IndexFunc(suggestions, func(v string) bool { return !strings.Contains(err.Error(), strconv.Quote(v)) }) < 0 {
+		return nil
+	}
+	return suggestions
+}
+
+// subsystemNotFound returns the error for an unknown subsystem, suggesting similarly named subsystems
+func subsystemNotFound(subsystemName string) error {
+	return &ErrSubsystemNotFound{
+		SubsystemName: subsystemName,
+		Suggestions:   suggestNames(subsystemName, GetRegisteredSubsystems()),
+	}
+}
+
+// convertSettingValue converts a string value to the setting's type if the type is a basic type
+// (bool, integer or floating point number). Values of other types, incl. named types, are left
+// to the decode hooks.
+func convertSettingValue(typ reflect.Type, value any) (any, error) {
+	s, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.PkgPath() != "" {
+		return value, nil
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, errors.New("must be true or false")
+		}
+		return b, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, typ.Bits())
+		if err != nil {
+			return nil, errors.New("must be an integer")
+		}
+		return int(n), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, typ.Bits())
+		if err != nil {
+			return nil, errors.New("must be a non-negative integer")
+		}
+		return uint(n), nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, typ.Bits())
+		if err != nil {
+			return nil, errors.New("must be a number")
+		}
+		return f, nil
+	}
+	return value, nil
+}
+
+// suggestNames returns the candidates that are similar to the name: those that are within a
+// small edit distance of it or that start with it (case-insensitively)
+func suggestNames(name string, candidates []string) []string {
+	if name == "" {
+		return nil
+	}
+	suggestions := []string{}
+	lowerName := strings.ToLower(name)
+	for _, candidate := range candidates {
+		if candidate == name {
+			continue // exact matches are not suggestions (e.g., a valid value rejected for another reason)
+		}
+		lowerCandidate := strings.ToLower(candidate)
+		if editDistance(lowerName, lowerCandidate) <= 2 || strings.HasPrefix(lowerCandidate, lowerName) {
+			suggestions = append(suggestions, candidate)
+		}
+	}
+	slices.Sort(suggestions)
+	return suggestions
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
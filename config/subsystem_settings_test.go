@@ -0,0 +1,118 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testMode string
+
+var testModes = []string{"fast", "safe"}
+
+func (m *testMode) ValidateAndSet(v any) error {
+	s, ok := v.(string)
+	if !ok || !slices.Contains(testModes, s) {
+		return fmt.Errorf("mode %v is not supported", v)
+	}
+	*m = testMode(s)
+	return nil
+}
+
+func (m *testMode) ValidValues() []string {
+	return testModes
+}
+
+type testSettingsConfig struct {
+	Mode     *testMode `mapstructure:"mode,omitempty" fsoc-help:"Processing mode"`
+	Verbose  bool      `mapstructure:"verbose"`
+	PageSize int       `mapstructure:"page-size"`
+	Label    string    `mapstructure:"label"`
+}
+
+func TestGetSubsystemSettings(t *testing.T) {
+	subsystemConfigs["settingsub"] = &testSettingsConfig{}
+	defer delete(subsystemConfigs, "settingsub")
+
+	settings, err := GetSubsystemSettings("settingsub")
+	require.Nil(t, err)
+	require.Equal(t, 4, len(settings))
+	assert.Equal(t, "mode", settings[0].Name)
+	assert.Equal(t, "Processing mode", settings[0].Help)
+	assert.Equal(t, testModes, settings[0].Values)
+	assert.Equal(t, "verbose", settings[1].Name)
+	assert.Nil(t, settings[1].Values)
+
+	_, err = GetSubsystemSettings("settingsubb")
+	var notFound *ErrSubsystemNotFound
+	require.True(t, errors.As(err, &notFound))
+	assert.Equal(t, []string{"settingsub"}, notFound.Suggestions)
+}
+
+func TestValidateSubsystemSetting(t *testing.T) {
+	subsystemConfigs["settingsub"] = &testSettingsConfig{}
+	defer delete(subsystemConfigs, "settingsub")
+
+	// valid values, converted to the settings' types
+	for setting, expected := range map[string]struct {
+		value     string
+		converted any
+	}{
+		"mode":      {"safe", "safe"},
+		"verbose":   {"true", true},
+		"page-size": {"50", 50},
+		"label":     {"42", "42"},
+	} {
+		value, err := ValidateSubsystemSetting("settingsub", setting, expected.value)
+		assert.Nil(t, err, setting)
+		assert.Equal(t, expected.converted, value, setting)
+	}
+
+	// unknown settings, with or without suggestions
+	_, err := ValidateSubsystemSetting("settingsub", "pagesize", "50")
+	assert.EqualError(t, err, `unknown setting "pagesize" for subsystem "settingsub"; did you mean "page-size"?`)
+	_, err = ValidateSubsystemSetting("settingsub", "color", "red")
+	assert.EqualError(t, err, `unknown setting "color" for subsystem "settingsub"; valid setting(s): "mode", "verbose", "page-size", "label"`)
+
+	// invalid values
+	_, err = ValidateSubsystemSetting("settingsub", "mode", "fsat")
+	assert.EqualError(t, err, `invalid value "fsat" for setting "mode" of subsystem "settingsub": mode fsat is not supported; did you mean "fast"?`)
+	_, err = ValidateSubsystemSetting("settingsub", "page-size", "many")
+	var invalid *ErrSubsystemSettingValue
+	require.True(t, errors.As(err, &invalid))
+	assert.Equal(t, "page-size", invalid.SettingName)
+	assert.ErrorContains(t, err, "must be an integer")
+	_, err = ValidateSubsystemSetting("settingsub", "verbose", "maybe")
+	assert.ErrorContains(t, err, "must be true or false")
+
+	// no suggestions if the error already lists the valid values
+	assert.Equal(t, []string{"fast"}, suggestValues("fsat", testModes, errors.New("mode fsat is not supported")))
+	assert.Nil(t, suggestValues("fsat", testModes, errors.New(`mode fsat is not supported; valid value(s): "fast", "safe"`)))
+	assert.Nil(t, suggestValues(42, testModes, errors.New("not a string")))
+
+	// the settings are checked before they are added to the profile
+	ctx := &Context{}
+	assert.NotNil(t, SetSubsystemSetting(ctx, "settingsub", "mode", "slow"))
+	assert.Nil(t, ctx.SubsystemConfigs["settingsub"])
+	require.Nil(t, SetSubsystemSetting(ctx, "settingsub", "page-size", "25"))
+	assert.Equal(t, map[string]any{"page-size": 25}, ctx.SubsystemConfigs["settingsub"])
+	assert.Nil(t, UpdateSubsystemConfigs(ctx))
+}
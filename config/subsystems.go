@@ -58,7 +58,7 @@ func GetRegisteredSubsystems() []string {
 func GetSubsytemConfigTemplate(subsystemName string) (any, error) {
 	tmpl, ok := subsystemConfigs[subsystemName]
 	if !ok {
-		return nil, &ErrSubsystemNotFound{subsystemName}
+		return nil, subsystemNotFound(subsystemName)
 	}
 	return tmpl, nil
 }
@@ -71,14 +71,19 @@ func SetSubsystemSetting(ctx *Context, subsystemName string, settingName string,
 	// fail if the subsystem doesn't exist or has not registered a config template
 	_, ok := subsystemConfigs[subsystemName]
 	if !ok {
-		return &ErrSubsystemNotFound{subsystemName}
+		return subsystemNotFound(subsystemName)
 	}
 
-	// TODO: add a check for the setting name, so that unknown settings can be caught here rather than
-	// on parse. Doing this will require some additional work to extract the mapstructure names of the
-	// fields to check against
+	// check the setting name and value, so that typos are caught here rather than when the config is parsed
+	value, err := ValidateSubsystemSetting(subsystemName, settingName, value)
+	if err != nil {
+		return err
+	}
 
 	// add value to the context (without parsing or validation, as the structure may not be final)
+	if ctx.SubsystemConfigs == nil {
+		ctx.SubsystemConfigs = map[string]map[string]any{}
+	}
 	ssmap, ok := ctx.SubsystemConfigs[subsystemName]
 	if !ok {
 		ssmap = map[string]any{settingName: value}
@@ -96,7 +101,7 @@ func DeleteSubsystemSetting(ctx *Context, subsystemName string, settingName stri
 	// fail if the subsystem doesn't exist or has not registered a config template
 	_, ok := subsystemConfigs[subsystemName]
 	if !ok {
-		return &ErrSubsystemNotFound{subsystemName}
+		return subsystemNotFound(subsystemName)
 	}
 
 	// remove value (and ignore if it doesn't exist)
@@ -126,23 +131,13 @@ func UpdateSubsystemConfigs(ctx *Context) error {
 	for name, config := range ctx.SubsystemConfigs {
 		configStruct, ok := subsystemConfigs[name]
 		if !ok {
-			err := fmt.Errorf("found configuration for %w", &ErrSubsystemNotFound{name})
+			err := fmt.Errorf("found configuration for %w", &ErrSubsystemNotFound{SubsystemName: name})
 			errlist = append(errlist, err)
 			continue
 		}
 
 		// create a decoder with the desired options & decode
-		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
-			// DecodeHook: mapstructure.ComposeDecodeHookFunc([]mapstructure.DecodeHookFunc{}...),
-			DecodeHook:  mapstructure.ComposeDecodeHookFunc(decodeHooks...),
-			ErrorUnused: true,         // no extra settings that are not recognized by the subsystem; this is mostly to avoid typos
-			ZeroFields:  true,         // on re-parsing/re-loading, ensure that any maps start from empty (although we currently support only atomic types)
-			Result:      configStruct, // target which will be used for introspection and result storage
-		})
-		if err != nil {
-			log.Fatalf("(bug) failed to create mapstrucure decoder: %v", err) // nb: likely not subsystem-specific, so no need to print name
-		}
-		parseErr := decoder.Decode(config)
+		parseErr := newSubsystemDecoder(configStruct).Decode(config)
 		if parseErr != nil {
 			err := &ErrSubsystemParsingError{name, parseErr}
 			errlist = append(errlist, err)
@@ -165,6 +160,21 @@ func UpdateSubsystemConfigs(ctx *Context) error {
 	return nil
 }
 
+// newSubsystemDecoder creates a decoder of subsystem-specific settings into the result, a subsystem's config structure
+func newSubsystemDecoder(result any) *mapstructure.Decoder {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		// DecodeHook: mapstructure.ComposeDecodeHookFunc([]mapstructure.DecodeHookFunc{}...),
+		DecodeHook:  mapstructure.ComposeDecodeHookFunc(decodeHooks...),
+		ErrorUnused: true,   // no extra settings that are not recognized by the subsystem; this is mostly to avoid typos
+		ZeroFields:  true,   // on re-parsing/re-loading, ensure that any maps start from empty (although we currently support only atomic types)
+		Result:      result, // target which will be used for introspection and result storage
+	})
+	if err != nil {
+		log.Fatalf("(bug) failed to create mapstrucure decoder: %v", err) // nb: likely not subsystem-specific, so no need to print name
+	}
+	return decoder
+}
+
 // RegisterTypeDecodeHook registers a mapstructure type decode hook for subsystem-
 // specific configuration types, primarily to enforce formats and parse directly
 // into types that are convenient for the subsystems to use.
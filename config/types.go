@@ -16,6 +16,7 @@ package config
 
 import (
 	"fmt"
+	"strings"
 )
 
 const (
@@ -38,7 +39,7 @@ const (
 	AuthMethodServicePrincipal = "service-principal"
 	// Use an agent principal
 	AuthMethodAgentPrincipal = "agent-principal"
-	// Use Session Manager (experimental)
+	// Use Session Manager (not supported yet, see api.ErrSessionManagerNotSupported)
 	AuthMethodSessionManager = "session-manager"
 	// Use for local setup
 	AuthMethodLocal = "local"
@@ -46,25 +47,41 @@ const (
 
 const (
 	AnnotationForConfigBypass = "config/bypass-check"
+	// commands with this annotation don't upgrade the config file's schema when reading it
+	AnnotationForSchemaUpgradeBypass = "config/bypass-schema-upgrade"
+	// commands with this annotation can be run for multiple profiles (see the --profiles flag); they must
+	// use the profile from their Go context (see CurrentContext) and pass the context to platform API calls
+	AnnotationForMultipleProfiles = "config/multiple-profiles"
 )
 
 // Struct Context defines a full configuration context (aka access profile). The Name
 // field contains the name of the context (which is unique within the config file);
 // the remaining fields define the access profile.
 type Context struct {
-	Name             string                    `json:"name" yaml:"name" mapstructure:"name"`
-	AuthMethod       string                    `json:"auth_method" yaml:"auth_method" mapstructure:"auth_method"`
-	Server           string                    `json:"server,omitempty" yaml:"server,omitempty" mapstructure:"server,omitempty"` // deprecated
-	URL              string                    `json:"url" yaml:"url" mapstructure:"url"`
-	Tenant           string                    `json:"tenant,omitempty" yaml:"tenant,omitempty" mapstructure:"tenant,omitempty"`
-	User             string                    `json:"user,omitempty" yaml:"user,omitempty" mapstructure:"user,omitempty"`
-	Token            string                    `json:"token,omitempty" yaml:"token,omitempty" mapstructure:"token,omitempty"` // access token
-	RefreshToken     string                    `json:"refresh_token,omitempty" yaml:"refresh_token,omitempty" mapstructure:"refresh_token,omitempty"`
-	CsvFile          string                    `json:"csv_file,omitempty" yaml:"csv_file,omitempty" mapstructure:"csv_file,omitempty"`
-	SecretFile       string                    `json:"secret_file,omitempty" yaml:"secret_file,omitempty" mapstructure:"secret_file,omitempty"`
-	EnvType          string                    `json:"env_type,omitempty" yaml:"env_type,omitempty" mapstructure:"env_type,omitempty"`
-	LocalAuthOptions LocalAuthOptions          `json:"auth-options,omitempty" yaml:"auth-options,omitempty" mapstructure:"auth-options,omitempty"`
-	SubsystemConfigs map[string]map[string]any `json:"subsystems,omitempty" yaml:"subsystems,omitempty" mapstructure:"subsystems,omitempty"`
+	Name              string                    `json:"name" yaml:"name" mapstructure:"name"`
+	Parent            string                    `json:"parent,omitempty" yaml:"parent,omitempty" mapstructure:"parent,omitempty"`          // profile to inherit settings from, see inherit.go
+	Overrides         []string                  `json:"overrides,omitempty" yaml:"overrides,omitempty" mapstructure:"overrides,omitempty"` // inherited settings (by path) that the profile sets to empty or false values
+	AuthMethod        string                    `json:"auth_method" yaml:"auth_method" mapstructure:"auth_method"`
+	Server            string                    `json:"server,omitempty" yaml:"server,omitempty" mapstructure:"server,omitempty"` // deprecated
+	URL               string                    `json:"url" yaml:"url" mapstructure:"url"`
+	Tenant            string                    `json:"tenant,omitempty" yaml:"tenant,omitempty" mapstructure:"tenant,omitempty"`
+	User              string                    `json:"user,omitempty" yaml:"user,omitempty" mapstructure:"user,omitempty"`
+	Token             string                    `json:"token,omitempty" yaml:"token,omitempty" mapstructure:"token,omitempty"` // access token
+	RefreshToken      string                    `json:"refresh_token,omitempty" yaml:"refresh_token,omitempty" mapstructure:"refresh_token,omitempty"`
+	TokenExpiry       string                    `json:"token_expiry,omitempty" yaml:"token_expiry,omitempty" mapstructure:"token_expiry,omitempty"` // RFC 3339 time when Token expires, as reported by the token endpoint
+	CsvFile           string                    `json:"csv_file,omitempty" yaml:"csv_file,omitempty" mapstructure:"csv_file,omitempty"`
+	SecretFile        string                    `json:"secret_file,omitempty" yaml:"secret_file,omitempty" mapstructure:"secret_file,omitempty"`
+	EnvType           string                    `json:"env_type,omitempty" yaml:"env_type,omitempty" mapstructure:"env_type,omitempty"`
+	LocalAuthOptions  LocalAuthOptions          `json:"auth-options,omitempty" yaml:"auth-options,omitempty" mapstructure:"auth-options,omitempty"`
+	RetryOptions      RetryOptions              `json:"retry,omitempty" yaml:"retry,omitempty" mapstructure:"retry,omitempty"`
+	TransportOptions  TransportOptions          `json:"transport,omitempty" yaml:"transport,omitempty" mapstructure:"transport,omitempty"`
+	TokenRefreshSkew  string                    `json:"token_refresh_skew,omitempty" yaml:"token_refresh_skew,omitempty" mapstructure:"token_refresh_skew,omitempty"`    // Go duration string, e.g., "1m"
+	CredentialStore   string                    `json:"credential_store,omitempty" yaml:"credential_store,omitempty" mapstructure:"credential_store,omitempty"`          // where Token and RefreshToken are kept, see credstore.go
+	CredentialHelper  string                    `json:"credential_helper,omitempty" yaml:"credential_helper,omitempty" mapstructure:"credential_helper,omitempty"`       // command for the "helper" credential store
+	SessionManagerURL string                    `json:"session_manager_url,omitempty" yaml:"session_manager_url,omitempty" mapstructure:"session_manager_url,omitempty"` // token endpoint of the session manager (session-manager auth only)
+	TracingOptions    TracingOptions            `json:"tracing,omitempty" yaml:"tracing,omitempty" mapstructure:"tracing,omitempty"`
+	HeaderOptions     HeaderOptions             `json:"headers,omitempty" yaml:"headers,omitempty" mapstructure:"headers,omitempty"`
+	SubsystemConfigs  map[string]map[string]any `json:"subsystems,omitempty" yaml:"subsystems,omitempty" mapstructure:"subsystems,omitempty"`
 	// Note: when adding fields, remember to add display for them in get.go
 }
 
@@ -81,7 +98,104 @@ func (o *LocalAuthOptions) String() string {
 	return fmt.Sprintf("appd-pty=%v appd-pid=%v appd-tid=%v", o.AppdPty, o.AppdPid, o.AppdTid)
 }
 
+// RetryOptions defines how platform API calls are retried on throttling and transient failures.
+// Zero values mean that the built-in defaults are used.
+type RetryOptions struct {
+	MaxRetries *int   `json:"max-retries,omitempty" yaml:"max-retries,omitempty" mapstructure:"max-retries,omitempty"` // nil for default, 0 to disable retries
+	MaxDelay   string `json:"max-delay,omitempty" yaml:"max-delay,omitempty" mapstructure:"max-delay,omitempty"`       // Go duration string, e.g., "30s"
+}
+
+func (o *RetryOptions) String() string {
+	if o.MaxRetries == nil && o.MaxDelay == "" {
+		return ""
+	}
+	s := []string{}
+	if o.MaxRetries != nil {
+		s = append(s, fmt.Sprintf("retries=%v", *o.MaxRetries))
+	}
+	if o.MaxDelay != "" {
+		s = append(s, fmt.Sprintf("retry-max-delay=%v", o.MaxDelay))
+	}
+	return strings.Join(s, " ")
+}
+
+// TransportOptions defines how fsoc connects to the platform: via a proxy, trusting a custom
+// CA, presenting a client certificate (mTLS) and/or skipping server certificate verification.
+// Zero values mean that the system defaults are used (incl. the proxy environment variables).
+type TransportOptions struct {
+	Proxy              string `json:"proxy,omitempty" yaml:"proxy,omitempty" mapstructure:"proxy,omitempty"`                                              // proxy URL, e.g., http://proxy.corp:8080
+	CACertFile         string `json:"ca-cert-file,omitempty" yaml:"ca-cert-file,omitempty" mapstructure:"ca-cert-file,omitempty"`                         // PEM file with additional CA certificates to trust
+	ClientCertFile     string `json:"client-cert-file,omitempty" yaml:"client-cert-file,omitempty" mapstructure:"client-cert-file,omitempty"`             // PEM file with the client certificate
+	ClientKeyFile      string `json:"client-key-file,omitempty" yaml:"client-key-file,omitempty" mapstructure:"client-key-file,omitempty"`                // PEM file with the client certificate's private key
+	InsecureSkipVerify bool   `json:"insecure-skip-verify,omitempty" yaml:"insecure-skip-verify,omitempty" mapstructure:"insecure-skip-verify,omitempty"` // for local development stacks only
+}
+
+func (o *TransportOptions) String() string {
+	s := []string{}
+	if o.Proxy != "" {
+		s = append(s, fmt.Sprintf("proxy=%v", o.Proxy))
+	}
+	if o.CACertFile != "" {
+		s = append(s, fmt.Sprintf("ca-cert=%v", o.CACertFile))
+	}
+	if o.ClientCertFile != "" {
+		s = append(s, fmt.Sprintf("client-cert=%v", o.ClientCertFile))
+	}
+	if o.ClientKeyFile != "" {
+		s = append(s, fmt.Sprintf("client-key=%v", o.ClientKeyFile))
+	}
+	if o.InsecureSkipVerify {
+		s = append(s, "insecure=true")
+	}
+	return strings.Join(s, " ")
+}
+
+// TracingOptions defines where fsoc exports the traces of its own operations (commands, platform API
+// calls, logins, etc.), for troubleshooting fsoc itself. Tracing is disabled if Exporter is empty.
+type TracingOptions struct {
+	Exporter string `json:"exporter,omitempty" yaml:"exporter,omitempty" mapstructure:"exporter,omitempty"` // "otlp" or "file"
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty" mapstructure:"endpoint,omitempty"` // OTLP/HTTP endpoint, e.g., http://localhost:4318
+	File     string `json:"file,omitempty" yaml:"file,omitempty" mapstructure:"file,omitempty"`             // file to append the spans to, in the OTLP JSON format
+}
+
+func (o *TracingOptions) String() string {
+	if o.Exporter == "" {
+		return ""
+	}
+	s := []string{fmt.Sprintf("exporter=%v", o.Exporter)}
+	if o.Endpoint != "" {
+		s = append(s, fmt.Sprintf("endpoint=%v", o.Endpoint))
+	}
+	if o.File != "" {
+		s = append(s, fmt.Sprintf("file=%v", o.File))
+	}
+	return strings.Join(s, " ")
+}
+
+// HeaderOptions defines default headers that are added to the profile's platform API requests, either
+// to all requests or only to the requests made by a given subsystem (e.g., knowledge or uql).
+// Header values may reference environment variables, see Headers.
+type HeaderOptions struct {
+	Global     map[string]string            `json:"global,omitempty" yaml:"global,omitempty" mapstructure:"global,omitempty"`             // header name -> value
+	Subsystems map[string]map[string]string `json:"subsystems,omitempty" yaml:"subsystems,omitempty" mapstructure:"subsystems,omitempty"` // subsystem name -> header name -> value
+}
+
+func (o *HeaderOptions) String() string {
+	s := []string{}
+	for _, name := range sortedKeys(o.Global) {
+		s = append(s, fmt.Sprintf("header.%v=%v", name, o.Global[name]))
+	}
+	for _, subsystem := range sortedKeys(o.Subsystems) {
+		headers := o.Subsystems[subsystem]
+		for _, name := range sortedKeys(headers) {
+			s = append(s, fmt.Sprintf("%v.header.%v=%v", subsystem, name, headers[name]))
+		}
+	}
+	return strings.Join(s, " ")
+}
+
 type configFileContents struct {
+	SchemaVersion  int `mapstructure:"schema_version" yaml:"schema_version,omitempty" json:"schema_version,omitempty"` // see CurrentSchemaVersion
 	Contexts       []Context
 	CurrentContext string `mapstructure:"current_context" yaml:"current_context,omitempty" json:"current_context,omitempty"`
 }
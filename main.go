@@ -29,13 +29,14 @@ func main() {
 }
 
 func realMain() int {
-	ctx := context.Background()
+	// cancel the context on interrupt, so that platform API calls in progress are aborted cleanly
+	ctx, stop := cmd.InterruptContext(context.Background())
+	defer stop()
 
 	log.SetHandler(cli.New(os.Stderr))
 
 	if err := cmd.Execute(ctx); err != nil {
-		log.WithFields(log.Fields{"error": err}).Error("command failed")
-		return 1
+		return cmd.ReportError(err)
 	}
 	return 0
 }
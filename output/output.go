@@ -116,6 +116,48 @@ func PrintJson(cmd *cobra.Command, v any) error {
 	return WriteJson(v, GetOutWriter(cmd))
 }
 
+// WriteNDJSON writes each item of a collection (or the object itself, if it is not a
+// collection) as a single line of compact JSON, see https://github.com/ndjson/ndjson-spec
+func WriteNDJSON(obj any, w io.Writer) error {
+	// convert to generic data, so the collection's items can be extracted
+	tmp, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	var data any
+	if err := json.Unmarshal(tmp, &data); err != nil {
+		return err
+	}
+
+	for _, item := range collectionItems(data) {
+		line, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		line = append(line, '\n')
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PrintNDJSON displays the output as newline-delimited JSON, one item per line
+func PrintNDJSON(cmd *cobra.Command, v any) error {
+	return WriteNDJSON(v, GetOutWriter(cmd))
+}
+
+// IsStreamingFormat returns true if the user-selected output format can display collection
+// items progressively, as they are retrieved (i.e., ndjson). Commands that fetch large collections
+// can use it to print each page with PrintCmdOutput as soon as the page arrives
+func IsStreamingFormat(cmd *cobra.Command) bool {
+	if cmd == nil {
+		return false
+	}
+	format, _ := cmd.Flags().GetString("output")
+	return format == "ndjson"
+}
+
 // PrintYaml displays the output in YAML
 func PrintYaml(cmd *cobra.Command, v any) error {
 	data, err := yaml.Marshal(v)
@@ -203,6 +245,11 @@ func printCmdOutputCustom(pr printRequest, v any, table *Table) {
 		v = transformFields(v, pr.fields)
 	}
 
+	// when run for one of multiple profiles, capture the output for combining it instead
+	if capture := captureOf(pr.cmd); capture != nil && capture.add(pr, v, table) {
+		return
+	}
+
 	// print according to format and presence of table
 	switch pr.format {
 	case "json":
@@ -215,6 +262,11 @@ func printCmdOutputCustom(pr printRequest, v any, table *Table) {
 			log.Fatalf("Failed to convert output to YAML: %v (%+v)", err, v)
 		}
 		return
+	case "ndjson":
+		if err := PrintNDJSON(pr.cmd, v); err != nil {
+			log.Fatalf("Failed to convert output to NDJSON: %v (%+v)", err, v)
+		}
+		return
 	}
 
 	// display simple values
@@ -223,6 +275,25 @@ func printCmdOutputCustom(pr printRequest, v any, table *Table) {
 		return
 	}
 
+	// prepare the table and display it
+	table, err := humanTable(pr, v, table)
+	if err != nil {
+		log.Warnf("Failed to convert output data to a table: %v; reverting to YAML output", err)
+		if err := PrintYaml(pr.cmd, v); err != nil {
+			log.Fatalf("Failed to convert output to YAML: %v (%+v)", err, v)
+		}
+		return
+	}
+	if table.Detail || pr.format == "detail" {
+		printDetail(pr.cmd, table)
+	} else {
+		printTable(pr.cmd, table)
+	}
+}
+
+// humanTable prepares the table for displaying the value in a human format, using the
+// table's line builder, if provided, or creating the table from the fields specification
+func humanTable(pr printRequest, v any, table *Table) (*Table, error) {
 	// prepare lines if builder provided
 	if table != nil && table.LineBuilder != nil {
 		lines, ok := buildLines(v, table.LineBuilder)
@@ -233,23 +304,9 @@ func printCmdOutputCustom(pr printRequest, v any, table *Table) {
 
 	// format table if a transform is provided or there is no custom table
 	if pr.fields != "" || table == nil || len(table.Headers) == 0 {
-		var err error
-		table, err = createTable(v, pr.fields, table) // replaces the table
-		if err != nil {
-			log.Warnf("Failed to convert output data to a table: %v; reverting to YAML output", err)
-			if err := PrintYaml(pr.cmd, v); err != nil {
-				log.Fatalf("Failed to convert output to YAML: %v (%+v)", err, v)
-			}
-			return
-		}
-	}
-
-	// display table
-	if table.Detail || pr.format == "detail" {
-		printDetail(pr.cmd, table)
-	} else {
-		printTable(pr.cmd, table)
+		return createTable(v, pr.fields, table) // replaces the table
 	}
+	return table, nil
 }
 
 func buildLines(in any, builderFunc func(any) []string) ([][]string, bool) {
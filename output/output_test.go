@@ -55,6 +55,24 @@ func TestPrintJSONAndYaml(t *testing.T) {
 	}
 }
 
+func TestPrintNDJSON(t *testing.T) {
+	pr := printRequest{format: "ndjson"}
+
+	// collection: one line per item
+	coll := map[string]any{
+		"items": []testStruct{{Field1: "a", Field2: 1}, {Field1: "b", Field2: 2, Field3: true}},
+		"total": 2,
+	}
+	outExpected := "{\"Field1\":\"a\",\"Field2\":1,\"Field3\":false}\n{\"Field1\":\"b\",\"Field2\":2,\"Field3\":true}\n"
+	outActual := test.CaptureConsoleOutput(func() { printCmdOutputCustom(pr, coll, nil) }, t)
+	require.Equal(t, outExpected, outActual)
+
+	// single object: a single line
+	outExpected = "{\"Field1\":\"hello\",\"Field2\":100,\"Field3\":true}\n"
+	outActual = test.CaptureConsoleOutput(func() { printCmdOutputCustom(pr, testStruct{Field1: "hello", Field2: 100, Field3: true}, nil) }, t)
+	require.Equal(t, outExpected, outActual)
+}
+
 func TestPrintSimple(t *testing.T) {
 	pr := printRequest{format: ""}
 
@@ -0,0 +1,243 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+)
+
+// Capture collects the output of a command run for one of multiple profiles (see the --profiles
+// flag), so that it can be combined with the output for the other profiles (see PrintProfileResults).
+// Commands display their output as usual; the output functions of this package add it to the
+// capture carried by the command's Go context (see WithCapture) instead of displaying it.
+type Capture struct {
+	mu      sync.Mutex
+	records []CapturedOutput
+}
+
+// CapturedOutput is a captured command output. Data is the output value (after the --fields
+// transform); Table is its human display form, if a human output format is selected and the
+// value can be displayed as a table.
+type CapturedOutput struct {
+	Data  any
+	Table *CapturedTable
+}
+
+// CapturedTable is the part of a Table needed to combine it with others
+type CapturedTable struct {
+	Headers []string
+	Lines   [][]string
+	Detail  bool
+}
+
+// ProfileResult is the output of a command run for one of multiple profiles. Output is the
+// output data (a list if the command displayed more than one output) and Text is any other
+// text the command displayed. Error describes the failure, if the command failed for the profile.
+type ProfileResult struct {
+	Profile string           `json:"profile" yaml:"profile"`
+	Output  any              `json:"output,omitempty" yaml:"output,omitempty"`
+	Text    string           `json:"text,omitempty" yaml:"text,omitempty"`
+	Error   any              `json:"error,omitempty" yaml:"error,omitempty"`
+	Records []CapturedOutput `json:"-" yaml:"-"`
+}
+
+type captureKey struct{}
+
+// WithCapture returns a copy of the Go context that carries the capture, so that the output of
+// commands run with the context is added to the capture rather than displayed
+func WithCapture(ctx context.Context, capture *Capture) context.Context {
+	return context.WithValue(ctx, captureKey{}, capture)
+}
+
+// Records returns the outputs captured so far
+func (c *Capture) Records() []CapturedOutput {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.records)
+}
+
+// captureOf returns the capture carried by the command's Go context, if any
+func captureOf(cmd *cobra.Command) *Capture {
+	if cmd == nil || cmd.Context() == nil {
+		return nil
+	}
+	capture, _ := cmd.Context().Value(captureKey{}).(*Capture)
+	return capture
+}
+
+// add captures an output, returning false if it cannot be captured (and so should be displayed).
+// The data is captured in its JSON form, as it would be displayed in JSON, so that the outputs of
+// all profiles can be combined uniformly.
+func (c *Capture) add(pr printRequest, v any, table *Table) bool {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Warnf("Failed to capture the command output (%v); displaying it instead", err)
+		return false
+	}
+	record := CapturedOutput{}
+	if err := json.Unmarshal(data, &record.Data); err != nil {
+		log.Warnf("Failed to capture the command output (%v); displaying it instead", err)
+		return false
+	}
+	if !slices.Contains([]string{"json", "yaml", "ndjson"}, pr.format) {
+		if _, isString := v.(string); !isString {
+			if t, err := humanTable(pr, v, table); err == nil {
+				record.Table = &CapturedTable{Headers: t.Headers, Lines: t.Lines, Detail: t.Detail || pr.format == "detail"}
+			}
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.records = append(c.records, record)
+	return true
+}
+
+// PrintProfileResults displays the combined output of a command run for multiple profiles in the
+// user-selected output format. Tables get a Profile column; JSON and YAML output is a list with
+// an entry per profile; NDJSON items get a "profile" key. Text that commands displayed directly
+// is shown after the combined output, under a heading for each profile.
+func PrintProfileResults(cmd *cobra.Command, results []ProfileResult) {
+	for i := range results {
+		results[i].Output = nil
+		switch len(results[i].Records) {
+		case 0:
+		case 1:
+			results[i].Output = results[i].Records[0].Data
+		default:
+			outputs := []any{}
+			for _, record := range results[i].Records {
+				outputs = append(outputs, record.Data)
+			}
+			results[i].Output = outputs
+		}
+	}
+
+	format, _ := cmd.Flags().GetString("output")
+	switch format {
+	case "json":
+		if err := PrintJson(cmd, results); err != nil {
+			log.Fatalf("Failed to convert output to JSON: %v", err)
+		}
+		return
+	case "yaml":
+		if err := PrintYaml(cmd, results); err != nil {
+			log.Fatalf("Failed to convert output to YAML: %v", err)
+		}
+		return
+	case "ndjson":
+		if err := WriteNDJSON(profileItems(results), GetOutWriter(cmd)); err != nil {
+			log.Fatalf("Failed to convert output to NDJSON: %v", err)
+		}
+		return
+	}
+
+	if table, ok := mergeTables(results); ok {
+		if table.Detail || format == "detail" {
+			printDetail(cmd, table)
+		} else {
+			printTable(cmd, table)
+		}
+	} else if slices.ContainsFunc(results, func(r ProfileResult) bool { return len(r.Records) > 0 }) {
+		if err := PrintYaml(cmd, results); err != nil {
+			log.Fatalf("Failed to convert output to YAML: %v", err)
+		}
+	}
+	for _, result := range results {
+		if result.Text != "" {
+			printf(cmd, "==> %v <==\n%v", result.Profile, result.Text)
+		}
+	}
+}
+
+// mergeTables combines the tables of all profiles into one, with a Profile column (unless the
+// tables have one already). It fails if any output has no table or if the tables are not of the same shape.
+func mergeTables(results []ProfileResult) (*Table, bool) {
+	var merged, first *CapturedTable
+	for _, result := range results {
+		for _, record := range result.Records {
+			t := record.Table
+			if t == nil {
+				return nil, false
+			}
+			if first == nil {
+				first = t
+				merged = &CapturedTable{Headers: t.Headers, Lines: [][]string{}, Detail: t.Detail}
+				if !slices.Contains(t.Headers, "Profile") {
+					merged.Headers = append([]string{"Profile"}, t.Headers...)
+				}
+			} else if !slices.Equal(first.Headers, t.Headers) || first.Detail != t.Detail {
+				return nil, false
+			}
+			for _, line := range t.Lines {
+				if len(merged.Headers) > len(t.Headers) {
+					line = append([]string{result.Profile}, line...)
+				}
+				merged.Lines = append(merged.Lines, line)
+			}
+		}
+	}
+	if merged == nil {
+		return nil, false
+	}
+	return &Table{Headers: merged.Headers, Lines: merged.Lines, Detail: merged.Detail}, true
+}
+
+// profileItems lists the collection items (or other output values) of all profiles, adding a
+// "profile" key to each. Values that are not objects are provided under a "value" key.
+func profileItems(results []ProfileResult) []any {
+	items := []any{}
+	for _, result := range results {
+		for _, record := range result.Records {
+			for _, item := range collectionItems(record.Data) {
+				if m, ok := item.(map[string]any); ok {
+					entry := map[string]any{"profile": result.Profile}
+					for k, v := range m {
+						entry[k] = v
+					}
+					items = append(items, entry)
+				} else {
+					items = append(items, map[string]any{"profile": result.Profile, "value": item})
+				}
+			}
+		}
+		if result.Text != "" {
+			items = append(items, map[string]any{"profile": result.Profile, "text": result.Text})
+		}
+		if result.Error != nil {
+			items = append(items, map[string]any{"profile": result.Profile, "error": result.Error})
+		}
+	}
+	return items
+}
+
+// collectionItems returns the items of a collection, or the value itself if it is not a collection
+func collectionItems(data any) []any {
+	switch d := data.(type) {
+	case map[string]any:
+		if lst, ok := d["items"].([]any); ok {
+			return lst
+		}
+	case []any:
+		return d
+	}
+	return []any{data}
+}
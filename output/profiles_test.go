@@ -0,0 +1,114 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func captureFor(t *testing.T, format string, v any, table *Table) []CapturedOutput {
+	capture := &Capture{}
+	cmd := &cobra.Command{}
+	cmd.SetContext(WithCapture(context.Background(), capture))
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	printCmdOutputCustom(printRequest{cmd: cmd, format: format}, v, table)
+	printSimple(cmd, "some text")
+	assert.Equal(t, "some text\n", buf.String()) // other text is displayed as is
+	return capture.Records()
+}
+
+func TestCaptureOutput(t *testing.T) {
+	data := map[string]any{"items": []any{map[string]any{"id": "s1"}}, "total": 1}
+	table := &Table{Headers: []string{"ID"}, Lines: [][]string{{"s1"}}}
+
+	records := captureFor(t, "table", data, table)
+	require.Len(t, records, 1)
+	assert.Equal(t, &CapturedTable{Headers: []string{"ID"}, Lines: [][]string{{"s1"}}}, records[0].Table)
+	assert.Equal(t, float64(1), records[0].Data.(map[string]any)["total"])
+
+	// machine formats carry only the data
+	records = captureFor(t, "json", data, table)
+	require.Len(t, records, 1)
+	assert.Nil(t, records[0].Table)
+	assert.NotNil(t, records[0].Data)
+}
+
+func TestMergeTables(t *testing.T) {
+	results := []ProfileResult{
+		{Profile: "dev", Records: []CapturedOutput{{Table: &CapturedTable{Headers: []string{"ID"}, Lines: [][]string{{"s1"}, {"s2"}}}}}},
+		{Profile: "prod", Records: []CapturedOutput{{Table: &CapturedTable{Headers: []string{"ID"}, Lines: [][]string{{"s3"}}}}}},
+		{Profile: "failed", Error: "failed"},
+	}
+	table, ok := mergeTables(results)
+	require.True(t, ok)
+	assert.Equal(t, []string{"Profile", "ID"}, table.Headers)
+	assert.Equal(t, [][]string{{"dev", "s1"}, {"dev", "s2"}, {"prod", "s3"}}, table.Lines)
+
+	// tables that already have a Profile column are kept as is
+	withProfile := []ProfileResult{
+		{Profile: "dev", Records: []CapturedOutput{{Table: &CapturedTable{Headers: []string{"Profile", "URL"}, Lines: [][]string{{"dev", "x"}}, Detail: true}}}},
+	}
+	table, ok = mergeTables(withProfile)
+	require.True(t, ok)
+	assert.Equal(t, []string{"Profile", "URL"}, table.Headers)
+	assert.True(t, table.Detail)
+
+	// different tables cannot be merged
+	results[1].Records[0].Table.Headers = []string{"Name"}
+	_, ok = mergeTables(results)
+	assert.False(t, ok)
+	_, ok = mergeTables([]ProfileResult{{Profile: "dev", Records: []CapturedOutput{{Data: "x"}}}})
+	assert.False(t, ok)
+}
+
+func TestPrintProfileResults(t *testing.T) {
+	results := func() []ProfileResult {
+		return []ProfileResult{
+			{Profile: "dev", Records: []CapturedOutput{{Data: map[string]any{"items": []any{map[string]any{"id": "s1"}, map[string]any{"id": "s2"}}}}}},
+			{Profile: "prod", Error: map[string]any{"kind": "network"}},
+		}
+	}
+	print := func(format string) string {
+		cmd := &cobra.Command{}
+		cmd.Flags().String("output", format, "")
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		PrintProfileResults(cmd, results())
+		return buf.String()
+	}
+
+	var list []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(print("json")), &list))
+	require.Len(t, list, 2)
+	assert.Equal(t, "dev", list[0]["profile"])
+	assert.NotNil(t, list[0]["output"])
+	assert.Equal(t, map[string]any{"kind": "network"}, list[1]["error"])
+
+	lines := strings.Split(strings.TrimSpace(print("ndjson")), "\n")
+	assert.Equal(t, []string{
+		`{"id":"s1","profile":"dev"}`,
+		`{"id":"s2","profile":"dev"}`,
+		`{"error":{"kind":"network"},"profile":"prod"}`,
+	}, lines)
+}
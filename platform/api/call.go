@@ -27,22 +27,38 @@ import (
 	"os"
 	"slices"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/apex/log"
 	"github.com/moul/http2curl"
 
 	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/tracing"
 )
 
+// FlagCurlifyRequests enables logging the curl equivalent of the requests made by the package-level functions
 var FlagCurlifyRequests bool
 
 // --- Public Interface -----------------------------------------------------
+// The package-level functions use the current profile of the fsoc config file (see defaultClient);
+// use a Client to call the platform with a different profile.
 
 type Options struct {
-	Headers         map[string]string
-	ResponseHeaders map[string][]string // headers as returned by the call
-	ExpectedErrors  []int               // log expected error status codes as Info rather than Error
+	Headers            map[string]string
+	ResponseHeaders    map[string][]string // headers as returned by the call
+	ExpectedErrors     []int               // log expected error status codes as Info rather than Error
+	RetryNonIdempotent bool                // allow retrying transient failures for non-idempotent methods (e.g., POST)
+	Context            context.Context     // Go context for the call; if nil, the default context is used (see SetDefaultContext); selects the client for package-level functions (see WithClient)
+	NoLogin            bool                // use the current access token as is, without logging in or refreshing it (e.g., to validate it)
+}
+
+// goContext returns the Go context for the call, if provided
+func (o *Options) goContext() context.Context {
+	if o == nil {
+		return nil
+	}
+	return o.Context
 }
 
 // JSONGet performs a GET request and parses the response as JSON
@@ -81,14 +97,15 @@ func JSONPatch(path string, body any, out any, options *Options) error {
 }
 
 // JSONRequest performs an HTTP request and parses the response as JSON, allowing
-// the http method to be specified
+// the http method to be specified. If out is a *[]byte, the response body is returned
+// as is, without parsing, e.g., for responses that may not be JSON.
 func JSONRequest(method string, path string, body any, out any, options *Options) error {
 	return httpRequest(method, path, body, out, options)
 }
 
 // --- Internal methods -----------------------------------------------------
 
-func prepareHTTPRequest(cfg *config.Context, client *http.Client, method string, path string, body any, headers map[string]string) (*http.Request, error) {
+func prepareHTTPRequest(ctx context.Context, cfg *config.Context, subsystem string, client *http.Client, method string, path string, body any, headers map[string]string) (*http.Request, error) {
 	// body will be JSONified if a body is given but no Content-Type is provided
 	// (if a content type is provided, we assume the body is in the desired format)
 	jsonify := body != nil && (headers == nil || headers["Content-Type"] == "")
@@ -118,7 +135,7 @@ func prepareHTTPRequest(cfg *config.Context, client *http.Client, method string,
 	path, query, _ := strings.Cut(path, "?")
 	uri, err := url.Parse(cfg.URL)
 	if err != nil {
-		log.Fatalf("Failed to parse the url provided in context (%q): %v", cfg.URL, err)
+		return nil, fmt.Errorf("failed to parse the url provided in context (%q): %w", cfg.URL, err)
 	}
 	// Create the full path again ensuring that we aren't double escaping characters in the path
 	joinedPath, err := url.JoinPath(uri.String(), path)
@@ -132,7 +149,7 @@ func prepareHTTPRequest(cfg *config.Context, client *http.Client, method string,
 		fullPath = fmt.Sprintf("%s?%s", joinedPath, query)
 	}
 
-	req, err := http.NewRequest(method, fullPath, bodyReader)
+	req, err := http.NewRequestWithContext(ctx, method, fullPath, bodyReader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create a request for %q: %w", uri.String(), err)
 	}
@@ -156,24 +173,43 @@ func prepareHTTPRequest(cfg *config.Context, client *http.Client, method string,
 		AddLocalAuthReqHeaders(req, &cfg.LocalAuthOptions)
 	}
 
+	// add the profile's default headers, unless provided explicitly; as their values may be secrets,
+	// they are redacted wherever requests are recorded or logged (see isRedactedHeader)
+	defaultHeaders := []string{}
+	for k, v := range cfg.HeaderOptions.Headers(subsystem) {
+		if !hasHeader(headers, k) {
+			req.Header.Set(k, v)
+			defaultHeaders = append(defaultHeaders, k)
+		}
+	}
+	if len(defaultHeaders) > 0 {
+		req = req.WithContext(withDefaultHeaders(req.Context(), defaultHeaders))
+	}
+
 	// add explicit headers
 	for k, v := range headers {
 		req.Header.Add(k, v)
 	}
 
-	if FlagCurlifyRequests { // global --curl flag
-		curlCommand, err := getCurlCommandOfRequest(req)
-		if err != nil {
-			return nil, fmt.Errorf("failed to generate curl equivalent command: %s", err)
+	return req, nil
+}
+
+// hasHeader returns true if the headers include the named header, regardless of case
+func hasHeader(headers map[string]string, name string) bool {
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			return true
 		}
-		log.WithField("command", curlCommand).Info("curl command equivalent")
 	}
-
-	return req, nil
+	return false
 }
 
 func getCurlCommandOfRequest(req *http.Request) (string, error) {
 	reqClone := req.Clone(context.Background())
+	reqClone.Header = redactHeaders(req.Header, defaultHeaderNames(req.Context()))
+	if reqClone.Header == nil {
+		reqClone.Header = http.Header{}
+	}
 	reqClone.Header.Set("Authorization", "Bearer REDACTED")
 
 	if strings.HasPrefix(reqClone.Header.Get("Content-Type"), "multipart/form-data") {
@@ -192,84 +228,84 @@ func getCurlCommandOfRequest(req *http.Request) (string, error) {
 	return command.String(), nil
 }
 
+// httpRequest performs a request using the default client (see defaultClient)
 func httpRequest(method string, path string, body any, out any, options *Options) error {
-	log.WithFields(log.Fields{"method": method, "path": path}).Info("Calling the observability platform API")
+	client, err := defaultClient(options.goContext())
+	if err != nil {
+		return err
+	}
+	return client.request(method, path, body, out, options)
+}
 
-	callCtx := newCallContext()
-	cfg := callCtx.cfg               // quick access
-	defer callCtx.stopSpinner(false) // ensure the spinner is not running when returning (belt & suspenders)
+func (c *Client) request(method string, path string, body any, out any, options *Options) (err error) {
+	c.logger.WithFields(log.Fields{"method": method, "path": path}).Info("Calling the observability platform API")
 
 	// create a default options to avoid nil-checking
 	if options == nil {
 		options = &Options{}
 	}
 
-	// force login if no token
-	if cfg.Token == "" {
-		log.Info("No auth token available, trying to log in")
+	callCtx := c.newCallContext(options.Context)
+	defer callCtx.stopSpinner(false) // ensure the spinner is not running when returning (belt & suspenders)
+
+	// trace the call, incl. any login and retries, as a parent of the HTTP requests it makes
+	apiPath, _, _ := strings.Cut(path, "?")
+	var span *tracing.Span
+	callCtx.goContext, span = tracing.Start(callCtx.goContext, method+" "+apiPath, tracing.SpanKindInternal, tracing.Attributes{
+		"fsoc.profile":        callCtx.cfg.Name,
+		"http.request.method": method,
+		"url.path":            apiPath,
+	})
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
+	// force login if no token; refresh the token proactively if it is expired or about to expire
+	if options.NoLogin {
+		c.logger.Info("Using the current access token as is, without login")
+	} else if callCtx.cfg.Token == "" {
+		c.logger.Info("No auth token available, trying to log in")
 		if err := login(callCtx); err != nil {
 			return err
 		}
-		cfg = callCtx.cfg // may have changed across login
+	} else if tokenNeedsRefresh(callCtx.cfg, time.Now()) {
+		c.logger.Info("Auth token is expired or about to expire, trying to refresh it")
+		if err := login(callCtx); err != nil {
+			return fmt.Errorf("failed to login: %w", err)
+		}
 	}
 
 	// create http client for the request
-	client := &http.Client{
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
-	}
-
-	// build HTTP request
-	req, err := prepareHTTPRequest(cfg, client, method, path, body, options.Headers)
+	client, err := callCtx.httpClient()
 	if err != nil {
-		return err // assume error messages provide sufficient info
+		return err
 	}
-
-	// execute request, speculatively, assuming the auth token is valid
-	callCtx.startSpinner(fmt.Sprintf("Platform API call (%v %v)", req.Method, urlDisplayPath(req.URL)))
-	resp, err := client.Do(req)
-	if err != nil {
-		// nb: spinner will be stopped by defer
-		return fmt.Errorf("%v request to %q failed: %w", method, req.URL.String(), err)
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
 	}
 
-	// collect response body (whether success or error)
-	var respBytes []byte
-	defer resp.Body.Close()
-	respBytes, err = io.ReadAll(resp.Body)
+	// execute request, speculatively, assuming the auth token is valid
+	resp, respBytes, err := sendRequest(callCtx, client, method, path, body, options, "Platform API call")
 	if err != nil {
-		return fmt.Errorf("failed reading response to %v to %q (status %v): %w", method, req.URL.String(), resp.StatusCode, err)
+		return err // assume error messages provide sufficient info
 	}
 
 	// handle special case when access token needs to be refreshed and request retried
-	if resp.StatusCode == http.StatusForbidden {
+	if !options.NoLogin && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) {
 		callCtx.stopSpinnerHide()
-		log.Warn("Current token is no longer valid; trying to refresh")
+		c.logger.Warn("Current token is no longer valid; trying to refresh")
 		err := login(callCtx)
 		if err != nil {
 			return fmt.Errorf("failed to login: %w", err)
 		}
-		cfg = callCtx.cfg // may have changed across login
 
 		// retry the request
-		log.Info("Retrying the request with the refreshed token")
-		req, err = prepareHTTPRequest(cfg, client, method, path, body, options.Headers)
-		if err != nil {
-			return err // error should have enough context
-		}
-		callCtx.startSpinner(fmt.Sprintf("Platform API call, retry after login (%v %v)", req.Method, urlDisplayPath(req.URL)))
-		resp, err = client.Do(req)
+		c.logger.Info("Retrying the request with the refreshed token")
+		resp, respBytes, err = sendRequest(callCtx, client, method, path, body, options, "Platform API call, retry after login")
 		// leave the spinner until the outcome is finalized, return will stop/fail it
 		if err != nil {
-			return fmt.Errorf("%v request to %q failed: %w", method, req.URL.String(), err)
-		}
-
-		// collect response body (whether success or error)
-		defer resp.Body.Close()
-		respBytes, err = io.ReadAll(resp.Body)
-		if err != nil {
-			return fmt.Errorf("failed reading response to %v to %q (status %v): %w", method, req.URL.String(), resp.StatusCode, err)
+			return err
 		}
 	}
 
@@ -278,9 +314,9 @@ func httpRequest(method string, path string, body any, out any, options *Options
 	if resp.StatusCode/100 != 2 && resp.StatusCode != 303 {
 		callCtx.stopSpinner(false) // if still running
 		if options.ExpectedErrors != nil && slices.Contains(options.ExpectedErrors, resp.StatusCode) {
-			log.WithFields(log.Fields{"status": resp.StatusCode}).Info("Platform API call failed with expected error")
+			c.logger.WithFields(log.Fields{"status": resp.StatusCode}).Info("Platform API call failed with expected error")
 		} else {
-			log.WithFields(log.Fields{"status": resp.StatusCode}).Error("Platform API call failed")
+			c.logger.WithFields(log.Fields{"status": resp.StatusCode}).Error("Platform API call failed")
 		}
 		return parseIntoError(resp, respBytes)
 	}
@@ -290,7 +326,9 @@ func httpRequest(method string, path string, body any, out any, options *Options
 
 	// process body
 	contentType := resp.Header.Get("content-type")
-	if method != "DELETE" {
+	if raw, ok := out.(*[]byte); ok {
+		*raw = respBytes // the caller processes the response body
+	} else if method != "DELETE" {
 		// for downloaded files, save them
 		if contentType == "application/octet-stream" || contentType == "application/zip" {
 			var solutionFileName = options.Headers["solutionFileName"]
@@ -324,6 +362,65 @@ func httpRequest(method string, path string, body any, out any, options *Options
 	return nil
 }
 
+// sendRequest prepares and executes a request using the call context's current config, collecting the
+// response body. Throttled requests and transient failures are retried per the profile's retry policy,
+// with a backoff between attempts. The returned response's body is already read and closed.
+func sendRequest(callCtx *callContext, client *http.Client, method string, path string, body any, options *Options, description string) (*http.Response, []byte, error) {
+	policy := newRetryPolicy(callCtx.cfg)
+	for attempt := 0; ; attempt++ {
+		// build HTTP request (again for each attempt, as the body reader is consumed)
+		req, err := prepareHTTPRequest(callCtx.goContext, callCtx.cfg, callCtx.subsystem(), client, method, path, body, options.Headers)
+		if err != nil {
+			return nil, nil, err // assume error messages provide sufficient info
+		}
+		if callCtx.client != nil && callCtx.client.curlify { // e.g., global --curl flag
+			curlCommand, err := getCurlCommandOfRequest(req)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to generate curl equivalent command: %s", err)
+			}
+			callCtx.logger().WithField("command", curlCommand).Info("curl command equivalent")
+		}
+
+		// execute request
+		callCtx.startSpinner(fmt.Sprintf("%v (%v %v)", description, req.Method, urlDisplayPath(req.URL)))
+		resp, err := doRequest(client, req)
+		var respBytes []byte
+		if err == nil {
+			// collect response body (whether success or error)
+			respBytes, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				err = fmt.Errorf("failed reading response to %v to %q (status %v): %w", method, req.URL.String(), resp.StatusCode, err)
+				resp = nil
+			}
+		} else {
+			err = &NetworkError{Method: method, URL: req.URL.String(), Err: err}
+			// nb: spinner will be stopped by the caller
+		}
+
+		// return unless the request failed in a way that can be retried (never retry if cancelled or timed out)
+		if callCtx.goContext.Err() != nil || !policy.shouldRetry(attempt, method, options, resp, err) {
+			return resp, respBytes, err
+		}
+
+		// log and wait before retrying
+		delay := policy.delay(attempt, resp)
+		fields := log.Fields{"method": method, "path": path, "attempt": attempt + 1, "max_retries": policy.maxRetries, "delay": delay.String()}
+		if err != nil {
+			fields["error"] = err.Error()
+		} else {
+			fields["status"] = resp.StatusCode
+		}
+		callCtx.stopSpinnerHide()
+		callCtx.logger().WithFields(fields).Warn("Platform API call failed with a transient error; retrying")
+		select {
+		case <-time.After(delay):
+		case <-callCtx.goContext.Done():
+			return nil, nil, fmt.Errorf("%v request to %q aborted while waiting to retry: %w", method, path, callCtx.goContext.Err())
+		}
+	}
+}
+
 // parseError creates an HttpStatusError error from HTTP response data
 // This method creates either a simple error with the status code and response body
 // or a wrapped Problem struct in case the response is of type "application/problem+json"
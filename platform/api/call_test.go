@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"net/http"
 	"testing"
 
@@ -14,7 +15,7 @@ func TestPrepareHTTPRequest(t *testing.T) {
 	cfg := &config.Context{
 		URL: "http://localhost:8080",
 	}
-	req, err := prepareHTTPRequest(cfg, client, "POST", "/test/path/1", nil, nil)
+	req, err := prepareHTTPRequest(context.Background(), cfg, "", client, "POST", "/test/path/1", nil, nil)
 	assert.Nil(t, err)
 	assert.Equal(t, "http://localhost:8080/test/path/1", req.URL.String())
 }
@@ -24,7 +25,40 @@ func TestPrepareJSONRequest(t *testing.T) {
 	cfg := &config.Context{
 		URL: "http://localhost:8080",
 	}
-	req, err := prepareHTTPRequest(cfg, client, "POST", "/test/path/1", nil, nil)
+	req, err := prepareHTTPRequest(context.Background(), cfg, "", client, "POST", "/test/path/1", nil, nil)
 	assert.Nil(t, err)
 	assert.Equal(t, "http://localhost:8080/test/path/1", req.URL.String())
 }
+
+func TestPrepareHTTPRequestDefaultHeaders(t *testing.T) {
+	t.Setenv("FSOC_TEST_TOKEN", "from-env")
+	client := &http.Client{}
+	cfg := &config.Context{
+		URL:        "http://localhost:8080",
+		AuthMethod: config.AuthMethodLocal,
+		HeaderOptions: config.HeaderOptions{
+			Global: map[string]string{"x-team": "observability", "X-Override": "default", config.AppdTid: "override"},
+			Subsystems: map[string]map[string]string{
+				"knowledge": {"X-Api-Key": "${FSOC_TEST_TOKEN}"},
+			},
+		},
+	}
+	req, err := prepareHTTPRequest(context.Background(), cfg, "knowledge", client, "GET", "/test/path/1", nil, map[string]string{"x-override": "explicit"})
+	assert.Nil(t, err)
+	assert.Equal(t, "observability", req.Header.Get("X-Team"))
+	assert.Equal(t, "from-env", req.Header.Get("X-Api-Key"))
+	assert.Equal(t, []string{"explicit"}, req.Header.Values("X-Override")) // explicit headers take precedence
+	assert.Equal(t, []string{"override"}, req.Header.Values(config.AppdTid))
+
+	// default header values may be secrets and are not logged
+	curl, err := getCurlCommandOfRequest(req)
+	assert.Nil(t, err)
+	assert.NotContains(t, curl, "from-env")
+	assert.NotContains(t, curl, "observability")
+	assert.Contains(t, curl, "explicit")
+
+	req, err = prepareHTTPRequest(context.Background(), cfg, "uql", client, "GET", "/test/path/1", nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "observability", req.Header.Get("X-Team"))
+	assert.Equal(t, "", req.Header.Get("X-Api-Key")) // knowledge only
+}
@@ -0,0 +1,382 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/apex/log"
+	"gopkg.in/yaml.v3"
+
+	"github.com/cisco-open/fsoc/tracing"
+)
+
+// redactedValue replaces secrets (credentials and tokens) in recorded cassettes
+const redactedValue = "REDACTED"
+
+// redactedHeaders are the request and response headers whose values are never recorded
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// sensitiveHeaderWords mark the headers whose values are never recorded, in addition to redactedHeaders
+// and the profile's default headers, e.g., X-Api-Key
+var sensitiveHeaderWords = []string{"key", "token", "secret", "password"}
+
+// redactedFields are the urlencoded form fields and top-level JSON fields whose values are never recorded
+var redactedFields = []string{
+	"access_token", "refresh_token", "id_token",
+	"client_secret", "client_assertion", "code", "code_verifier", "password", "token",
+}
+
+type cassetteMode int
+
+const (
+	cassetteOff cassetteMode = iota
+	cassetteRecord
+	cassetteReplay
+)
+
+// cassette is the file format for recorded platform API traffic (see --record and --replay)
+type cassette struct {
+	Interactions []interaction `yaml:"interactions"`
+}
+
+type interaction struct {
+	Request  recordedRequest  `yaml:"request"`
+	Response recordedResponse `yaml:"response"`
+}
+
+type recordedRequest struct {
+	Method  string      `yaml:"method"`
+	URL     string      `yaml:"url"`
+	Headers http.Header `yaml:"headers,omitempty"`
+	Body    string      `yaml:"body,omitempty"`
+}
+
+type recordedResponse struct {
+	StatusCode int         `yaml:"status"`
+	Headers    http.Header `yaml:"headers,omitempty"`
+	Body       string      `yaml:"body,omitempty"`
+}
+
+// cassetteState holds the active recording or replay, if any. Access is guarded by mu, as
+// requests may be executed concurrently
+var cassetteState struct {
+	mu       sync.Mutex
+	mode     cassetteMode
+	path     string
+	cassette cassette
+	used     []bool // for replay, which interactions have already been served
+}
+
+// StartRecording starts recording all platform API requests and their responses, incl. those
+// made by the login flows, into a cassette file. The file is (re)written after each request, so
+// that it is complete even if the command fails. Credentials and tokens are redacted.
+func StartRecording(path string) error {
+	cassetteState.mu.Lock()
+	defer cassetteState.mu.Unlock()
+
+	cassetteState.mode = cassetteRecord
+	cassetteState.path = path
+	cassetteState.cassette = cassette{Interactions: []interaction{}}
+	cassetteState.used = nil
+
+	// create the file early, so that an invalid path is detected before any requests are made
+	return saveCassette()
+}
+
+// StartReplay starts serving platform API requests, incl. those made by the login flows, from a
+// previously recorded cassette file instead of the network. Each recorded interaction is served
+// at most once, in the order of recording; requests that don't match any remaining recorded
+// interaction fail. Tokens obtained by logins during replay are not saved to the profile.
+func StartReplay(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read cassette file %q: %w", path, err)
+	}
+	var c cassette
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return fmt.Errorf("failed to parse cassette file %q: %w", path, err)
+	}
+
+	cassetteState.mu.Lock()
+	defer cassetteState.mu.Unlock()
+
+	cassetteState.mode = cassetteReplay
+	cassetteState.path = path
+	cassetteState.cassette = c
+	cassetteState.used = make([]bool, len(c.Interactions))
+	log.WithFields(log.Fields{"file": path, "interactions": len(c.Interactions)}).Info("Replaying platform API traffic from cassette")
+
+	return nil
+}
+
+// isReplaying returns true if requests are served from a cassette rather than the network
+func isReplaying() bool {
+	cassetteState.mu.Lock()
+	defer cassetteState.mu.Unlock()
+	return cassetteState.mode == cassetteReplay
+}
+
+// doRequest executes an HTTP request with the given client. All platform API requests, incl. those made
+// by the login flows, must be executed through this function so that they can be recorded and replayed,
+// and captured into a HAR file (see StartHAR).
+// Each request is traced as a client span, propagating the trace context to the platform.
+func doRequest(client *http.Client, req *http.Request) (*http.Response, error) {
+	ctx, span := tracing.Start(req.Context(), "HTTP "+req.Method, tracing.SpanKindClient, tracing.Attributes{
+		"http.request.method": req.Method,
+		"url.full":            req.URL.Redacted(),
+		"server.address":      req.URL.Hostname(),
+	})
+	defer span.End()
+	if span != nil {
+		req = req.WithContext(ctx)
+		tracing.Inject(ctx, req.Header)
+	}
+
+	var resp *http.Response
+	var err error
+	if isCapturingHAR() {
+		resp, err = captureRequest(client, req)
+	} else {
+		resp, err = executeRequest(client, req)
+	}
+	if err != nil {
+		span.SetError(err)
+		return nil, err
+	}
+	span.SetAttributes(tracing.Attributes{"http.response.status_code": resp.StatusCode})
+	if resp.StatusCode >= 400 {
+		span.SetError(fmt.Errorf("status %v", resp.StatusCode))
+	}
+	// link the platform's own trace of the request, if it reports one (e.g., MELT ingestion)
+	if traceresponse := resp.Header.Get("Traceresponse"); traceresponse != "" {
+		span.AddLink(traceresponse, tracing.Attributes{"fsoc.link": "traceresponse"})
+	}
+	return resp, nil
+}
+
+func executeRequest(client *http.Client, req *http.Request) (*http.Response, error) {
+	cassetteState.mu.Lock()
+	mode := cassetteState.mode
+	cassetteState.mu.Unlock()
+
+	switch mode {
+	case cassetteReplay:
+		return replayRequest(req)
+	case cassetteRecord:
+		return recordRequest(client, req)
+	default:
+		return client.Do(req)
+	}
+}
+
+func recordRequest(client *http.Client, req *http.Request) (*http.Response, error) {
+	// capture the request body without consuming it
+	var reqBody []byte
+	if req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err == nil {
+			reqBody, _ = io.ReadAll(body)
+			body.Close()
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err // failed requests are not recorded
+	}
+
+	// capture the response body and replace it, so that the caller can still read it
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body for recording: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	cassetteState.mu.Lock()
+	defer cassetteState.mu.Unlock()
+	cassetteState.cassette.Interactions = append(cassetteState.cassette.Interactions, interaction{
+		Request: recordedRequest{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: redactHeaders(req.Header, defaultHeaderNames(req.Context())),
+			Body:    redactBody(reqBody),
+		},
+		Response: recordedResponse{
+			StatusCode: resp.StatusCode,
+			Headers:    redactHeaders(resp.Header, nil),
+			Body:       redactBody(respBody),
+		},
+	})
+	if err := saveCassette(); err != nil {
+		log.Warnf("Failed to save the recorded platform API traffic: %v", err)
+	}
+
+	return resp, nil
+}
+
+func replayRequest(req *http.Request) (*http.Response, error) {
+	cassetteState.mu.Lock()
+	defer cassetteState.mu.Unlock()
+
+	reqURL := req.URL.String()
+	for i, inter := range cassetteState.cassette.Interactions {
+		if cassetteState.used[i] || inter.Request.Method != req.Method || inter.Request.URL != reqURL {
+			continue
+		}
+		cassetteState.used[i] = true
+		log.WithFields(log.Fields{"method": req.Method, "url": reqURL, "interaction": i + 1}).Info("Replaying recorded response")
+
+		rec := inter.Response
+		header := rec.Headers.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", rec.StatusCode, http.StatusText(rec.StatusCode)),
+			StatusCode:    rec.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(rec.Body)),
+			ContentLength: int64(len(rec.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no recorded interaction in cassette %q matches %v %v", cassetteState.path, req.Method, reqURL)
+}
+
+// saveCassette writes the cassette being recorded to its file; must be called with cassetteState.mu held
+func saveCassette() error {
+	data, err := yaml.Marshal(cassetteState.cassette)
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+	if err := os.WriteFile(cassetteState.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write cassette file %q: %w", cassetteState.path, err)
+	}
+	return nil
+}
+
+type defaultHeadersKey struct{}
+
+// withDefaultHeaders marks the request's Go context with the names of the profile's default headers,
+// whose values may be secrets (e.g., API keys) and so are redacted like credentials
+func withDefaultHeaders(ctx context.Context, names []string) context.Context {
+	return context.WithValue(ctx, defaultHeadersKey{}, names)
+}
+
+// defaultHeaderNames returns the names of the profile's default headers added to a request, see withDefaultHeaders
+func defaultHeaderNames(ctx context.Context) []string {
+	names, _ := ctx.Value(defaultHeadersKey{}).([]string)
+	return names
+}
+
+// isRedactedHeader returns true if the header's values are never recorded or logged: credentials,
+// headers whose names suggest a secret and the profile's default headers (defaultHeaders)
+func isRedactedHeader(name string, defaultHeaders []string) bool {
+	if slices.Contains(redactedHeaders, http.CanonicalHeaderKey(name)) {
+		return true
+	}
+	if slices.ContainsFunc(defaultHeaders, func(h string) bool { return strings.EqualFold(h, name) }) {
+		return true
+	}
+	lower := strings.ToLower(name)
+	return slices.ContainsFunc(sensitiveHeaderWords, func(w string) bool { return strings.Contains(lower, w) })
+}
+
+// redactHeaders returns a copy of the headers with the values of sensitive headers redacted (see
+// isRedactedHeader); defaultHeaders are the names of the profile's default headers, if any
+func redactHeaders(header http.Header, defaultHeaders []string) http.Header {
+	if len(header) == 0 {
+		return nil
+	}
+	out := header.Clone()
+	for name, values := range out {
+		if isRedactedHeader(name, defaultHeaders) {
+			for i := range values {
+				values[i] = redactedValue
+			}
+		}
+	}
+	return out
+}
+
+// redactBody replaces the values of sensitive fields in JSON objects and urlencoded forms, leaving
+// any other body as is
+func redactBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	// JSON object
+	var obj map[string]any
+	if err := json.Unmarshal(body, &obj); err == nil {
+		redacted := false
+		for _, field := range redactedFields {
+			if _, found := obj[field]; found {
+				obj[field] = redactedValue
+				redacted = true
+			}
+		}
+		if !redacted {
+			return string(body)
+		}
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return redactedValue // should never happen; don't risk leaking the original
+		}
+		return string(data)
+	}
+
+	// urlencoded form (only if it has at least one sensitive field, so that arbitrary text is left alone)
+	if values, err := url.ParseQuery(string(body)); err == nil {
+		redacted := false
+		for _, field := range redactedFields {
+			if values.Has(field) {
+				values.Set(field, redactedValue)
+				redacted = true
+			}
+		}
+		if redacted {
+			return values.Encode()
+		}
+	}
+
+	return string(body)
+}
+
+// resetCassette stops any recording or replay (used by tests)
+func resetCassette() {
+	cassetteState.mu.Lock()
+	defer cassetteState.mu.Unlock()
+	cassetteState.mode = cassetteOff
+	cassetteState.path = ""
+	cassetteState.cassette = cassette{}
+	cassetteState.used = nil
+}
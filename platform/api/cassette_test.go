@@ -0,0 +1,111 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	defer resetCassette()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/token" {
+			_, _ = w.Write([]byte(`{"access_token":"secret-token","expires_in":300}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"items":[1,2],"total":2}`))
+	}))
+	defer server.Close()
+	cassetteFile := filepath.Join(t.TempDir(), "cassette.yaml")
+
+	// record
+	assert.Nil(t, StartRecording(cassetteFile))
+	req, _ := http.NewRequest("POST", server.URL+"/token", strings.NewReader("grant_type=client_credentials&client_secret=s3cr3t"))
+	req.Header.Set("Authorization", "Basic c2VjcmV0")
+	resp, err := doRequest(server.Client(), req)
+	assert.Nil(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(body), "secret-token") // caller still gets the unredacted response
+
+	req, _ = http.NewRequest("GET", server.URL+"/objects?max=2", nil)
+	req.Header.Set("Authorization", "Bearer secret-token")
+	_, err = doRequest(server.Client(), req)
+	assert.Nil(t, err)
+
+	data, err := os.ReadFile(cassetteFile)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), "secret-token")
+	assert.NotContains(t, string(data), "s3cr3t")
+	assert.NotContains(t, string(data), "c2VjcmV0")
+	assert.Contains(t, string(data), redactedValue)
+
+	// replay without the server
+	server.Close()
+	assert.Nil(t, StartReplay(cassetteFile))
+	assert.True(t, isReplaying())
+
+	req, _ = http.NewRequest("GET", server.URL+"/objects?max=2", nil)
+	resp, err = doRequest(http.DefaultClient, req)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, `{"items":[1,2],"total":2}`, string(body))
+
+	// each interaction is served once; unmatched requests fail
+	_, err = doRequest(http.DefaultClient, req)
+	assert.NotNil(t, err)
+	req, _ = http.NewRequest("GET", server.URL+"/other", nil)
+	_, err = doRequest(http.DefaultClient, req)
+	assert.NotNil(t, err)
+}
+
+func TestRedactBody(t *testing.T) {
+	assert.Equal(t, "", redactBody(nil))
+	assert.Equal(t, `{"name":"x"}`, redactBody([]byte(`{"name":"x"}`)))
+	assert.Equal(t, `{"access_token":"REDACTED","name":"x"}`, redactBody([]byte(`{"name":"x","access_token":"t"}`)))
+	assert.Equal(t, "code=REDACTED&grant_type=authorization_code", redactBody([]byte("grant_type=authorization_code&code=abc")))
+	assert.Equal(t, "plain text", redactBody([]byte("plain text")))
+}
+
+func TestRedactHeaders(t *testing.T) {
+	header := http.Header{
+		"Authorization": {"Bearer t"},
+		"X-Api-Key":     {"k"},
+		"X-Team":        {"observability"},
+		"Layer-Type":    {"TENANT"},
+	}
+	assert.Equal(t, http.Header{
+		"Authorization": {redactedValue},
+		"X-Api-Key":     {redactedValue},
+		"X-Team":        {"observability"},
+		"Layer-Type":    {"TENANT"},
+	}, redactHeaders(header, nil))
+
+	// the profile's default headers are redacted, whatever their names
+	assert.Equal(t, []string{redactedValue}, redactHeaders(header, []string{"x-team"})["X-Team"])
+	assert.Equal(t, "k", header.Get("X-Api-Key")) // the original is not modified
+	assert.Nil(t, redactHeaders(nil, nil))
+}
@@ -0,0 +1,223 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/apex/log"
+
+	"github.com/cisco-open/fsoc/config"
+)
+
+// Client is a platform API client for a single access profile. Unlike the package-level functions,
+// which use the fsoc config file's current profile, a Client holds its own profile, HTTP client
+// and logger, so that it can be used by other Go programs. Its methods return errors rather than
+// terminating the process.
+//
+// A Client logs in as needed, using the profile's authentication method, and keeps the tokens it
+// obtains in memory; provide ClientOptions.SaveTokens to persist them. A Client is safe for
+// concurrent use.
+type Client struct {
+	cfgMutex      sync.Mutex
+	cfg           *config.Context
+	loginMutex    *sync.Mutex
+	httpClient    *http.Client
+	saveTokens    func(cfg *config.Context) error
+	logger        log.Interface
+	curlify       bool
+	spinner       bool
+	goContext     context.Context
+	subsystem     string
+	sharedProfile bool // the profile is kept in the config file, which other fsoc processes may update
+}
+
+// ClientOptions customize a Client. All fields are optional.
+type ClientOptions struct {
+	HTTPClient *http.Client                    // HTTP client for all requests; if nil, one is created per the profile's transport settings
+	SaveTokens func(cfg *config.Context) error // called with the updated profile after a login obtains new tokens
+	Logger     log.Interface                   // logger for the client's activity; if nil, the apex/log default logger is used
+	Curl       bool                            // log the curl equivalent of each request
+	Spinner    bool                            // display a progress spinner on stderr while requests are in progress
+	Context    context.Context                 // Go context for calls that don't provide their own in Options; if nil, context.Background()
+	Subsystem  string                          // subsystem (e.g., "knowledge") whose default headers from the profile are added to requests, in addition to the global ones
+}
+
+// NewClient creates a platform API client for the given profile. The client uses a copy of
+// the profile; use Config to obtain the profile with any tokens obtained by logging in.
+func NewClient(cfg *config.Context, options *ClientOptions) (*Client, error) {
+	if cfg == nil {
+		return nil, errors.New("a profile (config.Context) is required to create a platform API client")
+	}
+	if options == nil {
+		options = &ClientOptions{}
+	}
+	profile := *cfg
+	c := &Client{
+		cfg:        &profile,
+		loginMutex: &sync.Mutex{},
+		httpClient: options.HTTPClient,
+		saveTokens: options.SaveTokens,
+		logger:     options.Logger,
+		curlify:    options.Curl,
+		spinner:    options.Spinner,
+		goContext:  options.Context,
+		subsystem:  options.Subsystem,
+	}
+	if c.logger == nil {
+		c.logger = log.Log
+	}
+	if c.goContext == nil {
+		c.goContext = context.Background()
+	}
+	return c, nil
+}
+
+// NewConfigClient creates a platform API client for a profile of the fsoc config file, like the one
+// the package-level functions use for the current profile: tokens obtained by logging in are saved
+// to the config file and logins are serialized with those of the other profiles. The client displays
+// no progress spinner and logs the profile's name, so that clients for several profiles can be used
+// concurrently, e.g., to run a command for multiple profiles (see WithClient).
+func NewConfigClient(cfg *config.Context, goContext context.Context) *Client {
+	c := configClient(cfg)
+	c.logger = log.WithField("profile", cfg.Name)
+	c.spinner = false
+	if goContext != nil {
+		c.goContext = goContext
+	}
+	return c
+}
+
+// configClient returns a client for a profile of the fsoc config file
+func configClient(cfg *config.Context) *Client {
+	return &Client{
+		cfg:           cfg,
+		loginMutex:    &loginMutex,
+		saveTokens:    config.UpsertContext,
+		logger:        log.Log,
+		curlify:       FlagCurlifyRequests,
+		spinner:       true,
+		goContext:     defaultGoContext,
+		subsystem:     defaultSubsystem,
+		sharedProfile: true,
+	}
+}
+
+// defaultClient returns the client used by the package-level functions: the client bound to the
+// call's Go context, if any (see WithClient), or else a client for the current profile of the fsoc
+// config file. Tokens obtained by logging in are saved to the config file.
+func defaultClient(goContext context.Context) (*Client, error) {
+	if client := ClientFromContext(goContext); client != nil {
+		return client, nil
+	}
+	cfg := config.GetCurrentContext()
+	if cfg == nil {
+		return nil, errors.New("missing context; use 'fsoc config set' to configure your context")
+	}
+	return configClient(cfg), nil
+}
+
+type clientContextKey struct{}
+
+// WithClient returns a copy of the Go context that carries the client. The package-level functions
+// called with such a context (see Options.Context) use the client instead of the current profile.
+func WithClient(ctx context.Context, c *Client) context.Context {
+	return context.WithValue(ctx, clientContextKey{}, c)
+}
+
+// ClientFromContext returns the client carried by the Go context (see WithClient), or nil if none
+func ClientFromContext(ctx context.Context) *Client {
+	if ctx == nil {
+		return nil
+	}
+	c, _ := ctx.Value(clientContextKey{}).(*Client)
+	return c
+}
+
+// Config returns a copy of the client's profile, including the tokens obtained by logging in
+func (c *Client) Config() *config.Context {
+	c.cfgMutex.Lock()
+	defer c.cfgMutex.Unlock()
+	profile := *c.cfg
+	return &profile
+}
+
+func (c *Client) setConfig(cfg *config.Context) {
+	c.cfgMutex.Lock()
+	defer c.cfgMutex.Unlock()
+	profile := *cfg
+	c.cfg = &profile
+}
+
+// Login logs in using the profile's authentication method, obtaining new tokens
+func (c *Client) Login(ctx context.Context) error {
+	callCtx := c.newCallContext(ctx)
+	defer callCtx.stopSpinner(false) // ensure not running when returning
+	return login(callCtx)
+}
+
+// JSONGet performs a GET request and parses the response as JSON
+func (c *Client) JSONGet(path string, out any, options *Options) error {
+	return c.request("GET", path, nil, out, options)
+}
+
+// JSONDelete performs a DELETE request and parses the response as JSON
+func (c *Client) JSONDelete(path string, out any, options *Options) error {
+	return c.request("DELETE", path, nil, out, options)
+}
+
+// JSONPost performs a POST request with JSON command and response
+func (c *Client) JSONPost(path string, body any, out any, options *Options) error {
+	return c.request("POST", path, body, out, options)
+}
+
+// HTTPPost performs a POST request with HTTP command and response - Accept and Content-Type headers are provided by the caller
+func (c *Client) HTTPPost(path string, body []byte, out any, options *Options) error {
+	return c.request("POST", path, body, out, options)
+}
+
+// HTTPGet performs a GET request with HTTP command and response - Accept and Content-Type headers are provided by the caller
+func (c *Client) HTTPGet(path string, out any, options *Options) error {
+	return c.request("GET", path, nil, out, options)
+}
+
+// JSONPut performs a PUT request with JSON command and response
+func (c *Client) JSONPut(path string, body any, out any, options *Options) error {
+	return c.request("PUT", path, body, out, options)
+}
+
+// JSONPatch performs a PATCH request and parses the response as JSON
+func (c *Client) JSONPatch(path string, body any, out any, options *Options) error {
+	return c.request("PATCH", path, body, out, options)
+}
+
+// JSONRequest performs an HTTP request and parses the response as JSON, allowing
+// the http method to be specified
+func (c *Client) JSONRequest(method string, path string, body any, out any, options *Options) error {
+	return c.request(method, path, body, out, options)
+}
+
+// ClientGetCollection is the equivalent of JSONGetCollection for the given client
+func ClientGetCollection[T any](c *Client, path string, out *CollectionResult[T], options *Options) error {
+	return getCollection[T](c, path, out, options)
+}
+
+// ClientGetCollectionPages is the equivalent of JSONGetCollectionPages for the given client
+func ClientGetCollectionPages[T any](c *Client, path string, pageFunc func(items []T) error, options *Options) error {
+	return getCollectionPages[T](c, path, pageFunc, options)
+}
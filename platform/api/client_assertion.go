@@ -0,0 +1,209 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// clientAssertionType is the client assertion type for JWT client authentication (RFC 7523, section 2.2)
+const clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// clientAssertionLifetime is how long a signed client assertion is valid
+const clientAssertionLifetime = 5 * time.Minute
+
+// privateKeyStruct is a private key of a secretless service principal, as provided in the credentials
+// file. The key is provided either inline, as PEM, or in a separate PEM file; a relative file path is
+// relative to the credentials file. Keys can be rotated by adding the new key with a "Not Before" time
+// ahead of time, or by replacing the key file; the profile doesn't need to be updated.
+type privateKeyStruct struct {
+	KeyID          string     `json:"Key ID"`
+	PrivateKey     string     `json:"Private Key,omitempty"`      // PEM (PKCS#8, PKCS#1 or SEC 1)
+	PrivateKeyFile string     `json:"Private Key File,omitempty"` // path to a PEM file
+	NotBefore      *time.Time `json:"Not Before,omitempty"`       // the key is not used before this time (RFC 3339)
+}
+
+// signingKey is a parsed private key, ready for signing client assertions
+type signingKey struct {
+	keyID     string
+	signer    crypto.Signer
+	notBefore time.Time
+}
+
+// hasPrivateKeys returns true if the credentials authenticate with a signed client assertion rather than a secret
+func (c *credentialsStruct) hasPrivateKeys() bool {
+	return c.KeyID != "" || c.PrivateKey != "" || c.PrivateKeyFile != "" || len(c.Keys) > 0
+}
+
+// signingKeys returns the credentials' private keys that are usable at the given time, newest first
+func (c *credentialsStruct) signingKeys(now time.Time) ([]signingKey, error) {
+	specs := c.Keys
+	if c.PrivateKey != "" || c.PrivateKeyFile != "" {
+		specs = append([]privateKeyStruct{{KeyID: c.KeyID, PrivateKey: c.PrivateKey, PrivateKeyFile: c.PrivateKeyFile}}, specs...)
+	}
+
+	keys := []signingKey{}
+	for i, spec := range specs {
+		if spec.NotBefore != nil && spec.NotBefore.After(now) {
+			continue // not yet active (pre-provisioned for rotation)
+		}
+		signer, err := loadPrivateKey(spec, c.baseDir)
+		if err != nil {
+			return nil, fmt.Errorf("private key #%d (key ID %q): %w", i+1, spec.KeyID, err)
+		}
+		key := signingKey{keyID: spec.KeyID, signer: signer}
+		if spec.NotBefore != nil {
+			key.notBefore = *spec.NotBefore
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no private key is active yet (check the keys' Not Before times)")
+	}
+
+	// prefer the most recently activated key; keys without Not Before are the oldest
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].notBefore.After(keys[j].notBefore)
+	})
+	return keys, nil
+}
+
+func loadPrivateKey(spec privateKeyStruct, baseDir string) (crypto.Signer, error) {
+	data := []byte(spec.PrivateKey)
+	if spec.PrivateKeyFile != "" {
+		path := spec.PrivateKeyFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key file: %w", err)
+		}
+	}
+	return parsePrivateKey(data)
+}
+
+// parsePrivateKey parses a PEM-encoded RSA or ECDSA (P-256) private key
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM-encoded private key found")
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, nil
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("unsupported elliptic curve %v, only P-256 is supported", k.Curve.Params().Name)
+		}
+		return k, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T, must be RSA or ECDSA P-256", key)
+	}
+}
+
+// signClientAssertion creates a JWT client assertion (RFC 7523) for the client, to be presented at the
+// token endpoint (the audience). RSA keys sign with RS256 and ECDSA keys with ES256.
+func signClientAssertion(key signingKey, clientID string, audience string, now time.Time) (string, error) {
+	var alg string
+	switch key.signer.(type) {
+	case *rsa.PrivateKey:
+		alg = "RS256"
+	case *ecdsa.PrivateKey:
+		alg = "ES256"
+	default:
+		return "", fmt.Errorf("(bug) unsupported signer %T", key.signer)
+	}
+
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+	header := map[string]any{"alg": alg, "typ": "JWT"}
+	if key.keyID != "" {
+		header["kid"] = key.keyID
+	}
+	claims := map[string]any{
+		"iss": clientID,
+		"sub": clientID,
+		"aud": audience,
+		"jti": hex.EncodeToString(jti),
+		"iat": now.Unix(),
+		"exp": now.Add(clientAssertionLifetime).Unix(),
+	}
+
+	headerJson, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJson, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJson) + "." + base64.RawURLEncoding.EncodeToString(claimsJson)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch k := key.signer.(type) {
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, digest[:])
+		if err == nil {
+			// JWS uses the fixed-size R || S encoding rather than ASN.1 (RFC 7518, section 3.4)
+			signature = make([]byte, 64)
+			r.FillBytes(signature[:32])
+			s.FillBytes(signature[32:])
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to sign the client assertion: %w", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,186 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco-open/fsoc/config"
+)
+
+func pemPKCS8(t *testing.T, key any) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+// decodeAssertion splits a signed JWT, returning its header, claims, signing input and signature
+func decodeAssertion(t *testing.T, jwt string) (map[string]any, map[string]any, []byte, []byte) {
+	parts := strings.Split(jwt, ".")
+	require.Len(t, parts, 3)
+	var header, claims map[string]any
+	for i, v := range []*map[string]any{&header, &claims} {
+		data, err := base64.RawURLEncoding.DecodeString(parts[i])
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, v))
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	return header, claims, []byte(parts[0] + "." + parts[1]), signature
+}
+
+func TestSignClientAssertion(t *testing.T) {
+	now := time.Now()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	// RSA
+	signer, err := parsePrivateKey([]byte(pemPKCS8(t, rsaKey)))
+	require.NoError(t, err)
+	jwt, err := signClientAssertion(signingKey{keyID: "rsa-1", signer: signer}, "client-1", "https://x/token", now)
+	require.NoError(t, err)
+	header, claims, input, signature := decodeAssertion(t, jwt)
+	assert.Equal(t, "RS256", header["alg"])
+	assert.Equal(t, "rsa-1", header["kid"])
+	assert.Equal(t, "client-1", claims["iss"])
+	assert.Equal(t, "client-1", claims["sub"])
+	assert.Equal(t, "https://x/token", claims["aud"])
+	assert.Equal(t, float64(now.Add(clientAssertionLifetime).Unix()), claims["exp"])
+	digest := sha256.Sum256(input)
+	assert.NoError(t, rsa.VerifyPKCS1v15(&rsaKey.PublicKey, crypto.SHA256, digest[:], signature))
+
+	// ECDSA (SEC 1 PEM)
+	der, err := x509.MarshalECPrivateKey(ecKey)
+	require.NoError(t, err)
+	signer, err = parsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+	require.NoError(t, err)
+	jwt, err = signClientAssertion(signingKey{keyID: "ec-1", signer: signer}, "client-1", "https://x/token", now)
+	require.NoError(t, err)
+	header, _, input, signature = decodeAssertion(t, jwt)
+	assert.Equal(t, "ES256", header["alg"])
+	require.Len(t, signature, 64)
+	digest = sha256.Sum256(input)
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	assert.True(t, ecdsa.Verify(&ecKey.PublicKey, digest[:], r, s))
+
+	// invalid key
+	_, err = parsePrivateKey([]byte("not a key"))
+	assert.Error(t, err)
+}
+
+func TestSigningKeysRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	newKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	futureKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "old.pem"), []byte(pemPKCS8(t, oldKey)), 0600))
+
+	now := time.Now()
+	activated := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+	creds := &credentialsStruct{
+		ClientID: "client-1",
+		Keys: []privateKeyStruct{
+			{KeyID: "old", PrivateKeyFile: "old.pem"},
+			{KeyID: "new", PrivateKey: pemPKCS8(t, newKey), NotBefore: &activated},
+			{KeyID: "future", PrivateKey: pemPKCS8(t, futureKey), NotBefore: &future},
+		},
+		baseDir: dir,
+	}
+	assert.True(t, creds.hasPrivateKeys())
+	keys, err := creds.signingKeys(now)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "new", keys[0].keyID)
+	assert.Equal(t, "old", keys[1].keyID)
+
+	assert.False(t, (&credentialsStruct{ClientID: "c", Secret: "s"}).hasPrivateKeys())
+}
+
+func TestServicePrincipalLoginWithClientAssertion(t *testing.T) {
+	oldKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	newKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	// token endpoint that has not yet registered the new key
+	kids := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "/auth/tenant-1/default/oauth2/token", r.URL.Path)
+		assert.Equal(t, clientAssertionType, r.PostForm.Get("client_assertion_type"))
+		_, _, hasBasic := r.BasicAuth()
+		assert.False(t, hasBasic)
+
+		header, claims, input, signature := decodeAssertion(t, r.PostForm.Get("client_assertion"))
+		kids = append(kids, header["kid"].(string))
+		assert.Equal(t, "http://"+r.Host+r.URL.Path, claims["aud"])
+		digest := sha256.Sum256(input)
+		valid := header["kid"] == "old" && ecdsa.Verify(&oldKey.PublicKey, digest[:],
+			new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:]))
+		if !valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"` + makeTestToken("client-1", time.Now().Add(time.Hour)) + `","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	activated := time.Now().Add(-time.Minute)
+	credsJson, err := json.Marshal(credentialsStruct{
+		TenantID: "tenant-1",
+		ClientID: "client-1",
+		Keys: []privateKeyStruct{
+			{KeyID: "old", PrivateKey: pemPKCS8(t, oldKey)},
+			{KeyID: "new", PrivateKey: pemPKCS8(t, newKey), NotBefore: &activated},
+		},
+	})
+	require.NoError(t, err)
+	file := filepath.Join(t.TempDir(), "principal.json")
+	require.NoError(t, os.WriteFile(file, credsJson, 0600))
+
+	cfg := &config.Context{AuthMethod: config.AuthMethodServicePrincipal, URL: server.URL, SecretFile: file}
+	require.NoError(t, servicePrincipalLogin(&callContext{goContext: context.Background(), cfg: cfg}))
+	assert.Equal(t, []string{"new", "old"}, kids)
+	assert.Equal(t, "client-1", cfg.User)
+	assert.Equal(t, "tenant-1", cfg.Tenant)
+
+	// transport failures are network errors, not authentication failures
+	server.Close()
+	err = servicePrincipalLogin(&callContext{goContext: context.Background(), cfg: cfg})
+	assert.Equal(t, ErrorKindNetwork, ErrorKindOf(&LoginError{Err: err}))
+}
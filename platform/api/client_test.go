@@ -0,0 +1,176 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/tracing"
+)
+
+func TestNewClient(t *testing.T) {
+	_, err := NewClient(nil, nil)
+	assert.NotNil(t, err)
+
+	cfg := &config.Context{Name: "sdk", AuthMethod: config.AuthMethodNone, URL: "https://mytenant.observe.appdynamics.com"}
+	client, err := NewClient(cfg, nil)
+	require.NoError(t, err)
+
+	// the client works on its own copy of the profile
+	cfg.URL = "https://changed.example.com"
+	assert.Equal(t, "https://mytenant.observe.appdynamics.com", client.Config().URL)
+	client.Config().Token = "modified"
+	assert.Equal(t, "", client.Config().Token)
+}
+
+func TestClientLoginAndRequest(t *testing.T) {
+	var logins atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth/tenant-1/default/oauth2/token":
+			logins.Add(1)
+			_, _ = w.Write([]byte(`{"access_token":"token-1","expires_in":3600}`))
+		case "/knowledge-store/v1/objects/test:item":
+			assert.Equal(t, "Bearer token-1", r.Header.Get("Authorization"))
+			_, _ = w.Write([]byte(`{"items":[{"id":"a"},{"id":"b"}],"total":2}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	credsJson, err := json.Marshal(credentialsStruct{TenantID: "tenant-1", ClientID: "client-1", Secret: "secret"})
+	require.NoError(t, err)
+	file := filepath.Join(t.TempDir(), "principal.json")
+	require.NoError(t, os.WriteFile(file, credsJson, 0600))
+
+	var saved []*config.Context
+	var requests atomic.Int32
+	httpClient := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requests.Add(1)
+		return http.DefaultTransport.RoundTrip(req)
+	})}
+	cfg := &config.Context{Name: "sdk", AuthMethod: config.AuthMethodServicePrincipal, URL: server.URL, SecretFile: file}
+	client, err := NewClient(cfg, &ClientOptions{
+		HTTPClient: httpClient,
+		SaveTokens: func(cfg *config.Context) error {
+			saved = append(saved, cfg)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+
+	var result CollectionResult[map[string]any]
+	require.NoError(t, ClientGetCollection[map[string]any](client, "knowledge-store/v1/objects/test:item", &result, nil))
+	assert.Equal(t, 2, len(result.Items))
+	require.NoError(t, client.JSONGet("knowledge-store/v1/objects/test:item", &result, nil))
+
+	assert.Equal(t, int32(1), logins.Load()) // the token obtained by the first call is reused
+	assert.Equal(t, int32(3), requests.Load())
+	assert.Equal(t, "token-1", client.Config().Token)
+	assert.Equal(t, "tenant-1", client.Config().Tenant)
+	assert.NotEqual(t, "", client.Config().TokenExpiry)
+	require.Equal(t, 1, len(saved))
+	assert.Equal(t, "token-1", saved[0].Token)
+	assert.Equal(t, "", cfg.Token) // the caller's profile is not modified
+
+	// errors are returned rather than terminating the process
+	err = client.JSONGet("knowledge-store/v1/objects/missing", &result, nil)
+	assert.Equal(t, ErrorKindNotFound, ErrorKindOf(err))
+}
+
+func TestClientErrors(t *testing.T) {
+	client, err := NewClient(&config.Context{AuthMethod: config.AuthMethodNone, URL: "://bad-url"}, nil)
+	require.NoError(t, err)
+	assert.NotNil(t, client.JSONGet("some/path", nil, nil))
+
+	client, err = NewClient(&config.Context{AuthMethod: "unknown", URL: "https://mytenant.example.com"}, nil)
+	require.NoError(t, err)
+	assert.NotNil(t, client.Login(context.Background()))
+
+	// unreachable server
+	client, err = NewClient(&config.Context{AuthMethod: config.AuthMethodNone, URL: "http://127.0.0.1:1", Token: "t"}, nil)
+	require.NoError(t, err)
+	start := time.Now()
+	err = client.JSONGet("some/path", nil, nil)
+	assert.Equal(t, ErrorKindNetwork, ErrorKindOf(err))
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
+
+func TestClientTracing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Regexp(t, "^00-[0-9a-f]{32}-[0-9a-f]{16}-01$", r.Header.Get("traceparent"))
+		w.Header().Set("Traceresponse", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		_, _ = w.Write([]byte(`{"items":[{"id":"a"}],"total":1}`))
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "traces.json")
+	require.NoError(t, tracing.Setup(tracing.Options{Exporter: tracing.ExporterFile, File: file}))
+	defer func() { _ = tracing.Shutdown() }()
+
+	client, err := NewClient(&config.Context{Name: "sdk", AuthMethod: config.AuthMethodNone, URL: server.URL}, nil)
+	require.NoError(t, err)
+	var result CollectionResult[map[string]any]
+	require.NoError(t, ClientGetCollection[map[string]any](client, "knowledge-store/v1/objects/test:item?max=1", &result, nil))
+	require.NoError(t, tracing.Shutdown())
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	var request struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					Name         string           `json:"name"`
+					SpanID       string           `json:"spanId"`
+					ParentSpanID string           `json:"parentSpanId"`
+					Links        []map[string]any `json:"links"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	require.NoError(t, json.Unmarshal(data, &request))
+	parents := map[string]string{}
+	ids := map[string]string{}
+	for _, span := range request.ResourceSpans[0].ScopeSpans[0].Spans {
+		ids[span.Name] = span.SpanID
+		parents[span.Name] = span.ParentSpanID
+		if span.Name == "HTTP GET" {
+			assert.Equal(t, 1, len(span.Links)) // the platform's trace
+		}
+	}
+	assert.Equal(t, ids["GET collection knowledge-store/v1/objects/test:item"], parents["page 1"])
+	assert.Equal(t, ids["page 1"], parents["GET knowledge-store/v1/objects/test:item"])
+	assert.Equal(t, ids["GET knowledge-store/v1/objects/test:item"], parents["login"])
+	assert.Equal(t, ids["GET knowledge-store/v1/objects/test:item"], parents["HTTP GET"])
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -17,12 +17,14 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"strings"
 
-	"github.com/apex/log"
 	"github.com/peterhellberg/link"
+
+	"github.com/cisco-open/fsoc/tracing"
 )
 
 const (
@@ -30,35 +32,100 @@ const (
 	nextRelName    = "next"
 )
 
+// ErrStopIteration can be returned by a JSONGetCollectionPages page function to stop
+// the iteration early without failing
+var ErrStopIteration = errors.New("stop iteration")
+
 // JSONGetCollection performs a GET request and parses the response as JSON,
 // handling pagination per https://www.rfc-editor.org/rfc/rfc5988,
 // https://developer.cisco.com/api-guidelines/#rest-style/API.REST.STYLE.25 and
 // https://developer.cisco.com/api-guidelines/#rest-style/API.REST.STYLE.24
-func JSONGetCollection[T any](path string, out *CollectionResult[T], options *Options) (err error) {
+// Throttled and transiently failed page requests are retried individually (see retry.go), so
+// a transient failure resumes the iteration from the failed page rather than from the start.
+// If a page other than the first one fails, the items retrieved so far remain in out and
+// a *PartialCollectionError is returned.
+func JSONGetCollection[T any](path string, out *CollectionResult[T], options *Options) error {
+	client, err := defaultClient(options.goContext())
+	if err != nil {
+		return err
+	}
+	return getCollection[T](client, path, out, options)
+}
+
+func getCollection[T any](c *Client, path string, out *CollectionResult[T], options *Options) (err error) {
+	err = getCollectionPages[T](c, path, func(items []T) error {
+		// handle case where out.Items is uninitialized (nil) and items is an initalized but empty slice
+		// append results in a nil slice instead of an empty slice in this case
+		if out.Items == nil && items != nil {
+			out.Items = items
+		} else {
+			out.Items = append(out.Items, items...)
+		}
+		return nil
+	}, options)
+	out.Total = len(out.Items)
+	return err
+}
 
+// JSONGetCollectionPages performs a GET request for a collection and calls pageFunc with the items of
+// each page as soon as the page is retrieved, without accumulating the items of the whole collection.
+// The iteration stops when all pages are processed, when pageFunc returns an error or when a page
+// fails to be retrieved. If pageFunc returns ErrStopIteration, the iteration stops and nil is returned;
+// any other error returned by pageFunc is returned as is. If a page other than the first one fails,
+// the returned error is a *PartialCollectionError, indicating that the items of the previous pages
+// have already been processed. See JSONGetCollection for references to the pagination standards.
+func JSONGetCollectionPages[T any](path string, pageFunc func(items []T) error, options *Options) error {
+	client, err := defaultClient(options.goContext())
+	if err != nil {
+		return err
+	}
+	return getCollectionPages[T](client, path, pageFunc, options)
+}
+
+func getCollectionPages[T any](c *Client, path string, pageFunc func(items []T) error, options *Options) (err error) {
 	subOptions := Options{}
 	if options != nil {
 		subOptions = *options // shallow copy
 	}
 
-	var pageNo, pageItemsCount, pageTotalCount int
+	// trace the collection, with a child span for each page
+	goContext := subOptions.Context
+	if goContext == nil {
+		goContext = c.goContext
+	}
+	collectionPath, _, _ := strings.Cut(path, "?")
+	goContext, span := tracing.Start(goContext, "GET collection "+collectionPath, tracing.SpanKindInternal, tracing.Attributes{"url.path": collectionPath})
+	var pageNo, itemCount, pageItemsCount, pageTotalCount int
+	defer func() {
+		span.SetAttributes(tracing.Attributes{"fsoc.collection.pages": pageNo + 1, "fsoc.collection.items": itemCount})
+		span.SetError(err)
+		span.End()
+	}()
+
 	for pageNo = 0; true; pageNo += 1 {
 		var page CollectionResult[T]
 		// request collection
-		err := httpRequest("GET", path, nil, &page, &subOptions)
+		pageContext, pageSpan := tracing.Start(goContext, fmt.Sprintf("page %v", pageNo+1), tracing.SpanKindInternal, tracing.Attributes{"fsoc.collection.page": pageNo + 1})
+		subOptions.Context = pageContext
+		err := c.request("GET", path, nil, &page, &subOptions)
+		pageSpan.SetAttributes(tracing.Attributes{"fsoc.collection.page_items": len(page.Items)})
+		pageSpan.SetError(err)
+		pageSpan.End()
 		if err != nil {
 			if pageNo > 0 {
-				return fmt.Errorf("Error retrieving non-first page #%v in collection at %q: %v. All data discarded", pageNo+1, path, err)
+				return &PartialCollectionError{Path: path, PageNo: pageNo + 1, ItemCount: itemCount, Err: err}
 			}
 			return err
 		}
 
-		// handle case where out.Items is uninitialized (nil) and page.Items is an initalized but empty slice
-		// append results in a nil slice instead of an empty slice in this case
-		if out.Items == nil && page.Items != nil {
-			out.Items = page.Items
-		} else {
-			out.Items = append(out.Items, page.Items...)
+		// process the page's items
+		itemCount += len(page.Items)
+		if err := pageFunc(page.Items); err != nil {
+			if errors.Is(err, ErrStopIteration) {
+				c.logger.Infof("Collection iteration at %q stopped after page #%v, %v items", path, pageNo+1, itemCount)
+				return nil
+			}
+			return err
 		}
 
 		// break if no more pages (no response headers, no links or no next link)
@@ -76,7 +143,7 @@ func JSONGetCollection[T any](path string, out *CollectionResult[T], options *Op
 
 		// compute path to the next page, working around incomplete paths usually returned by APIs
 		// This is done by keeping the original path up to the query string and just replacing the query string
-		log.Infof("Collection page #%v at %q returned %v items and indicated that more are available at %q for a total of %v", pageNo+1, path, len(page.Items), next, page.Total)
+		c.logger.Infof("Collection page #%v at %q returned %v items and indicated that more are available at %q for a total of %v", pageNo+1, path, len(page.Items), next, page.Total)
 		nextUrl, err := url.Parse(next.String())
 		if err != nil {
 			return fmt.Errorf("failed to parse collection iterator link(s) %v: %v ", links, err)
@@ -91,11 +158,10 @@ func JSONGetCollection[T any](path string, out *CollectionResult[T], options *Op
 		pageItemsCount = len(page.Items)
 		pageTotalCount = page.Total
 	}
-	log.Infof("Collection page #%v at %q returned %v items (last page)", pageNo+1, path, pageItemsCount)
+	c.logger.Infof("Collection page #%v at %q returned %v items (last page)", pageNo+1, path, pageItemsCount)
 
-	out.Total = len(out.Items)
-	if out.Total != pageTotalCount {
-		log.Warnf("Collection at %q returned %v items vs. expected %v items", path, out.Total, pageTotalCount)
+	if itemCount != pageTotalCount {
+		c.logger.Warnf("Collection at %q returned %v items vs. expected %v items", path, itemCount, pageTotalCount)
 	}
 
 	return nil
@@ -0,0 +1,133 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco-open/fsoc/config"
+)
+
+// newCollectionServer serves a collection of pages of 2 items each at /items; the page numbered
+// failPage (if any) fails with HTTP status 500
+func newCollectionServer(t *testing.T, pages int, failPage int) (*httptest.Server, *[]int) {
+	requested := []int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/items" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		page := 1
+		if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+			page, _ = strconv.Atoi(cursor)
+		}
+		requested = append(requested, page)
+		if page == failPage {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if page < pages {
+			w.Header().Set("Link", fmt.Sprintf(`</items?cursor=%d>; rel="next"`, page+1))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"items":["%d-a","%d-b"],"total":%d}`, page, page, 2*pages)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requested
+}
+
+func useTestProfile(t *testing.T, url string) {
+	noRetries := 0
+	config.SetEphemeralContext(&config.Context{
+		Name:         "test",
+		AuthMethod:   config.AuthMethodNone,
+		URL:          url,
+		RetryOptions: config.RetryOptions{MaxRetries: &noRetries},
+	})
+	t.Cleanup(func() { config.SetEphemeralContext(nil) })
+}
+
+func TestJSONGetCollectionPages(t *testing.T) {
+	server, requested := newCollectionServer(t, 3, 0)
+	useTestProfile(t, server.URL)
+
+	// all pages, processed one by one
+	pages := [][]string{}
+	err := JSONGetCollectionPages[string]("items", func(items []string) error {
+		pages = append(pages, items)
+		return nil
+	}, nil)
+	require.Nil(t, err)
+	assert.Equal(t, [][]string{{"1-a", "1-b"}, {"2-a", "2-b"}, {"3-a", "3-b"}}, pages)
+	assert.Equal(t, []int{1, 2, 3}, *requested)
+
+	// the whole collection
+	var result CollectionResult[string]
+	require.Nil(t, JSONGetCollection[string]("items", &result, nil))
+	assert.Equal(t, 6, result.Total)
+	assert.Equal(t, []string{"1-a", "1-b", "2-a", "2-b", "3-a", "3-b"}, result.Items)
+}
+
+func TestJSONGetCollectionPagesStop(t *testing.T) {
+	server, requested := newCollectionServer(t, 3, 0)
+	useTestProfile(t, server.URL)
+
+	// stopping early is not a failure and no further pages are requested
+	count := 0
+	err := JSONGetCollectionPages[string]("items", func(items []string) error {
+		count += len(items)
+		return ErrStopIteration
+	}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, []int{1}, *requested)
+
+	// other errors are returned as is
+	pageErr := errors.New("cannot process page")
+	err = JSONGetCollectionPages[string]("items", func(items []string) error {
+		return pageErr
+	}, nil)
+	assert.Equal(t, pageErr, err)
+}
+
+func TestJSONGetCollectionPartial(t *testing.T) {
+	server, _ := newCollectionServer(t, 4, 3)
+	useTestProfile(t, server.URL)
+
+	// a failure on a later page returns the items retrieved so far, with a partial collection error
+	var result CollectionResult[string]
+	err := JSONGetCollection[string]("items", &result, nil)
+	var partialErr *PartialCollectionError
+	require.True(t, errors.As(err, &partialErr))
+	assert.Equal(t, 3, partialErr.PageNo)
+	assert.Equal(t, 4, partialErr.ItemCount)
+	assert.Equal(t, ErrorKindServer, ErrorKindOf(err))
+	assert.Equal(t, []string{"1-a", "1-b", "2-a", "2-b"}, result.Items)
+
+	// a failure on the first page is not partial
+	server, _ = newCollectionServer(t, 4, 1)
+	useTestProfile(t, server.URL)
+	err = JSONGetCollectionPages[string]("items", func(items []string) error { return nil }, nil)
+	require.NotNil(t, err)
+	assert.False(t, errors.As(err, &partialErr))
+}
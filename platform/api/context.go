@@ -31,32 +31,88 @@ type callContext struct {
 	goContext context.Context
 	cfg       *config.Context
 	spinner   *spinner.Spinner
+	client    *Client // nil only in tests
 }
 
+// defaultGoContext is the Go context used for API calls that don't provide their own, see SetDefaultContext
+var defaultGoContext = context.Background()
+
+// defaultSubsystem is the subsystem making the API calls, see SetDefaultSubsystem
+var defaultSubsystem string
+
 var statusChar = map[bool]string{
 	false: color.RedString("\u00d7"),   // cross mark
 	true:  color.GreenString("\u2713"), // checkmark
 }
 
-func newCallContext() *callContext {
-	// get current config context
-	cfg := config.GetCurrentContext()
-	if cfg == nil {
-		log.Fatal("Missing context; use 'fsoc config set' to configure your context")
-		panic("unreachable") // keep golintci happy (until it recognizes apex/log fatals)
+// SetDefaultContext sets the Go context to be used by platform API calls and logins, unless a call
+// provides its own context in Options. Cancelling the context (e.g., on interrupt or timeout) aborts
+// any calls in progress.
+func SetDefaultContext(ctx context.Context) {
+	if ctx == nil {
+		ctx = context.Background()
 	}
-	log.WithFields(log.Fields{"context": cfg.Name, "url": cfg.URL, "tenant": cfg.Tenant}).Info("Using context")
+	defaultGoContext = ctx
+}
+
+// SetDefaultSubsystem sets the name of the subsystem (e.g., knowledge or uql) on whose behalf platform API
+// calls are made, so that the profile's default headers for that subsystem are added to the calls
+func SetDefaultSubsystem(name string) {
+	defaultSubsystem = name
+}
+
+// DefaultContext returns the Go context used by platform API calls that don't provide their own, see SetDefaultContext
+func DefaultContext() context.Context {
+	return defaultGoContext
+}
+
+// newCallContext prepares the context for a call (or login) with the client's profile
+func (c *Client) newCallContext(goContext context.Context) *callContext {
+	cfg := c.Config()
+	c.logger.WithFields(log.Fields{"context": cfg.Name, "url": cfg.URL, "tenant": cfg.Tenant}).Info("Using context")
 
 	// prepare call context
+	if goContext == nil {
+		goContext = c.goContext
+	}
 	callCtx := callContext{
-		context.Background(),
-		cfg,
-		spinner.New(spinner.CharSets[21], 50*time.Millisecond, spinner.WithWriterFile(os.Stderr)),
+		goContext: goContext,
+		cfg:       cfg,
+		client:    c,
+	}
+	if c.spinner {
+		callCtx.spinner = spinner.New(spinner.CharSets[21], 50*time.Millisecond, spinner.WithWriterFile(os.Stderr))
 	}
 
 	return &callCtx
 }
 
+// newDefaultCallContext prepares the context for a call with the current profile, see defaultClient
+func newDefaultCallContext(goContext context.Context) (*callContext, error) {
+	client, err := defaultClient(goContext)
+	if err != nil {
+		return nil, err
+	}
+	return client.newCallContext(goContext), nil
+}
+
+// subsystem returns the name of the subsystem making the call, which selects the profile's
+// subsystem-specific default headers
+func (c *callContext) subsystem() string {
+	if c.client == nil {
+		return ""
+	}
+	return c.client.subsystem
+}
+
+// logger returns the logger for the call
+func (c *callContext) logger() log.Interface {
+	if c.client == nil {
+		return log.Log
+	}
+	return c.client.logger
+}
+
 func (c *callContext) startSpinner(msg string) {
 	if c.spinner != nil {
 		if msg != "" {
@@ -14,6 +14,8 @@
 
 package api
 
+import "fmt"
+
 type HttpStatusError struct {
 	Message    string // used only if WrappedError is nil
 	StatusCode int
@@ -30,3 +32,49 @@ func (e *HttpStatusError) Error() string {
 func (e *HttpStatusError) Unwrap() error {
 	return e.WrappedErr
 }
+
+// PartialCollectionError indicates that retrieving a collection failed after one
+// or more pages were successfully retrieved (and processed)
+type PartialCollectionError struct {
+	Path      string // path of the page that failed
+	PageNo    int    // 1-based number of the page that failed
+	ItemCount int    // number of items retrieved before the failure
+	Err       error
+}
+
+func (e *PartialCollectionError) Error() string {
+	return fmt.Sprintf("error retrieving non-first page #%v in collection at %q: %v; %v item(s) retrieved before the failure", e.PageNo, e.Path, e.Err, e.ItemCount)
+}
+
+func (e *PartialCollectionError) Unwrap() error {
+	return e.Err
+}
+
+// LoginError indicates that logging in (obtaining or refreshing an access token) failed
+type LoginError struct {
+	Err error
+}
+
+func (e *LoginError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LoginError) Unwrap() error {
+	return e.Err
+}
+
+// NetworkError indicates that a request could not be sent or its response could not be
+// received, e.g., because the server cannot be resolved or reached or the connection was broken
+type NetworkError struct {
+	Method string
+	URL    string
+	Err    error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("%v request to %q failed: %v", e.Method, e.URL, e.Err)
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,176 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// ErrorKind classifies the cause of a failure, so that callers (and scripts, via the process
+// exit code) can react to it without parsing error messages
+type ErrorKind string
+
+// Error kinds and the process exit codes they map to (see ExitCode). The exit codes are
+// part of fsoc's command line interface and must not change once released.
+const (
+	ErrorKindGeneral        ErrorKind = "error"          // exit code 1: any other failure
+	ErrorKindUsage          ErrorKind = "usage"          // exit code 2: invalid command line (command, flags or arguments)
+	ErrorKindAuthentication ErrorKind = "authentication" // exit code 3: login failed or credentials rejected (401)
+	ErrorKindPermission     ErrorKind = "permission"     // exit code 4: access denied (403)
+	ErrorKindNotFound       ErrorKind = "not_found"      // exit code 5: object or resource not found (404, 410)
+	ErrorKindConflict       ErrorKind = "conflict"       // exit code 6: conflicting change or failed precondition (409, 412)
+	ErrorKindValidation     ErrorKind = "validation"     // exit code 7: request rejected as invalid (400, 405, 413, 415, 422)
+	ErrorKindThrottled      ErrorKind = "throttled"      // exit code 8: rate limited (429)
+	ErrorKindServer         ErrorKind = "server"         // exit code 9: server-side failure (5xx)
+	ErrorKindNetwork        ErrorKind = "network"        // exit code 10: server cannot be reached or the connection failed
+	ErrorKindTimeout        ErrorKind = "timeout"        // exit code 11: the command or request timed out
+	ErrorKindCancelled      ErrorKind = "cancelled"      // exit code 130: the command was interrupted
+)
+
+var exitCodes = map[ErrorKind]int{
+	ErrorKindGeneral:        1,
+	ErrorKindUsage:          2,
+	ErrorKindAuthentication: 3,
+	ErrorKindPermission:     4,
+	ErrorKindNotFound:       5,
+	ErrorKindConflict:       6,
+	ErrorKindValidation:     7,
+	ErrorKindThrottled:      8,
+	ErrorKindServer:         9,
+	ErrorKindNetwork:        10,
+	ErrorKindTimeout:        11,
+	ErrorKindCancelled:      130, // same as shells use for SIGINT
+}
+
+// ExitCode returns the process exit code for an error (0 if err is nil)
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	return exitCodes[ErrorKindOf(err)]
+}
+
+// ExitCodeForKind returns the process exit code for an error kind
+func ExitCodeForKind(kind ErrorKind) int {
+	if code, found := exitCodes[kind]; found {
+		return code
+	}
+	return exitCodes[ErrorKindGeneral]
+}
+
+// ErrorKindOf classifies an error by examining its chain. Cancellation, timeouts and network
+// failures take precedence, followed by login failures and, finally, the HTTP status code.
+func ErrorKindOf(err error) ErrorKind {
+	if errors.Is(err, context.Canceled) {
+		return ErrorKindCancelled
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorKindTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorKindTimeout
+	}
+	var networkErr *NetworkError
+	if errors.As(err, &networkErr) {
+		return ErrorKindNetwork
+	}
+	var loginErr *LoginError
+	if errors.As(err, &loginErr) {
+		return ErrorKindAuthentication
+	}
+	var statusErr *HttpStatusError
+	if errors.As(err, &statusErr) {
+		return errorKindForStatus(statusErr.StatusCode)
+	}
+	return ErrorKindGeneral
+}
+
+func errorKindForStatus(statusCode int) ErrorKind {
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return ErrorKindAuthentication
+	case http.StatusForbidden:
+		return ErrorKindPermission
+	case http.StatusNotFound, http.StatusGone:
+		return ErrorKindNotFound
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return ErrorKindConflict
+	case http.StatusBadRequest, http.StatusMethodNotAllowed, http.StatusRequestEntityTooLarge,
+		http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity:
+		return ErrorKindValidation
+	case http.StatusTooManyRequests:
+		return ErrorKindThrottled
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return ErrorKindTimeout
+	}
+	if statusCode/100 == 5 {
+		return ErrorKindServer
+	}
+	return ErrorKindGeneral
+}
+
+// ErrorReport is a machine-readable description of a failure, including the
+// Problem details (RFC 7807) returned by the platform, if any
+type ErrorReport struct {
+	Kind       ErrorKind      `json:"kind" yaml:"kind"`
+	ExitCode   int            `json:"exitCode" yaml:"exitCode"`
+	Message    string         `json:"message" yaml:"message"`
+	Status     int            `json:"status,omitempty" yaml:"status,omitempty"`
+	Type       string         `json:"type,omitempty" yaml:"type,omitempty"`
+	Title      string         `json:"title,omitempty" yaml:"title,omitempty"`
+	Detail     string         `json:"detail,omitempty" yaml:"detail,omitempty"`
+	Errors     any            `json:"errors,omitempty" yaml:"errors,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty" yaml:"extensions,omitempty"`
+}
+
+// NewErrorReport creates a report for an error, classifying it and extracting the Problem
+// details from it, if present
+func NewErrorReport(err error) *ErrorReport {
+	kind := ErrorKindOf(err)
+	report := &ErrorReport{
+		Kind:     kind,
+		ExitCode: ExitCodeForKind(kind),
+		Message:  err.Error(),
+	}
+
+	var statusErr *HttpStatusError
+	if errors.As(err, &statusErr) {
+		report.Status = statusErr.StatusCode
+	}
+	var problem *Problem
+	if errors.As(err, &problem) {
+		if problem.Status != 0 {
+			report.Status = problem.Status
+		}
+		report.Type = problem.Type
+		report.Title = problem.Title
+		report.Detail = problem.Detail
+		for key, value := range problem.Extensions {
+			if key == "errors" {
+				report.Errors = value
+				continue
+			}
+			if report.Extensions == nil {
+				report.Extensions = map[string]any{}
+			}
+			report.Extensions[key] = value
+		}
+	}
+	return report
+}
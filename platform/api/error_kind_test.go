@@ -0,0 +1,72 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorKindOf(t *testing.T) {
+	statusErr := func(code int) error {
+		return &HttpStatusError{Message: http.StatusText(code), StatusCode: code}
+	}
+	tests := []struct {
+		err      error
+		kind     ErrorKind
+		exitCode int
+	}{
+		{errors.New("boom"), ErrorKindGeneral, 1},
+		{statusErr(401), ErrorKindAuthentication, 3},
+		{statusErr(403), ErrorKindPermission, 4},
+		{fmt.Errorf("wrapped: %w", statusErr(404)), ErrorKindNotFound, 5},
+		{statusErr(409), ErrorKindConflict, 6},
+		{statusErr(422), ErrorKindValidation, 7},
+		{statusErr(429), ErrorKindThrottled, 8},
+		{statusErr(503), ErrorKindServer, 9},
+		{&NetworkError{Method: "GET", URL: "https://x", Err: errors.New("connection refused")}, ErrorKindNetwork, 10},
+		{&NetworkError{Method: "GET", URL: "https://x", Err: context.DeadlineExceeded}, ErrorKindTimeout, 11},
+		{fmt.Errorf("aborted: %w", context.Canceled), ErrorKindCancelled, 130},
+		{&LoginError{Err: statusErr(400)}, ErrorKindAuthentication, 3},
+		{&PartialCollectionError{Path: "/x", PageNo: 2, Err: statusErr(500)}, ErrorKindServer, 9},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.kind, ErrorKindOf(tt.err), tt.err.Error())
+		assert.Equal(t, tt.exitCode, ExitCode(tt.err), tt.err.Error())
+	}
+	assert.Equal(t, 0, ExitCode(nil))
+}
+
+func TestNewErrorReport(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusBadRequest}
+	body := []byte(`{"type":"urn:invalid","title":"Invalid object","detail":"name is required","errors":[{"field":"name"}],"traceId":"abc"}`)
+	err := fmt.Errorf("failed to create object: %w", parseIntoError(resp, body))
+
+	report := NewErrorReport(err)
+	assert.Equal(t, ErrorKindValidation, report.Kind)
+	assert.Equal(t, 7, report.ExitCode)
+	assert.Equal(t, 400, report.Status)
+	assert.Equal(t, "urn:invalid", report.Type)
+	assert.Equal(t, "Invalid object", report.Title)
+	assert.Equal(t, "name is required", report.Detail)
+	assert.Equal(t, []any{map[string]any{"field": "name"}}, report.Errors)
+	assert.Equal(t, map[string]any{"traceId": "abc"}, report.Extensions)
+	assert.Equal(t, err.Error(), report.Message)
+}
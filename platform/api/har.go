@@ -0,0 +1,454 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/apex/log"
+)
+
+// harVersion is the version of the HTTP Archive format (http://www.softwareishard.com/blog/har-12-spec/)
+const harVersion = "1.2"
+
+// HAR is an HTTP Archive, as written by the --har flag
+type HAR struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string      `json:"version"`
+	Creator harCreator  `json:"creator"`
+	Pages   []harPage   `json:"pages,omitempty"`
+	Entries []*harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harPage struct {
+	StartedDateTime string         `json:"startedDateTime"`
+	ID              string         `json:"id"`
+	Title           string         `json:"title"`
+	PageTimings     map[string]any `json:"pageTimings"`
+}
+
+type harEntry struct {
+	PageRef         string         `json:"pageref,omitempty"`
+	StartedDateTime string         `json:"startedDateTime"`
+	Time            float64        `json:"time"` // total elapsed time, in milliseconds
+	Request         harRequest     `json:"request"`
+	Response        harResponse    `json:"response"`
+	Cache           map[string]any `json:"cache"`
+	Timings         harTimings     `json:"timings"`
+	ServerIPAddress string         `json:"serverIPAddress,omitempty"`
+	Error           string         `json:"_error,omitempty"` // custom field: the request failed without a response
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"_encoding,omitempty"` // custom field: "base64" for binary bodies
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// harTimings are in milliseconds; -1 means that the phase does not apply (e.g., a reused connection)
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"` // incl. ssl
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// harState holds the HAR being captured, if any. Access is guarded by mu, as requests may be
+// executed concurrently
+var harState struct {
+	mu   sync.Mutex
+	path string
+	har  *HAR
+}
+
+// StartHAR starts capturing all platform API requests and their responses, incl. those made by the
+// login flows (e.g., tenant resolution and token requests), with their timings, headers and bodies,
+// into an HTTP Archive (HAR) file. The file is (re)written after each request, so that it is complete
+// even if the command fails. Credentials and tokens are redacted.
+func StartHAR(path string, fsocVersion string) error {
+	harState.mu.Lock()
+	defer harState.mu.Unlock()
+
+	harState.path = path
+	harState.har = newHAR(fsocVersion)
+
+	// create the file early, so that an invalid path is detected before any requests are made
+	return saveHAR()
+}
+
+func newHAR(fsocVersion string) *HAR {
+	return &HAR{Log: harLog{
+		Version: harVersion,
+		Creator: harCreator{Name: "fsoc", Version: fsocVersion},
+		Entries: []*harEntry{},
+	}}
+}
+
+func isCapturingHAR() bool {
+	harState.mu.Lock()
+	defer harState.mu.Unlock()
+	return harState.har != nil
+}
+
+// harTimer collects the timestamps of a request's phases
+type harTimer struct {
+	mu                       sync.Mutex
+	start, gotConn           time.Time
+	dnsStart, dnsDone        time.Time
+	connectStart, connectEnd time.Time
+	tlsStart, tlsDone        time.Time
+	wroteRequest, firstByte  time.Time
+	serverAddress            string
+}
+
+func (t *harTimer) mark(ts *time.Time) func() {
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if ts.IsZero() {
+			*ts = time.Now()
+		}
+	}
+}
+
+func (t *harTimer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mark(&t.gotConn)()
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if info.Conn != nil {
+				t.serverAddress = info.Conn.RemoteAddr().String()
+			}
+		},
+		DNSStart:             func(httptrace.DNSStartInfo) { t.mark(&t.dnsStart)() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { t.mark(&t.dnsDone)() },
+		ConnectStart:         func(string, string) { t.mark(&t.connectStart)() },
+		ConnectDone:          func(string, string, error) { t.mark(&t.connectEnd)() },
+		TLSHandshakeStart:    t.mark(&t.tlsStart),
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.mark(&t.tlsDone)() },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.mark(&t.wroteRequest)() },
+		GotFirstResponseByte: t.mark(&t.firstByte),
+	}
+}
+
+// timings computes the total time and the HAR timings of a request whose response was received
+// by end. If the connection phases were not observed (e.g., replayed responses), the whole time
+// is attributed to waiting for the response.
+func (t *harTimer) timings(end time.Time) (float64, harTimings) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ms := func(from, to time.Time) float64 {
+		if from.IsZero() || to.IsZero() || to.Before(from) {
+			return -1
+		}
+		return float64(to.Sub(from).Microseconds()) / 1000
+	}
+	nonNegative := func(v float64) float64 {
+		return max(v, 0)
+	}
+
+	total := nonNegative(ms(t.start, end))
+	if t.gotConn.IsZero() {
+		return total, harTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Wait: total}
+	}
+	connectEnd := t.connectEnd
+	if t.tlsDone.After(connectEnd) {
+		connectEnd = t.tlsDone // connect includes the TLS handshake
+	}
+	timings := harTimings{
+		DNS:     ms(t.dnsStart, t.dnsDone),
+		Connect: ms(t.connectStart, connectEnd),
+		SSL:     ms(t.tlsStart, t.tlsDone),
+		Send:    nonNegative(ms(t.gotConn, t.wroteRequest)),
+		Wait:    nonNegative(ms(t.wroteRequest, t.firstByte)),
+		Receive: nonNegative(ms(t.firstByte, end)),
+	}
+	// the time until the connection was available, net of the DNS lookup and connection setup
+	blocked := t.gotConn.Sub(t.start)
+	for _, setup := range [][2]time.Time{{t.dnsStart, t.dnsDone}, {t.connectStart, connectEnd}} {
+		if !setup[0].IsZero() && setup[1].After(setup[0]) {
+			blocked -= setup[1].Sub(setup[0])
+		}
+	}
+	timings.Blocked = nonNegative(float64(blocked.Microseconds()) / 1000)
+	return total, timings
+}
+
+// captureRequest executes the request, capturing it with its response into the HAR
+func captureRequest(client *http.Client, req *http.Request) (*http.Response, error) {
+	// capture the request body without consuming it
+	var reqBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody != nil {
+			if body, err := req.GetBody(); err == nil {
+				reqBody, _ = io.ReadAll(body)
+				body.Close()
+			}
+		} else {
+			var err error
+			reqBody, err = io.ReadAll(req.Body)
+			req.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read request body for the HAR file: %w", err)
+			}
+			req.Body = io.NopCloser(bytes.NewReader(reqBody))
+		}
+	}
+
+	timer := &harTimer{start: time.Now()}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), timer.clientTrace()))
+	entry := newHAREntry(req, reqBody, timer.start)
+
+	resp, err := executeRequest(client, req)
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		// capture the response body and replace it, so that the caller can still read it
+		var respBody []byte
+		respBody, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			entry.Error = err.Error()
+			err = fmt.Errorf("failed to read response body for the HAR file: %w", err)
+			resp = nil
+		} else {
+			resp.Body = io.NopCloser(bytes.NewReader(respBody))
+			entry.Response = newHARResponse(resp, respBody)
+		}
+	}
+	entry.Time, entry.Timings = timer.timings(time.Now())
+	entry.ServerIPAddress = timer.serverAddress
+
+	harState.mu.Lock()
+	defer harState.mu.Unlock()
+	if harState.har != nil {
+		// group the requests of clients bound to Go contexts (e.g., of a command run for multiple
+		// profiles) into a page per profile
+		if client := ClientFromContext(req.Context()); client != nil {
+			entry.PageRef = client.Config().Name
+			addHARPage(harState.har, entry)
+		}
+		harState.har.Log.Entries = append(harState.har.Log.Entries, entry)
+		if err := saveHAR(); err != nil {
+			log.Warnf("Failed to save the HAR file: %v", err)
+		}
+	}
+
+	return resp, err
+}
+
+// addHARPage adds the page of the entry to the HAR, unless it is already there; must be called with harState.mu held
+func addHARPage(har *HAR, entry *harEntry) {
+	if slices.ContainsFunc(har.Log.Pages, func(p harPage) bool { return p.ID == entry.PageRef }) {
+		return
+	}
+	har.Log.Pages = append(har.Log.Pages, harPage{
+		StartedDateTime: entry.StartedDateTime,
+		ID:              entry.PageRef,
+		Title:           "fsoc profile " + entry.PageRef,
+		PageTimings:     map[string]any{},
+	})
+}
+
+func newHAREntry(req *http.Request, body []byte, start time.Time) *harEntry {
+	reqURL := redactURL(req.URL)
+	entry := &harEntry{
+		StartedDateTime: start.Format(time.RFC3339Nano),
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         reqURL.String(),
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(req.Header, defaultHeaderNames(req.Context())),
+			QueryString: []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    len(body),
+		},
+		Response: harResponse{ // for requests that fail without a response
+			Cookies: []harNameValue{},
+			Headers: []harNameValue{},
+			Content: harContent{MimeType: "x-unknown"},
+		},
+		Cache: map[string]any{},
+	}
+	for name, values := range reqURL.Query() {
+		for _, value := range values {
+			entry.Request.QueryString = append(entry.Request.QueryString, harNameValue{Name: name, Value: value})
+		}
+	}
+	sortNameValues(entry.Request.QueryString)
+	if len(body) > 0 {
+		text, encoding := harText(body)
+		entry.Request.PostData = &harPostData{MimeType: req.Header.Get("Content-Type"), Text: text, Encoding: encoding}
+	}
+	return entry
+}
+
+func newHARResponse(resp *http.Response, body []byte) harResponse {
+	text, encoding := harText(body)
+	httpVersion := resp.Proto
+	if httpVersion == "" {
+		httpVersion = "HTTP/1.1"
+	}
+	return harResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: httpVersion,
+		Cookies:     []harNameValue{},
+		Headers:     harHeaders(resp.Header, nil),
+		Content: harContent{
+			Size:     len(body),
+			MimeType: resp.Header.Get("Content-Type"),
+			Text:     text,
+			Encoding: encoding,
+		},
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+}
+
+// harText returns a body as HAR text, redacting secrets in text bodies; binary bodies are base64-encoded
+func harText(body []byte) (string, string) {
+	if len(body) == 0 {
+		return "", ""
+	}
+	if !utf8.Valid(body) {
+		return base64.StdEncoding.EncodeToString(body), "base64"
+	}
+	return redactBody(body), ""
+}
+
+func harHeaders(header http.Header, defaultHeaders []string) []harNameValue {
+	out := []harNameValue{}
+	for name, values := range redactHeaders(header, defaultHeaders) {
+		for _, value := range values {
+			out = append(out, harNameValue{Name: name, Value: value})
+		}
+	}
+	sortNameValues(out)
+	return out
+}
+
+func sortNameValues(list []harNameValue) {
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+}
+
+// redactURL returns a copy of the URL with its user info and the values of sensitive query parameters redacted
+func redactURL(u *url.URL) *url.URL {
+	out := *u
+	if out.User != nil {
+		out.User = url.User(redactedValue)
+	}
+	query := out.Query()
+	redacted := false
+	for _, field := range redactedFields {
+		if query.Has(field) {
+			query.Set(field, redactedValue)
+			redacted = true
+		}
+	}
+	if redacted {
+		out.RawQuery = query.Encode()
+	}
+	return &out
+}
+
+// saveHAR writes the HAR being captured to its file; must be called with harState.mu held
+func saveHAR() error {
+	return writeHAR(harState.path, harState.har)
+}
+
+func writeHAR(path string, har *HAR) error {
+	data, err := json.MarshalIndent(har, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the HAR: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write the HAR file %q: %w", path, err)
+	}
+	return nil
+}
+
+// resetHAR stops any HAR capture (used by tests)
+func resetHAR() {
+	harState.mu.Lock()
+	defer harState.mu.Unlock()
+	harState.path = ""
+	harState.har = nil
+}
@@ -0,0 +1,174 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco-open/fsoc/config"
+)
+
+func readHAR(t *testing.T, path string) *HAR {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var har HAR
+	require.NoError(t, json.Unmarshal(data, &har))
+	return &har
+}
+
+func TestHARCapture(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/token":
+			assert.Equal(t, "grant_type=client_credentials&client_secret=top-secret", string(body)) // sent as is
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token":"new-token","expires_in":3600}`))
+		case "/download":
+			w.Header().Set("Content-Type", "application/zip")
+			_, _ = w.Write([]byte{0x50, 0x4b, 0x03, 0x04, 0xff, 0xfe})
+		}
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "session.har")
+	require.NoError(t, StartHAR(file, "1.2.3"))
+	defer resetHAR()
+
+	req, err := http.NewRequest("POST", server.URL+"/token?code=abc&state=xyz", strings.NewReader("grant_type=client_credentials&client_secret=top-secret"))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := doRequest(http.DefaultClient, req)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, `{"access_token":"new-token","expires_in":3600}`, string(body)) // the caller gets the original response
+
+	req, err = http.NewRequest("GET", server.URL+"/download", nil)
+	require.NoError(t, err)
+	_, err = doRequest(http.DefaultClient, req)
+	require.NoError(t, err)
+
+	req, err = http.NewRequest("GET", "http://127.0.0.1:1/unreachable", nil)
+	require.NoError(t, err)
+	_, err = doRequest(http.DefaultClient, req)
+	assert.NotNil(t, err)
+
+	har := readHAR(t, file)
+	assert.Equal(t, "1.2", har.Log.Version)
+	assert.Equal(t, "1.2.3", har.Log.Creator.Version)
+	require.Equal(t, 3, len(har.Log.Entries))
+
+	token := har.Log.Entries[0]
+	assert.Equal(t, "POST", token.Request.Method)
+	assert.Equal(t, server.URL+"/token?code=REDACTED&state=xyz", token.Request.URL)
+	assert.Contains(t, token.Request.QueryString, harNameValue{Name: "code", Value: "REDACTED"})
+	assert.Contains(t, token.Request.Headers, harNameValue{Name: "Authorization", Value: "REDACTED"})
+	assert.Equal(t, "client_secret=REDACTED&grant_type=client_credentials", token.Request.PostData.Text)
+	assert.Equal(t, 200, token.Response.Status)
+	assert.Equal(t, `{"access_token":"REDACTED","expires_in":3600}`, token.Response.Content.Text)
+	assert.Greater(t, token.Time, 0.0)
+	assert.GreaterOrEqual(t, token.Timings.Wait, 0.0)
+	assert.GreaterOrEqual(t, token.Timings.Blocked, 0.0)
+	assert.NotEqual(t, "", token.ServerIPAddress)
+	_, err = time.Parse(time.RFC3339Nano, token.StartedDateTime)
+	assert.NoError(t, err)
+
+	download := har.Log.Entries[1]
+	assert.Equal(t, "base64", download.Response.Content.Encoding)
+	assert.Equal(t, "UEsDBP/+", download.Response.Content.Text)
+	assert.Equal(t, 6, download.Response.Content.Size)
+
+	failed := har.Log.Entries[2]
+	assert.Equal(t, 0, failed.Response.Status)
+	assert.NotEqual(t, "", failed.Error)
+}
+
+func TestHARCaptureReplay(t *testing.T) {
+	dir := t.TempDir()
+	cassetteFile := filepath.Join(dir, "cassette.yaml")
+	require.NoError(t, os.WriteFile(cassetteFile, []byte(`
+interactions:
+    - request:
+        method: GET
+        url: https://mytenant.observe.appdynamics.com/some/path
+      response:
+        status: 200
+        body: '{"ok":true}'
+`), 0600))
+	require.NoError(t, StartReplay(cassetteFile))
+	defer resetCassette()
+	harFile := filepath.Join(dir, "session.har")
+	require.NoError(t, StartHAR(harFile, "1.2.3"))
+	defer resetHAR()
+
+	req, err := http.NewRequest("GET", "https://mytenant.observe.appdynamics.com/some/path", nil)
+	require.NoError(t, err)
+	_, err = doRequest(http.DefaultClient, req)
+	require.NoError(t, err)
+
+	har := readHAR(t, harFile)
+	require.Equal(t, 1, len(har.Log.Entries))
+	entry := har.Log.Entries[0]
+	assert.Equal(t, `{"ok":true}`, entry.Response.Content.Text)
+	assert.Equal(t, -1.0, entry.Timings.Connect) // no connection was made
+	assert.Equal(t, entry.Time, entry.Timings.Wait)
+}
+
+func TestHARProfilePages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	config.SetEphemeralContext(&config.Context{Name: "current", AuthMethod: config.AuthMethodNone, URL: server.URL})
+	defer config.SetEphemeralContext(nil)
+	file := filepath.Join(t.TempDir(), "session.har")
+	require.NoError(t, StartHAR(file, "1.2.3"))
+	defer resetHAR()
+
+	// requests of clients bound to Go contexts are grouped into a page per profile
+	for _, profile := range []string{"dev", "prod", "dev"} {
+		client, err := NewClient(&config.Context{Name: profile, AuthMethod: config.AuthMethodNone, URL: server.URL}, nil)
+		require.NoError(t, err)
+		ctx := WithClient(context.Background(), client)
+		var out any
+		require.NoError(t, JSONGet("/things", &out, &Options{Context: ctx}))
+	}
+	var out any
+	require.NoError(t, JSONGet("/things", &out, &Options{Context: context.Background()})) // the current profile
+
+	har := readHAR(t, file)
+	require.Equal(t, 2, len(har.Log.Pages))
+	assert.Equal(t, "dev", har.Log.Pages[0].ID)
+	assert.Equal(t, "prod", har.Log.Pages[1].ID)
+	pages := []string{}
+	for _, entry := range har.Log.Entries {
+		pages = append(pages, entry.PageRef)
+	}
+	assert.Equal(t, []string{"dev", "prod", "dev", ""}, pages)
+}
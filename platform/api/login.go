@@ -17,11 +17,15 @@ package api
 import (
 	"fmt"
 	"reflect"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/apex/log"
 
 	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/tracing"
 )
 
 // requiredSettings defines what config.Context fields are required for each authentication method
@@ -32,6 +36,7 @@ var requiredSettings = map[string][]string{
 	config.AuthMethodServicePrincipal: {"SecretFile"},   // tenant and server can usually be obtained from the file
 	config.AuthMethodAgentPrincipal:   {"SecretFile"},   // tenant and server can usually be obtained from the file
 	config.AuthMethodJWT:              {"URL", "Token"}, // tenant is desired but may not be mandatory for all requests
+	config.AuthMethodSessionManager:   {"URL", "SessionManagerURL"},
 }
 
 // fieldToFlag maps a config.Context field to CLI flag name, so that we can display better
@@ -45,25 +50,71 @@ var fieldToFlag = map[string]string{
 	"LocalAuthOptions.AppdPty": "appd-pty",
 	"LocalAuthOptions.AppdPid": "appd-pid",
 	"LocalAuthOptions.AppdTid": "appd-tid",
+	"SessionManagerURL":        "session-manager-url",
 }
 
+// defaultTokenRefreshSkew is how long before its expiry an access token is refreshed, unless configured otherwise
+const defaultTokenRefreshSkew = 30 * time.Second
+
+// profileLockTimeout is how long login waits for another fsoc process that is logging in with the same profile
+const profileLockTimeout = 60 * time.Second
+
+// refreshableAuthMethods are the authentication methods for which login can obtain a fresh access token
+var refreshableAuthMethods = []string{config.AuthMethodOAuth, config.AuthMethodServicePrincipal, config.AuthMethodAgentPrincipal}
+
+// loginMutex serializes logins with the current profile (see defaultClient), so that when several
+// concurrent requests find the token expired or rejected, the token is refreshed (and saved to the
+// profile) only once
+var loginMutex sync.Mutex
+
 // Login performs a login into the platform API and saves the provided access token.
 // Login respects different access profile types (when supported) to provide the correct
 // login mechanism for each.
 func Login() error {
-	callCtx := newCallContext()
+	callCtx, err := newDefaultCallContext(nil)
+	if err != nil {
+		return err
+	}
 	defer callCtx.stopSpinner(false) // ensure not running when returning
 
 	return login(callCtx)
 }
 
-func login(callCtx *callContext) error {
-	log.Infof("Login is forced in order to get a valid access token")
+func login(callCtx *callContext) (err error) {
+	client := callCtx.client
+	logger := callCtx.logger()
+
+	// trace the login (incl. waiting for a concurrent one), with its requests as children
+	parentContext := callCtx.goContext
+	var span *tracing.Span
+	callCtx.goContext, span = tracing.Start(parentContext, "login", tracing.SpanKindInternal, loginSpanAttributes(callCtx.cfg))
+	defer func() {
+		span.SetError(err)
+		span.End()
+		callCtx.goContext = parentContext
+	}()
+	if client != nil {
+		client.loginMutex.Lock()
+		defer client.loginMutex.Unlock()
+	}
+
+	// another request (or process) may have refreshed the token while this one was waiting; if so, use it
+	current, unlock := concurrentlyRefreshed(callCtx)
+	defer unlock()
+	if current != nil {
+		logger.Info("Using the access token refreshed by a concurrent request")
+		span.SetAttributes(tracing.Attributes{"fsoc.login.concurrent": true})
+		callCtx.cfg = current
+		client.setConfig(current)
+		return nil
+	}
+
+	logger.Infof("Login is forced in order to get a valid access token")
 
 	// check current context for required fields
 	cfg := callCtx.cfg
 	if err := checkConfigForAuth(cfg); err != nil {
-		return err
+		return &LoginError{Err: err}
 	}
 
 	var authErr error
@@ -73,29 +124,134 @@ func login(callCtx *callContext) error {
 	case config.AuthMethodNone:
 		authErr = nil // nothing to do
 	case config.AuthMethodJWT:
-		authErr = nil // nothing to do (TODO: we may check its validity by executing a no-op request)
+		authErr = nil // nothing to do; "fsoc whoami" validates the token with the platform
 	case config.AuthMethodServicePrincipal:
 		authErr = servicePrincipalLogin(callCtx)
 	case config.AuthMethodAgentPrincipal:
 		authErr = agentPrincipalLogin(callCtx)
 	case config.AuthMethodOAuth:
 		authErr = oauthLogin(callCtx)
+	case config.AuthMethodSessionManager:
+		authErr = sessionManagerLogin(callCtx)
 	default:
-		panic(fmt.Sprintf("bug: unhandled authentication method %q", cfg.AuthMethod))
+		authErr = fmt.Errorf("unsupported authentication method %q", cfg.AuthMethod)
 	}
 	if authErr != nil {
-		return authErr
+		return &LoginError{Err: authErr}
 	}
 
-	// update current context with logged in credentials (token(s)) to use
-	config.ReplaceCurrentContext(cfg)
+	if client == nil {
+		return nil
+	}
+	client.setConfig(cfg)
+
+	// keep replayed (redacted) tokens in memory only, so that replaying doesn't clobber the profile
+	if isReplaying() {
+		logger.Info("Replaying recorded traffic; not saving the login token(s) to the profile")
+		return nil
+	}
 
-	// reload context
-	callCtx.cfg = config.GetCurrentContext()
+	// update the profile with logged in credentials (token(s)) to use
+	if client.saveTokens != nil {
+		if err := client.saveTokens(client.Config()); err != nil {
+			return fmt.Errorf("failed to save the login token(s): %w", err)
+		}
+	}
 
 	return nil
 }
 
+func loginSpanAttributes(cfg *config.Context) tracing.Attributes {
+	if cfg == nil {
+		return nil
+	}
+	return tracing.Attributes{"fsoc.profile": cfg.Name, "fsoc.auth_method": cfg.AuthMethod}
+}
+
+// concurrentlyRefreshed returns the client's profile if its token was refreshed by another request
+// while this one was waiting to log in, nil otherwise. For profiles kept in the config file, logins
+// are also serialized with other fsoc processes using the profile (e.g., parallel CI jobs), so that
+// only one of them refreshes the token and the others reuse it; the returned function releases the lock.
+func concurrentlyRefreshed(callCtx *callContext) (*config.Context, func()) {
+	unlock := func() {}
+	client := callCtx.client
+	if client == nil || callCtx.cfg == nil {
+		return nil, unlock
+	}
+
+	current := client.Config()
+	if client.sharedProfile && callCtx.cfg.Name != "" && !isReplaying() {
+		var err error
+		unlock, err = config.LockProfile(callCtx.goContext, callCtx.cfg.Name, profileLockTimeout)
+		if err != nil {
+			callCtx.logger().Warnf("Proceeding with login without coordinating with other fsoc processes: %v", err)
+			unlock = func() {}
+		}
+		current, err = config.ReloadContext(callCtx.cfg.Name)
+		if err != nil {
+			return nil, unlock
+		}
+	}
+
+	if current.Token != "" && current.Token != callCtx.cfg.Token && !tokenNeedsRefresh(current, time.Now()) {
+		return current, unlock
+	}
+	return nil, unlock
+}
+
+// tokenNeedsRefresh determines whether the context's access token is expired or will expire within the
+// configured skew, so that it should be refreshed before making a request. Tokens that cannot be
+// decoded or that belong to auth methods which cannot refresh them are assumed to be valid.
+func tokenNeedsRefresh(cfg *config.Context, now time.Time) bool {
+	if cfg.Token == "" || !slices.Contains(refreshableAuthMethods, cfg.AuthMethod) {
+		return false
+	}
+	expiry, ok := contextTokenExpiry(cfg)
+	if !ok {
+		return false
+	}
+	return now.Add(tokenRefreshSkew(cfg)).After(expiry)
+}
+
+// contextTokenExpiry returns when the context's access token expires: the earlier of the expiry
+// claimed in the token itself and the expiry recorded at login from the token endpoint's response
+// (the latter allows refreshing opaque tokens ahead of time, too)
+func contextTokenExpiry(cfg *config.Context) (time.Time, bool) {
+	expiry, ok := tokenExpiry(cfg.Token)
+	if cfg.TokenExpiry != "" {
+		recorded, err := time.Parse(time.RFC3339, cfg.TokenExpiry)
+		if err != nil {
+			log.Warnf("Ignoring invalid token expiry %q in the profile", cfg.TokenExpiry)
+		} else if !ok || recorded.Before(expiry) {
+			expiry, ok = recorded, true
+		}
+	}
+	return expiry, ok
+}
+
+// setTokenExpiry records when the context's newly obtained access token expires, based on the
+// token endpoint's "expires_in" (in seconds); zero means the endpoint did not report it
+func setTokenExpiry(cfg *config.Context, expiresIn int, now time.Time) {
+	if expiresIn <= 0 {
+		cfg.TokenExpiry = ""
+		return
+	}
+	cfg.TokenExpiry = now.Add(time.Duration(expiresIn) * time.Second).UTC().Format(time.RFC3339)
+}
+
+// tokenRefreshSkew returns how long before its expiry the context's access token should be refreshed
+func tokenRefreshSkew(cfg *config.Context) time.Duration {
+	if cfg.TokenRefreshSkew == "" {
+		return defaultTokenRefreshSkew
+	}
+	d, err := time.ParseDuration(cfg.TokenRefreshSkew)
+	if err != nil || d < 0 {
+		log.Warnf("Invalid token refresh skew %q in the profile; using the default %v instead", cfg.TokenRefreshSkew, defaultTokenRefreshSkew)
+		return defaultTokenRefreshSkew
+	}
+	return d
+}
+
 func nonZeroStructFields(theStruct *config.Context) []string {
 	if theStruct == nil {
 		return []string{}
@@ -1,7 +1,10 @@
 package api
 
 import (
+	"encoding/base64"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -19,3 +22,106 @@ func TestRecursiveWalkThroughStruct(t *testing.T) {
 	fields := nonZeroStructFields(&ctx)
 	assert.ElementsMatch(t, fields, []string{"Name", "AuthMethod", "LocalAuthOptions", "LocalAuthOptions.AppdTid"})
 }
+
+// makeTestToken creates an unsigned JWT with the given subject and expiry
+func makeTestToken(sub string, exp time.Time) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	claims := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"sub":%q,"exp":%d}`, sub, exp.Unix())))
+	return header + "." + claims + ".sig"
+}
+
+func TestTokenNeedsRefresh(t *testing.T) {
+	now := time.Now()
+	validToken := makeTestToken("user1", now.Add(10*time.Minute))
+	expiringToken := makeTestToken("user1", now.Add(10*time.Second))
+	expiredToken := makeTestToken("user1", now.Add(-time.Minute))
+
+	oauth := func(token, skew string) *config.Context {
+		return &config.Context{AuthMethod: config.AuthMethodOAuth, Token: token, TokenRefreshSkew: skew}
+	}
+	assert.False(t, tokenNeedsRefresh(oauth(validToken, ""), now))
+	assert.True(t, tokenNeedsRefresh(oauth(expiringToken, ""), now)) // within default skew
+	assert.False(t, tokenNeedsRefresh(oauth(expiringToken, "5s"), now))
+	assert.True(t, tokenNeedsRefresh(oauth(validToken, "15m"), now))
+	assert.True(t, tokenNeedsRefresh(oauth(expiredToken, "0s"), now))
+	assert.False(t, tokenNeedsRefresh(oauth("not-a-jwt", ""), now))
+	assert.False(t, tokenNeedsRefresh(oauth("", ""), now))
+
+	// tokens that fsoc cannot refresh are left alone
+	jwt := &config.Context{AuthMethod: config.AuthMethodJWT, Token: expiredToken}
+	assert.False(t, tokenNeedsRefresh(jwt, now))
+
+	// expiry recorded at login applies to opaque tokens and takes precedence when earlier
+	principal := func(token string, expiresIn int) *config.Context {
+		cfg := &config.Context{AuthMethod: config.AuthMethodServicePrincipal, Token: token}
+		setTokenExpiry(cfg, expiresIn, now)
+		return cfg
+	}
+	assert.False(t, tokenNeedsRefresh(principal("opaque", 3600), now))
+	assert.True(t, tokenNeedsRefresh(principal("opaque", 10), now))
+	assert.True(t, tokenNeedsRefresh(principal(validToken, 10), now))
+	assert.True(t, tokenNeedsRefresh(principal(expiringToken, 3600), now))
+	assert.False(t, tokenNeedsRefresh(principal("opaque", 0), now)) // expiry not reported
+	assert.Equal(t, "", principal("opaque", 0).TokenExpiry)
+}
+
+func TestExtractUser(t *testing.T) {
+	user, err := extractUser(makeTestToken("someone@example.com", time.Now()))
+	assert.Nil(t, err)
+	assert.Equal(t, "someone@example.com", user)
+
+	_, err = extractUser("invalid")
+	assert.NotNil(t, err)
+}
+
+func TestDescribeToken(t *testing.T) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"sp-123","iss":"https://issuer.example.com","aud":["a","b"],"scope":"openid introspect","iat":1700000000,"exp":1700003600}`))
+	info, err := DescribeToken(header + "." + claims + ".sig")
+	assert.Nil(t, err)
+	assert.Equal(t, "sp-123", info.Principal)
+	assert.Equal(t, "https://issuer.example.com", info.Issuer)
+	assert.Equal(t, []string{"a", "b"}, info.Audience)
+	assert.Equal(t, []string{"openid", "introspect"}, info.Scopes)
+	assert.Equal(t, int64(1700000000), info.IssuedAt.Unix())
+	assert.Equal(t, int64(1700003600), info.ExpiresAt.Unix())
+
+	// scopes as an array in the "scp" claim, no times
+	claims = base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"user","aud":"fsoc","scp":["read","write"]}`))
+	info, err = DescribeToken(header + "." + claims + ".sig")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"fsoc"}, info.Audience)
+	assert.Equal(t, []string{"read", "write"}, info.Scopes)
+	assert.Nil(t, info.IssuedAt)
+	assert.Nil(t, info.ExpiresAt)
+}
+
+func TestParseRedirectUrl(t *testing.T) {
+	codes, err := parseRedirectUrl("  http://127.0.0.1:3101/callback?code=abc&scope=openid&state=xyz\n")
+	assert.Nil(t, err)
+	assert.Equal(t, authCodes{Code: "abc", Scope: "openid", State: "xyz"}, *codes)
+
+	_, err = parseRedirectUrl("http://127.0.0.1:3101/callback?error=access_denied&error_description=denied")
+	assert.ErrorContains(t, err, "access_denied")
+
+	_, err = parseRedirectUrl("http://127.0.0.1:3101/callback")
+	assert.NotNil(t, err)
+
+	_, err = parseRedirectUrl("")
+	assert.NotNil(t, err)
+}
+
+func TestHeadlessReason(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(name string) string { return vars[name] }
+	}
+	assert.Equal(t, "running in an SSH session", headlessReason("darwin", env(map[string]string{"SSH_CONNECTION": "10.0.0.1 22 10.0.0.2 22"})))
+	assert.Equal(t, "running in an SSH session", headlessReason("linux", env(map[string]string{"SSH_TTY": "/dev/pts/0", "DISPLAY": ":0"})))
+	assert.Equal(t, "no graphical display", headlessReason("linux", env(nil)))
+	assert.Equal(t, "no graphical display", headlessReason("freebsd", env(nil)))
+	assert.Equal(t, "", headlessReason("linux", env(map[string]string{"DISPLAY": ":0"})))
+	assert.Equal(t, "", headlessReason("linux", env(map[string]string{"WAYLAND_DISPLAY": "wayland-0"})))
+	assert.Equal(t, "", headlessReason("linux", env(map[string]string{"WSL_DISTRO_NAME": "Ubuntu"}))) // uses the Windows browser
+	assert.Equal(t, "", headlessReason("darwin", env(nil)))
+	assert.Equal(t, "", headlessReason("windows", env(nil)))
+}
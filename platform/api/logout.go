@@ -0,0 +1,79 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cisco-open/fsoc/config"
+)
+
+// ErrRevocationNotSupported indicates that the profile's auth server has no token revocation endpoint
+var ErrRevocationNotSupported = errors.New("the auth server does not support token revocation")
+
+// RevokeRefreshToken revokes the profile's OAuth refresh token at the auth server's revocation
+// endpoint (RFC 7009), which also invalidates the access tokens issued with it. It returns false if
+// there is nothing to revoke, i.e., the profile doesn't use oauth or has no refresh token.
+// ErrRevocationNotSupported is returned if the auth server has no revocation endpoint.
+func RevokeRefreshToken(cfg *config.Context) (bool, error) {
+	if cfg.AuthMethod != config.AuthMethodOAuth || cfg.RefreshToken == "" || cfg.Tenant == "" {
+		return false, nil
+	}
+	callCtx := &callContext{goContext: defaultGoContext, cfg: cfg}
+
+	client, err := callCtx.httpClient()
+	if err != nil {
+		return false, err
+	}
+	values := url.Values{}
+	values.Add("client_id", oauth2ClientId)
+	values.Add("token", cfg.RefreshToken)
+	values.Add("token_type_hint", "refresh_token")
+
+	revokeUri, err := oauthUriWithSuffix(cfg, oauth2RevokeUriSuffix)
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(callCtx.goContext, "POST", revokeUri, strings.NewReader(values.Encode()))
+	if err != nil {
+		return false, fmt.Errorf("failed to create a token revocation request %q: %v", revokeUri, err)
+	}
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	resp, err := doRequest(client, req)
+	if err != nil {
+		return false, &NetworkError{Method: req.Method, URL: revokeUri, Err: err}
+	}
+	defer resp.Body.Close()
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("failed reading the token revocation response from %q: %w", revokeUri, err)
+	}
+
+	switch {
+	case resp.StatusCode/100 == 2:
+		callCtx.logger().WithField("profile", cfg.Name).Info("Refresh token revoked")
+		return true, nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusNotImplemented:
+		return false, ErrRevocationNotSupported
+	default:
+		return false, parseIntoError(resp, respBytes)
+	}
+}
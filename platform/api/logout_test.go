@@ -0,0 +1,91 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco-open/fsoc/config"
+)
+
+func TestRevokeRefreshToken(t *testing.T) {
+	var revoked []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/auth/tenant-1/default/oauth2/revoke" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "refresh_token", r.PostForm.Get("token_type_hint"))
+		revoked = append(revoked, r.PostForm.Get("token"))
+	}))
+	defer server.Close()
+
+	cfg := &config.Context{AuthMethod: config.AuthMethodOAuth, URL: server.URL, Tenant: "tenant-1", RefreshToken: "refresh-1"}
+	ok, err := RevokeRefreshToken(cfg)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"refresh-1"}, revoked)
+
+	// no revocation endpoint
+	cfg.Tenant = "tenant-2"
+	ok, err = RevokeRefreshToken(cfg)
+	assert.ErrorIs(t, err, ErrRevocationNotSupported)
+	assert.False(t, ok)
+
+	// nothing to revoke
+	ok, err = RevokeRefreshToken(&config.Context{AuthMethod: config.AuthMethodServicePrincipal, Token: "token"})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestRevokeRefreshTokenRecording(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest) // the token is still valid
+	}))
+	defer server.Close()
+	cfg := &config.Context{AuthMethod: config.AuthMethodOAuth, URL: server.URL, Tenant: "tenant-1", RefreshToken: "live-refresh-token"}
+
+	// the refresh token is not recorded in cassettes ...
+	cassetteFile := filepath.Join(t.TempDir(), "cassette.yaml")
+	require.NoError(t, StartRecording(cassetteFile))
+	_, err := RevokeRefreshToken(cfg)
+	resetCassette()
+	assert.Error(t, err)
+	data, err := os.ReadFile(cassetteFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "live-refresh-token")
+	assert.Contains(t, string(data), "token=REDACTED")
+
+	// ... nor in HAR files
+	harFile := filepath.Join(t.TempDir(), "session.har")
+	require.NoError(t, StartHAR(harFile, "1.2.3"))
+	_, err = RevokeRefreshToken(cfg)
+	resetHAR()
+	assert.Error(t, err)
+	data, err = os.ReadFile(harFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "live-refresh-token")
+	har := readHAR(t, harFile)
+	require.Equal(t, 1, len(har.Log.Entries))
+	assert.Contains(t, har.Log.Entries[0].Request.PostData.Text, "token=REDACTED")
+}
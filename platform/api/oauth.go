@@ -17,13 +17,17 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"reflect"
+	"runtime"
 	"strings"
+	"time"
 
 	"github.com/apex/log"
 	"github.com/pkg/browser"
@@ -34,10 +38,11 @@ import (
 )
 
 const (
-	oauth2ClientId       = "default"
-	oauth2AuthUriSuffix  = "oauth2/authorize" // API for obtaining authorization codes
-	oauth2TokenUriSuffix = "oauth2/token"     // API for exchanging the auth code for a token
-	oauthRedirectUri     = "http://127.0.0.1:3101/callback"
+	oauth2ClientId        = "default"
+	oauth2AuthUriSuffix   = "oauth2/authorize" // API for obtaining authorization codes
+	oauth2TokenUriSuffix  = "oauth2/token"     // API for exchanging the auth code for a token
+	oauth2RevokeUriSuffix = "oauth2/revoke"    // API for revoking a refresh token (RFC 7009)
+	oauthRedirectUri      = "http://127.0.0.1:3101/callback"
 )
 
 // appTokens is what the AppD backend returns when it hands back the tokens (in exchange for the authorization code)
@@ -80,7 +85,7 @@ func (s refreshTokenStaleError) Error() string {
 
 // oauthLogin performs a login into the platform API and updates the token(s) in the provided context
 func oauthLogin(ctx *callContext) error {
-	log.Infof("Starting OAuth authentication flow")
+	ctx.logger().Infof("Starting OAuth authentication flow")
 
 	// get tenant if one is not provided, update into ctx
 	if ctx.cfg.Tenant == "" {
@@ -89,7 +94,7 @@ func oauthLogin(ctx *callContext) error {
 			return fmt.Errorf("could not resolve tenant ID for %q: %v", ctx.cfg.URL, err.Error())
 		}
 		ctx.cfg.Tenant = tenantId
-		log.Infof("Successfully resolved tenant ID to %v", ctx.cfg.Tenant)
+		ctx.logger().Infof("Successfully resolved tenant ID to %v", ctx.cfg.Tenant)
 		// tenant is now updated in ctx, will be saved if we successfully log in
 	}
 
@@ -98,14 +103,14 @@ func oauthLogin(ctx *callContext) error {
 		// refresh and return if successful
 		err := oauthRefreshToken(ctx)
 		if err == nil {
-			log.Infof("Access token refreshed successfully")
+			ctx.logger().Infof("Access token refreshed successfully")
 			return nil
 		}
 		rtse, ok := err.(refreshTokenStaleError)
 		if ok {
-			log.Infof("%v; going for a new login", rtse) // display token refresh error
+			ctx.logger().Infof("%v; going for a new login", rtse) // display token refresh error
 		} else {
-			log.Infof("Refresh token rejected: %v; going for a new login", err)
+			ctx.logger().Infof("Refresh token rejected: %v; going for a new login", err)
 		}
 	}
 
@@ -123,12 +128,20 @@ func oauthLogin(ctx *callContext) error {
 	state := string(stateCode)
 
 	// prepare OAuth2 config
+	authUri, err := oauthUriWithSuffix(ctx.cfg, oauth2AuthUriSuffix)
+	if err != nil {
+		return err
+	}
+	tokenUri, err := oauthUriWithSuffix(ctx.cfg, oauth2TokenUriSuffix)
+	if err != nil {
+		return err
+	}
 	conf := &oauth2.Config{
 		ClientID:    oauth2ClientId,
 		RedirectURL: oauthRedirectUri,
 		Endpoint: oauth2.Endpoint{
-			AuthURL:   oauthUriWithSuffix(ctx.cfg, oauth2AuthUriSuffix),
-			TokenURL:  oauthUriWithSuffix(ctx.cfg, oauth2TokenUriSuffix),
+			AuthURL:   authUri,
+			TokenURL:  tokenUri,
 			AuthStyle: oauth2.AuthStyleInParams,
 		},
 		Scopes: []string{"openid", "introspect_tokens", "offline_access"},
@@ -139,9 +152,15 @@ func oauthLogin(ctx *callContext) error {
 	)
 
 	// open browser to perform login, collect auth with a localhost http server
-	authCode, err := getAuthorizationCodes(ctx, url)
-	if err != nil {
-		return fmt.Errorf("login failed to obtain the authorization code: %v", err)
+	// (when replaying recorded traffic, there is no interactive step; the token exchange is replayed)
+	var authCode *authCodes
+	if isReplaying() {
+		authCode = &authCodes{Code: redactedValue, State: state}
+	} else {
+		authCode, err = getAuthorizationCodes(ctx, url)
+		if err != nil {
+			return fmt.Errorf("login failed to obtain the authorization code: %v", err)
+		}
 	}
 
 	// verify nonce, must match
@@ -165,16 +184,17 @@ func oauthLogin(ctx *callContext) error {
 
 	userID, err := extractUser(token.AccessToken)
 	if err != nil {
-		log.Warnf("Could not extract user identity from the bearer token: %v. Continuing without user ID", err)
+		ctx.logger().Warnf("Could not extract user identity from the bearer token: %v. Continuing without user ID", err)
 		userID = ""
 		// fall through and continue without a user ID
 	} else {
-		log.WithFields(log.Fields{"userId": userID}).Info("Extracted user ID")
+		ctx.logger().WithFields(log.Fields{"userId": userID}).Info("Extracted user ID")
 	}
 
 	// update profile
 	ctx.cfg.Token = token.AccessToken
 	ctx.cfg.RefreshToken = token.RefreshToken
+	setTokenExpiry(ctx.cfg, token.ExpiresIn, time.Now())
 	if userID != "" {
 		ctx.cfg.User = userID
 	}
@@ -183,38 +203,57 @@ func oauthLogin(ctx *callContext) error {
 }
 
 func getAuthorizationCodes(ctx *callContext, url string) (*authCodes, error) {
+	// use the headless flow if requested or if a local browser cannot be used
+	if FlagHeadlessLogin {
+		return getAuthorizationCodesHeadless(ctx, url)
+	}
+	if reason := headlessReason(runtime.GOOS, os.Getenv); reason != "" {
+		ctx.logger().Infof("Using headless login: %v", reason)
+		return getAuthorizationCodesHeadless(ctx, url)
+	}
+
 	// start http server to receive the auth callback
 	callbackServer, respChan, err := startCallbackServer()
 	if err != nil {
-		return nil, fmt.Errorf("could not start a local http server for auth: %v", err.Error())
+		ctx.logger().Warnf("Could not start a local http server for auth (%v); switching to headless login", err)
+		return getAuthorizationCodesHeadless(ctx, url)
 	}
 	defer func() {
 		_ = stopCallbackServer(callbackServer) // no check needed, error should be logged
 	}()
 
 	// open browser
-	log.Infof("Starting a browser to perform authentication")
+	ctx.logger().Infof("Starting a browser to perform authentication")
 	//fmt.Printf("If a browser window does not open shortly, please visit the following URL to login\n%v\n", url)
 	if err = openBrowser(url); err != nil {
-		log.Errorf("Failed to automatically launch browser auth window: %v", err)
-		log.Errorf("Please visit the following URL to login\n%v\n", url)
-		// fall through
+		ctx.logger().Warnf("Could not launch a browser for the login (%v); switching to headless login", err)
+		return getAuthorizationCodesHeadless(ctx, url)
 	}
+	fmt.Fprintln(os.Stderr, `Log in using the browser window that fsoc opened. If no browser window opened, interrupt fsoc and use "fsoc login --no-browser".`)
 
-	// wait for authorization codes (TODO: add timeout, e.g., a few minutes)
+	// wait for authorization codes, unless interrupted or timed out
 	ctx.startSpinner("OAuth interactive authentication")
-	authCode := <-respChan // nb: blocks until a callback is received on localhost with the correct path
-	ctx.stopSpinner(true)  // TODO: figure out whether this can indicate fail/in what condition
-	log.Infof("PKCE authorization codes received")
+	var authCode authCodes
+	select {
+	case authCode = <-respChan: // nb: blocks until a callback is received on localhost with the correct path
+	case <-ctx.goContext.Done():
+		ctx.stopSpinner(false)
+		return nil, fmt.Errorf("interactive authentication aborted: %w", ctx.goContext.Err())
+	}
+	ctx.stopSpinner(true) // TODO: figure out whether this can indicate fail/in what condition
+	ctx.logger().Infof("PKCE authorization codes received")
 
 	return &authCode, nil
 }
 
 func exchangeCodeForToken(ctx *callContext, conf *oauth2.Config, pkce pkce.Code, auth *authCodes) (*appTokens, error) {
-	log.Infof("Exchanging authorization codes for access token")
+	ctx.logger().Infof("Exchanging authorization codes for access token")
 
 	// create http client for the request
-	client := &http.Client{}
+	client, err := ctx.httpClient()
+	if err != nil {
+		return nil, err
+	}
 
 	// prepare urlencoded data body
 	values := url.Values{}
@@ -226,7 +265,7 @@ func exchangeCodeForToken(ctx *callContext, conf *oauth2.Config, pkce pkce.Code,
 	bodyReader := bytes.NewReader([]byte(values.Encode()))
 
 	// create a POST HTTP request
-	req, err := http.NewRequest("POST", conf.Endpoint.TokenURL, bodyReader)
+	req, err := http.NewRequestWithContext(ctx.goContext, "POST", conf.Endpoint.TokenURL, bodyReader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create a request %q: %v", conf.Endpoint.TokenURL, err.Error())
 	}
@@ -234,7 +273,7 @@ func exchangeCodeForToken(ctx *callContext, conf *oauth2.Config, pkce pkce.Code,
 
 	// execute request
 	ctx.startSpinner("OAuth auth codes exchange for token")
-	resp, err := client.Do(req)
+	resp, err := doRequest(client, req)
 	ctx.stopSpinner(err == nil && resp.StatusCode/100 == 2)
 	if err != nil {
 		return nil, fmt.Errorf("POST request to %q failed: %v", req.RequestURI, err.Error())
@@ -243,7 +282,7 @@ func exchangeCodeForToken(ctx *callContext, conf *oauth2.Config, pkce pkce.Code,
 	// log error if it occurred
 	if resp.StatusCode/100 != 2 {
 		// log error before trying to parse body, more processing later
-		log.Errorf("Request failed, status %q; more info to follow", resp.Status)
+		ctx.logger().Errorf("Request failed, status %q; more info to follow", resp.Status)
 		// fall through
 	}
 
@@ -282,10 +321,13 @@ func exchangeCodeForToken(ctx *callContext, conf *oauth2.Config, pkce pkce.Code,
 // that has a refresh token. Note that the refresh token also changes, so it will be updated
 // as well.
 func oauthRefreshToken(ctx *callContext) error {
-	log.Infof("Trying to get a new access token using the refresh token")
+	ctx.logger().Infof("Trying to get a new access token using the refresh token")
 
 	// create http client for the request
-	client := &http.Client{}
+	client, err := ctx.httpClient()
+	if err != nil {
+		return err
+	}
 
 	// prepare urlencoded data body
 	values := url.Values{}
@@ -296,8 +338,11 @@ func oauthRefreshToken(ctx *callContext) error {
 	bodyReader := bytes.NewReader([]byte(values.Encode()))
 
 	// create a POST HTTP request
-	tokenUri := oauthUriWithSuffix(ctx.cfg, oauth2TokenUriSuffix)
-	req, err := http.NewRequest("POST", tokenUri, bodyReader)
+	tokenUri, err := oauthUriWithSuffix(ctx.cfg, oauth2TokenUriSuffix)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx.goContext, "POST", tokenUri, bodyReader)
 	if err != nil {
 		return fmt.Errorf("failed to create a token refresh request %q: %v", tokenUri, err)
 	}
@@ -305,7 +350,7 @@ func oauthRefreshToken(ctx *callContext) error {
 
 	// execute request
 	ctx.startSpinner("OAuth token refresh")
-	resp, err := client.Do(req)
+	resp, err := doRequest(client, req)
 	ctx.stopSpinner(err == nil && resp.StatusCode/100 == 2)
 	if err != nil {
 		return fmt.Errorf("POST request to %q failed: %v", req.RequestURI, err.Error())
@@ -314,7 +359,7 @@ func oauthRefreshToken(ctx *callContext) error {
 	// log error if it occurred
 	if resp.StatusCode/100 != 2 {
 		// log error before trying to parse body, more processing later
-		log.Errorf("Request failed, status %q; more info to follow", resp.Status)
+		ctx.logger().Errorf("Request failed, status %q; more info to follow", resp.Status)
 		// fall through
 	}
 
@@ -340,22 +385,23 @@ func oauthRefreshToken(ctx *callContext) error {
 	// update tokens in context
 	ctx.cfg.Token = tokenObject.AccessToken
 	ctx.cfg.RefreshToken = tokenObject.RefreshToken
+	setTokenExpiry(ctx.cfg, tokenObject.ExpiresIn, time.Now())
 
 	return nil
 }
 
-func oauthUriWithSuffix(ctx *config.Context, suffix string) string {
+func oauthUriWithSuffix(ctx *config.Context, suffix string) (string, error) {
 	uri, err := url.JoinPath(ctx.URL, "auth", ctx.Tenant, oauth2ClientId, suffix)
 	if err != nil {
-		log.Fatalf("unexpected failure constructing oauth2 endpoint URI: %v; terminating (likely a bug)", err)
+		return "", fmt.Errorf("failed to construct the oauth2 endpoint URI from the profile's url %q: %w", ctx.URL, err)
 	}
-	return uri
+	return uri, nil
 	// return strings.Join([]string{ctx.URL, "auth", ctx.Tenant, oauth2ClientId, suffix}, "/")
 }
 
 func startCallbackServer() (*http.Server, chan authCodes, error) {
-	// construct a channel for the response
-	respChan := make(chan authCodes)
+	// construct a channel for the response (buffered, so the handler doesn't block if the wait is aborted)
+	respChan := make(chan authCodes, 1)
 
 	// start server at oauthRedirectUri
 	urlStruct, err := url.Parse(oauthRedirectUri)
@@ -369,11 +415,16 @@ func startCallbackServer() (*http.Server, chan authCodes, error) {
 		}),
 		//ErrorLog: log, // TODO: set apex/log as a logger for http
 	}
+	// listen synchronously, so that failure to bind the address is reported to the caller
+	log.Infof("Starting the auth http server on %v", server.Addr)
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to listen on %v: %w", server.Addr, err)
+	}
 	go func() {
-		log.Infof("Starting the auth http server on %v", server.Addr)
-		err := server.ListenAndServe()
-		if err != nil && err.Error() != "http: Server closed" { // TODO: find a better way to ignore the close error specifically
-			log.Errorf("Failed to start auth http server on %v: %v", server.Addr, err)
+		err := server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Auth http server on %v failed: %v", server.Addr, err)
 		}
 	}()
 	return server, respChan, nil
@@ -0,0 +1,121 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// FlagHeadlessLogin forces the headless OAuth login flow (see login --no-browser). In it, the user
+// opens the login URL in a browser on any machine and pastes back the URL that the browser was
+// redirected to after login, so that neither a local browser nor the local callback server is needed.
+var FlagHeadlessLogin bool
+
+// headlessReason returns why a local browser cannot be used for the login, or an empty string if it can.
+// In an SSH session, a browser started by fsoc (if any) would not be on the user's machine and could not
+// reach the local callback server. On Linux and BSD, a browser needs a graphical display, except in WSL,
+// where the Windows browser is used (e.g., with wslview). In other cases, opening the browser is attempted,
+// switching to the headless login if that fails.
+func headlessReason(goos string, getenv func(string) string) string {
+	if getenv("SSH_CONNECTION") != "" || getenv("SSH_TTY") != "" {
+		return "running in an SSH session"
+	}
+	switch goos {
+	case "linux", "freebsd", "openbsd", "netbsd", "dragonfly":
+		if getenv("DISPLAY") == "" && getenv("WAYLAND_DISPLAY") == "" && getenv("WSL_DISTRO_NAME") == "" {
+			return "no graphical display"
+		}
+	}
+	return ""
+}
+
+// getAuthorizationCodesHeadless obtains the authorization codes by asking the user to log in with
+// a browser of their choice and paste back the URL of the (failed to load) redirect page
+func getAuthorizationCodesHeadless(ctx *callContext, authUrl string) (*authCodes, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil, fmt.Errorf("headless login requires an interactive terminal; for non-interactive use, please use a service principal")
+	}
+
+	fmt.Fprintf(os.Stderr, `To log in, open the following URL in a browser (on any machine):
+
+    %v
+
+After logging in, the browser will be redirected to a page at %v that will likely fail to load.
+Copy the full URL of that page from the browser's address bar and paste it here.
+
+Redirect URL: `, authUrl, oauthRedirectUri)
+
+	// read the pasted URL, unless interrupted or timed out
+	lineChan := make(chan string, 1)
+	errChan := make(chan error, 1)
+	go func() {
+		reader := bufio.NewReader(os.Stdin)
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			errChan <- err
+			return
+		}
+		lineChan <- line
+	}()
+
+	var line string
+	select {
+	case line = <-lineChan:
+	case err := <-errChan:
+		return nil, fmt.Errorf("failed to read the redirect URL: %w", err)
+	case <-ctx.goContext.Done():
+		fmt.Fprintln(os.Stderr)
+		return nil, fmt.Errorf("interactive authentication aborted: %w", ctx.goContext.Err())
+	}
+
+	codes, err := parseRedirectUrl(line)
+	if err != nil {
+		return nil, err
+	}
+	ctx.logger().Infof("PKCE authorization codes received")
+	return codes, nil
+}
+
+// parseRedirectUrl extracts the authorization codes from the URL of the OAuth redirect page
+func parseRedirectUrl(redirectUrl string) (*authCodes, error) {
+	redirectUrl = strings.TrimSpace(redirectUrl)
+	if redirectUrl == "" {
+		return nil, fmt.Errorf("no redirect URL provided")
+	}
+	uri, err := url.Parse(redirectUrl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the redirect URL: %v", err)
+	}
+
+	values := uri.Query()
+	if errCode := values.Get("error"); errCode != "" {
+		return nil, fmt.Errorf("login failed: %v: %v", errCode, values.Get("error_description"))
+	}
+	if values.Get("code") == "" || values.Get("state") == "" {
+		return nil, fmt.Errorf("the redirect URL does not contain the authorization code and state; please paste the full URL")
+	}
+
+	return &authCodes{
+		Code:  values.Get("code"),
+		Scope: values.Get("scope"),
+		State: values.Get("state"),
+	}, nil
+}
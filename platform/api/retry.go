@@ -0,0 +1,150 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/apex/log"
+
+	"github.com/cisco-open/fsoc/config"
+)
+
+const (
+	defaultMaxRetries     = 3
+	defaultRetryBaseDelay = 500 * time.Millisecond
+	defaultRetryMaxDelay  = 30 * time.Second
+)
+
+// retryableStatusCodes are the HTTP status codes that indicate throttling or a
+// transient failure, after which the request can be retried
+var retryableStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// idempotentMethods are the HTTP methods that are safe to retry without the caller's consent
+var idempotentMethods = []string{"GET", "HEAD", "OPTIONS", "PUT", "DELETE", "TRACE"}
+
+// retryPolicy defines how many times and how often a failed request is retried
+type retryPolicy struct {
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+}
+
+// newRetryPolicy creates a retry policy from the context's retry options, using defaults for
+// any value that is not set or is invalid
+func newRetryPolicy(cfg *config.Context) retryPolicy {
+	policy := retryPolicy{
+		maxRetries: defaultMaxRetries,
+		baseDelay:  defaultRetryBaseDelay,
+		maxDelay:   defaultRetryMaxDelay,
+	}
+	if cfg == nil {
+		return policy
+	}
+
+	if cfg.RetryOptions.MaxRetries != nil && *cfg.RetryOptions.MaxRetries >= 0 {
+		policy.maxRetries = *cfg.RetryOptions.MaxRetries
+	}
+	if cfg.RetryOptions.MaxDelay != "" {
+		d, err := time.ParseDuration(cfg.RetryOptions.MaxDelay)
+		if err != nil || d <= 0 {
+			log.Warnf("Invalid retry max delay %q in the profile; using the default %v instead", cfg.RetryOptions.MaxDelay, defaultRetryMaxDelay)
+		} else {
+			policy.maxDelay = d
+		}
+	}
+
+	return policy
+}
+
+// shouldRetry determines whether a request can be retried after the given attempt (0-based), given
+// the outcome of the request (either resp or err is expected to be provided).
+func (p retryPolicy) shouldRetry(attempt int, method string, options *Options, resp *http.Response, err error) bool {
+	if attempt >= p.maxRetries {
+		return false
+	}
+	if !slices.Contains(idempotentMethods, method) && (options == nil || !options.RetryNonIdempotent) {
+		return false
+	}
+	if err != nil {
+		return isTransientError(err)
+	}
+	return resp != nil && slices.Contains(retryableStatusCodes, resp.StatusCode)
+}
+
+// delay returns how long to wait before the next retry, honoring the Retry-After header if
+// the server provided one; otherwise, uses exponential backoff with jitter. The delay never
+// exceeds the policy's maximum delay.
+func (p retryPolicy) delay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return min(d, p.maxDelay)
+		}
+	}
+
+	// exponential backoff with jitter in the upper half of the interval
+	backoff := p.baseDelay << min(attempt, 16) // cap the shift to avoid overflow
+	if backoff <= 0 || backoff > p.maxDelay {
+		backoff = p.maxDelay
+	}
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which can be either
+// a number of seconds or an HTTP date. Returns false if the value is missing or invalid.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// isTransientError determines whether a request error is likely to be transient,
+// such as a dropped connection or a timeout
+func isTransientError(err error) bool {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cisco-open/fsoc/config"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	d, ok := parseRetryAfter("7", now)
+	assert.True(t, ok)
+	assert.Equal(t, 7*time.Second, d)
+
+	d, ok = parseRetryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now)
+	assert.True(t, ok)
+	assert.Equal(t, 90*time.Second, d)
+
+	d, ok = parseRetryAfter(now.Add(-time.Minute).Format(http.TimeFormat), now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(0), d)
+
+	for _, bad := range []string{"", "-3", "soon"} {
+		_, ok = parseRetryAfter(bad, now)
+		assert.Falsef(t, ok, "%q should not parse", bad)
+	}
+}
+
+func TestRetryPolicyFromConfig(t *testing.T) {
+	p := newRetryPolicy(&config.Context{})
+	assert.Equal(t, defaultMaxRetries, p.maxRetries)
+	assert.Equal(t, defaultRetryMaxDelay, p.maxDelay)
+
+	zero := 0
+	p = newRetryPolicy(&config.Context{RetryOptions: config.RetryOptions{MaxRetries: &zero, MaxDelay: "5s"}})
+	assert.Equal(t, 0, p.maxRetries)
+	assert.Equal(t, 5*time.Second, p.maxDelay)
+}
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	p := retryPolicy{maxRetries: 2, baseDelay: time.Millisecond, maxDelay: time.Second}
+	throttled := &http.Response{StatusCode: http.StatusTooManyRequests}
+	notFound := &http.Response{StatusCode: http.StatusNotFound}
+	dropped := fmt.Errorf("request failed: %w", syscall.ECONNRESET)
+
+	assert.True(t, p.shouldRetry(0, "GET", nil, throttled, nil))
+	assert.True(t, p.shouldRetry(1, "GET", nil, nil, dropped))
+	assert.True(t, p.shouldRetry(0, "GET", nil, nil, io.ErrUnexpectedEOF))
+	assert.False(t, p.shouldRetry(2, "GET", nil, throttled, nil), "retries exhausted")
+	assert.False(t, p.shouldRetry(0, "GET", nil, notFound, nil), "non-transient status")
+	assert.False(t, p.shouldRetry(0, "GET", nil, nil, fmt.Errorf("bad request")), "non-transient error")
+	assert.False(t, p.shouldRetry(0, "POST", nil, throttled, nil), "non-idempotent method")
+	assert.True(t, p.shouldRetry(0, "POST", &Options{RetryNonIdempotent: true}, throttled, nil))
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := retryPolicy{maxRetries: 5, baseDelay: 100 * time.Millisecond, maxDelay: time.Second}
+
+	for attempt := 0; attempt < 10; attempt++ {
+		d := p.delay(attempt, nil)
+		assert.LessOrEqual(t, d, p.maxDelay)
+		assert.Greater(t, d, time.Duration(0))
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"1"}}}
+	assert.Equal(t, time.Second, p.delay(0, resp))
+	resp.Header.Set("Retry-After", "120")
+	assert.Equal(t, p.maxDelay, p.delay(0, resp), "Retry-After is capped at the max delay")
+}
@@ -17,13 +17,16 @@ package api
 import (
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/apex/log"
 	"gopkg.in/yaml.v3"
@@ -36,12 +39,19 @@ type tokenStruct struct {
 	TokenType        string `json:"token_type"`
 }
 
-// json-style solution principal credentials file format
+// json-style solution principal credentials file format. Secretless service principals provide
+// a private key (inline or in a file, see privateKeyStruct) and a key ID instead of the secret, or a
+// list of keys for rotation, and authenticate with a signed JWT client assertion.
 type credentialsStruct struct {
-	TenantID string `json:"Tenant ID"`
-	TokenURL string `json:"Token URL"`
-	ClientID string `json:"Client ID"`
-	Secret   string `json:"Secret"`
+	TenantID       string             `json:"Tenant ID"`
+	TokenURL       string             `json:"Token URL"`
+	ClientID       string             `json:"Client ID"`
+	Secret         string             `json:"Secret"`
+	KeyID          string             `json:"Key ID,omitempty"`
+	PrivateKey     string             `json:"Private Key,omitempty"`
+	PrivateKeyFile string             `json:"Private Key File,omitempty"`
+	Keys           []privateKeyStruct `json:"Keys,omitempty"`
+	baseDir        string             // directory of the credentials file, for relative key file paths
 }
 
 // json response to "/administration/v1beta/clients/agents" request. Only the
@@ -99,7 +109,7 @@ func agentPrincipalLogin(ctx *callContext) error {
 
 // agentOrServicePrincipalLogin performs a login into the platform API and updates the token(s) in the provided context
 func agentOrServicePrincipalLogin(ctx *callContext, principalType string, credentials *credentialsStruct) error {
-	log.Infof("Starting login flow using %v", principalType)
+	ctx.logger().Infof("Starting login flow using %v", principalType)
 
 	// check/backfill missing fields (the new, JSON, format of the credentials has tenant and server URL)
 	if ctx.cfg.Tenant == "" {
@@ -108,7 +118,7 @@ func agentOrServicePrincipalLogin(ctx *callContext, principalType string, creden
 			return fmt.Errorf(`missing tenant ID, please specify using "fsoc config set tenant=TENANTID"`)
 		}
 		ctx.cfg.Tenant = credentials.TenantID
-		log.WithField("tenantID", ctx.cfg.Tenant).Info("Extracted tenant ID from the credentials file")
+		ctx.logger().WithField("tenantID", ctx.cfg.Tenant).Info("Extracted tenant ID from the credentials file")
 	}
 	if ctx.cfg.URL == "" {
 		// some credentials formats provide the tokenURL from which we can get the server URL
@@ -120,35 +130,110 @@ func agentOrServicePrincipalLogin(ctx *callContext, principalType string, creden
 			return fmt.Errorf("failed to parse server URL from the credentials token URL, %q: %v", credentials.TokenURL, err)
 		}
 		ctx.cfg.URL = urlStruct.Scheme + "://" + urlStruct.Host
-		log.WithField("url", ctx.cfg.URL).Info("Extracted server URL from the credentials file")
+		ctx.logger().WithField("url", ctx.cfg.URL).Info("Extracted server URL from the credentials file")
 	}
 
-	// create a HTTP request
-	url, err := url.Parse(ctx.cfg.URL)
+	// determine the token endpoint
+	tokenUrl, err := url.Parse(ctx.cfg.URL)
 	if err != nil {
-		log.Fatalf("Failed to parse the url provided in context. URL: %s, err: %s", ctx.cfg.URL, err)
+		return fmt.Errorf("failed to parse the url provided in context (%q): %w", ctx.cfg.URL, err)
+	}
+	tokenUrl.Path = "auth/" + ctx.cfg.Tenant + "/default/oauth2/token"
+
+	// authenticate with the secret or, for secretless principals, with a client assertion
+	// signed by each of the active keys in turn (newest first), so that keys can be rotated
+	var respBytes []byte
+	if !credentials.hasPrivateKeys() {
+		respBytes, err = requestPrincipalToken(ctx, principalType, tokenUrl.String(), "grant_type=client_credentials", func(req *http.Request) { //TODO: urlencode data!
+			req.SetBasicAuth(credentials.ClientID, credentials.Secret)
+		})
+	} else {
+		if credentials.ClientID == "" {
+			return fmt.Errorf("the %v credentials file has no client ID", principalType)
+		}
+		var keys []signingKey
+		keys, err = credentials.signingKeys(time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to load the %v private key: %w", principalType, err)
+		}
+		for i, key := range keys {
+			var assertion string
+			assertion, err = signClientAssertion(key, credentials.ClientID, tokenUrl.String(), time.Now())
+			if err != nil {
+				return err
+			}
+			values := url.Values{}
+			values.Set("grant_type", "client_credentials")
+			values.Set("client_id", credentials.ClientID)
+			values.Set("client_assertion_type", clientAssertionType)
+			values.Set("client_assertion", assertion)
+			respBytes, err = requestPrincipalToken(ctx, principalType, tokenUrl.String(), values.Encode(), nil)
+
+			var statusErr *HttpStatusError
+			if err == nil || i == len(keys)-1 || !errors.As(err, &statusErr) || statusErr.StatusCode/100 != 4 {
+				break
+			}
+			ctx.logger().Warnf("Login with key ID %q was rejected (%v); trying the next key", key.keyID, err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// update context with token
+	var token tokenStruct
+	err = json.Unmarshal(respBytes, &token)
+	if err != nil {
+		ctx.logger().Errorf("failed to parse token: %v", err.Error())
+		return err
+	}
+	ctx.logger().Info("Login returned a valid token")
+	ctx.cfg.Token = token.AccessToken
+	setTokenExpiry(ctx.cfg, token.ExpiresInSeconds, time.Now())
+
+	// extract user (client ID) from token
+	userID, err := extractUser(token.AccessToken)
+	if err != nil {
+		ctx.logger().Warnf("Could not extract client ID from the bearer token: %v. Continuing without client ID", err)
+		userID = ""
+		// fall through and continue without a user ID
+	} else {
+		ctx.logger().WithFields(log.Fields{"clientId": userID}).Info("Extracted principal's client ID")
+	}
+	if userID != "" {
+		ctx.cfg.User = userID
 	}
-	url.Path = "auth/" + ctx.cfg.Tenant + "/default/oauth2/token"
 
-	client := &http.Client{}
-	req, err := http.NewRequest("POST", url.String(), strings.NewReader("grant_type=client_credentials")) //TODO: urlencode data!
+	return nil
+}
+
+// requestPrincipalToken sends a client credentials token request to the token endpoint, returning
+// the response body if successful. The setAuth function, if provided, adds client authentication.
+func requestPrincipalToken(ctx *callContext, principalType string, tokenUrl string, body string, setAuth func(req *http.Request)) ([]byte, error) {
+	client, err := ctx.httpClient()
 	if err != nil {
-		return fmt.Errorf("failed to create a request for %q: %v", url.String(), err)
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx.goContext, "POST", tokenUrl, strings.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a request for %q: %v", tokenUrl, err)
 	}
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
-	req.SetBasicAuth(credentials.ClientID, credentials.Secret)
+	if setAuth != nil {
+		setAuth(req)
+	}
 
 	// execute request
 	ctx.startSpinner(fmt.Sprintf("Exchange %v for auth token", principalType))
-	resp, err := client.Do(req)
+	resp, err := doRequest(client, req)
 	ctx.stopSpinner(err == nil && resp.StatusCode == 200)
 	if err != nil {
-		return fmt.Errorf("failed to request auth (%q): %w", url.String(), err)
+		return nil, &NetworkError{Method: req.Method, URL: tokenUrl, Err: err}
 	}
 	if resp.StatusCode != 200 {
 		// log error here before trying to parse body, more processing later
-		log.Errorf("Login failed, status %q; details to follow", resp.Status)
+		ctx.logger().Errorf("Login failed, status %q; details to follow", resp.Status)
 		// fall through to reading the payload body for more error info
 	}
 
@@ -156,38 +241,14 @@ func agentOrServicePrincipalLogin(ctx *callContext, principalType string, creden
 	defer resp.Body.Close()
 	respBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed reading login response from %q: %w", url.String(), err)
+		return nil, fmt.Errorf("failed reading login response from %q: %w", tokenUrl, err)
 	}
 
 	// report error if failed & return
 	if resp.StatusCode != 200 { // exactly 200 expected, none of the other 2xx is good
-		return parseIntoError(resp, respBytes)
-	}
-
-	// update context with token
-	var token tokenStruct
-	err = json.Unmarshal(respBytes, &token)
-	if err != nil {
-		log.Errorf("failed to parse token: %v", err.Error())
-		return err
+		return nil, parseIntoError(resp, respBytes)
 	}
-	log.Info("Login returned a valid token")
-	ctx.cfg.Token = token.AccessToken
-
-	// extract user (client ID) from token
-	userID, err := extractUser(token.AccessToken)
-	if err != nil {
-		log.Warnf("Could not extract client ID from the bearer token: %v. Continuing without client ID", err)
-		userID = ""
-		// fall through and continue without a user ID
-	} else {
-		log.WithFields(log.Fields{"clientId": userID}).Info("Extracted principal's client ID")
-	}
-	if userID != "" {
-		ctx.cfg.User = userID
-	}
-
-	return nil
+	return respBytes, nil
 }
 
 func readServiceCredentials(file string) (*credentialsStruct, error) {
@@ -227,6 +288,7 @@ func readJsonCredentials(file string) (*credentialsStruct, error) {
 	if err = json.Unmarshal(data, &credentials); err != nil {
 		return nil, fmt.Errorf("failed to parse credentials file %q: %w", file, err)
 	}
+	credentials.baseDir = filepath.Dir(file)
 
 	return &credentials, nil
 }
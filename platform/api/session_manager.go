@@ -0,0 +1,31 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"errors"
+)
+
+// ErrSessionManagerNotSupported is returned by logins with the session-manager auth method. The token
+// protocol of the platform's session manager is not published yet, so fsoc doesn't implement the login
+// rather than guess the protocol; the profile settings for the method (e.g., the session manager's URL)
+// are accepted, so that profiles can be prepared.
+var ErrSessionManagerNotSupported = errors.New(`the "session-manager" auth method is not supported yet, as the session manager's token protocol is not available; please use another auth method, e.g., "oauth"`)
+
+// sessionManagerLogin fails with ErrSessionManagerNotSupported, without contacting the session manager
+func sessionManagerLogin(ctx *callContext) error {
+	ctx.logger().Warnf("Cannot log in using the session manager at %v: %v", ctx.cfg.SessionManagerURL, ErrSessionManagerNotSupported)
+	return ErrSessionManagerNotSupported
+}
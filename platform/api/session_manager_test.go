@@ -0,0 +1,40 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cisco-open/fsoc/config"
+)
+
+func TestSessionManagerLoginNotSupported(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	cfg := &config.Context{AuthMethod: config.AuthMethodSessionManager, URL: server.URL, SessionManagerURL: server.URL + "/token"}
+	err := sessionManagerLogin(&callContext{goContext: context.Background(), cfg: cfg})
+	assert.ErrorIs(t, err, ErrSessionManagerNotSupported)
+	assert.Equal(t, "", cfg.Token)
+	assert.Equal(t, 0, requests) // no invented protocol requests
+}
@@ -22,8 +22,6 @@ import (
 	"net/url"
 	"strings"
 
-	"github.com/apex/log"
-
 	"github.com/cisco-open/fsoc/config"
 )
 
@@ -37,18 +35,21 @@ func resolveTenant(ctx *callContext) (string, error) {
 		return "", err
 	}
 
-	log.Infof("Looking up tenant ID for %v", ctx.cfg.URL)
+	ctx.logger().Infof("Looking up tenant ID for %v", ctx.cfg.URL)
 
 	// create a GET HTTP request
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", resolverUri, nil)
+	client, err := ctx.httpClient()
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx.goContext, "GET", resolverUri, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create a request %q: %v", resolverUri, err.Error())
 	}
 
 	// execute request
 	ctx.startSpinner("Tenant ID resolution")
-	resp, err := client.Do(req)
+	resp, err := doRequest(client, req)
 	ctx.stopSpinner(err == nil && resp.StatusCode/100 == 2)
 	if err != nil {
 		return "", fmt.Errorf("GET request to %q failed: %v", req.URL, err.Error())
@@ -57,7 +58,7 @@ func resolveTenant(ctx *callContext) (string, error) {
 	// log error if it occurred
 	if resp.StatusCode/100 != 2 {
 		// log error before trying to parse body, more processing later
-		log.Errorf("Request to %q failed, status %q; more info to follow", req.URL, resp.Status)
+		ctx.logger().Errorf("Request to %q failed, status %q; more info to follow", req.URL, resp.Status)
 		// fall through
 	}
 
@@ -0,0 +1,70 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/cisco-open/fsoc/config"
+)
+
+// tokenValidationPath is a cheap platform API call that any authenticated principal can make
+const tokenValidationPath = "knowledge-store/v1/objects/extensibility:solution?max=1"
+
+// AccessToken returns a valid access token for the current profile, logging in if the profile has
+// no token. With refresh, the token is refreshed first (for auth methods that can refresh tokens),
+// so that it remains valid for as long as possible, e.g., when it is passed to other tools.
+func AccessToken(refresh bool) (string, error) {
+	callCtx, err := newDefaultCallContext(nil)
+	if err != nil {
+		return "", err
+	}
+	defer callCtx.stopSpinner(false) // ensure not running when returning
+
+	if callCtx.cfg.Token == "" || (refresh && slices.Contains(refreshableAuthMethods, callCtx.cfg.AuthMethod)) {
+		if err := login(callCtx); err != nil {
+			return "", err
+		}
+	}
+	if callCtx.cfg.Token == "" {
+		return "", fmt.Errorf("the %q authentication method does not use access tokens", callCtx.cfg.AuthMethod)
+	}
+	return callCtx.cfg.Token, nil
+}
+
+// ValidateToken verifies that the platform accepts the current profile's access token by making a
+// cheap API call with it, without logging in or refreshing the token. A permission error (403)
+// means that the token is valid but the principal is not allowed to make the validation call.
+func ValidateToken() error {
+	cfg := config.GetCurrentContext()
+	if cfg == nil {
+		return fmt.Errorf("fsoc is not configured, please run 'fsoc config set' first")
+	}
+	if cfg.Token == "" && cfg.AuthMethod != config.AuthMethodNone && cfg.AuthMethod != config.AuthMethodLocal {
+		return &LoginError{Err: fmt.Errorf("not logged in; please use 'fsoc login'")}
+	}
+
+	var out any
+	return JSONGet(tokenValidationPath, &out, &Options{
+		Headers: map[string]string{
+			"layer-type": "TENANT",
+			"layer-id":   cfg.Tenant,
+		},
+		ExpectedErrors: []int{http.StatusUnauthorized, http.StatusForbidden},
+		NoLogin:        true,
+	})
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/apex/log"
+
+	"github.com/cisco-open/fsoc/config"
+)
+
+// transports keeps one transport per distinct set of transport options, so that
+// connections are kept alive and reused across calls within the same command
+var transports = struct {
+	sync.Mutex
+	byOptions map[config.TransportOptions]*http.Transport
+}{byOptions: map[config.TransportOptions]*http.Transport{}}
+
+// NewHTTPClient returns an HTTP client that uses the transport settings of the given
+// profile (proxy, CA certificates, client certificate, etc.). All clients for the same settings
+// share the same transport and its connection pool. All outbound calls to the platform should
+// use clients created by this function. If cfg is nil, the system defaults are used.
+func NewHTTPClient(cfg *config.Context) (*http.Client, error) {
+	var options config.TransportOptions
+	if cfg != nil {
+		options = cfg.TransportOptions
+	}
+	transport, err := getTransport(options)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// httpClient returns an HTTP client for the call context's profile, or the client's own
+func (c *callContext) httpClient() (*http.Client, error) {
+	if c.client != nil && c.client.httpClient != nil {
+		httpClient := *c.client.httpClient // copy, as the caller may customize it
+		return &httpClient, nil
+	}
+	return NewHTTPClient(c.cfg)
+}
+
+func getTransport(options config.TransportOptions) (*http.Transport, error) {
+	transports.Lock()
+	defer transports.Unlock()
+
+	if transport, found := transports.byOptions[options]; found {
+		return transport, nil
+	}
+	transport, err := newTransport(options)
+	if err != nil {
+		return nil, err
+	}
+	transports.byOptions[options] = transport
+	return transport, nil
+}
+
+func newTransport(options config.TransportOptions) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone() // keeps proxy from environment, keep-alives, timeouts
+
+	// proxy
+	if options.Proxy != "" {
+		proxyUrl, err := url.Parse(options.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q in the profile: %w", options.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyUrl)
+	}
+
+	// TLS settings
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if options.CACertFile != "" {
+		pem, err := os.ReadFile(options.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificates file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			log.Warnf("Failed to load the system CA certificates (%v); trusting only the certificates from %q", err, options.CACertFile)
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid PEM certificates found in CA certificates file %q", options.CACertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if options.ClientCertFile != "" || options.ClientKeyFile != "" {
+		if options.ClientCertFile == "" || options.ClientKeyFile == "" {
+			return nil, fmt.Errorf("both a client certificate file and a client key file are required for client certificate authentication")
+		}
+		cert, err := tls.LoadX509KeyPair(options.ClientCertFile, options.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if options.InsecureSkipVerify {
+		log.Warn("Server certificate verification is disabled for this profile; use only with local development environments")
+		tlsConfig.InsecureSkipVerify = true // #nosec G402 -- explicitly requested in the profile
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return transport, nil
+}
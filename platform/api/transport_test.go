@@ -0,0 +1,83 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cisco-open/fsoc/config"
+)
+
+func TestNewHTTPClientSharesTransport(t *testing.T) {
+	c1, err := NewHTTPClient(&config.Context{})
+	assert.Nil(t, err)
+	c2, err := NewHTTPClient(nil)
+	assert.Nil(t, err)
+	assert.Same(t, c1.Transport, c2.Transport)
+
+	c3, err := NewHTTPClient(&config.Context{TransportOptions: config.TransportOptions{Proxy: "http://proxy.example.com:8080"}})
+	assert.Nil(t, err)
+	assert.NotSame(t, c1.Transport, c3.Transport)
+	req, _ := http.NewRequest("GET", "https://platform.example.com/", nil)
+	proxyUrl, err := c3.Transport.(*http.Transport).Proxy(req)
+	assert.Nil(t, err)
+	assert.Equal(t, "proxy.example.com:8080", proxyUrl.Host)
+}
+
+func TestNewHTTPClientCustomCA(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// without the server's CA, the call fails
+	client, err := NewHTTPClient(&config.Context{})
+	assert.Nil(t, err)
+	_, err = client.Get(server.URL)
+	assert.NotNil(t, err)
+
+	// with the CA file, it succeeds
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	assert.Nil(t, os.WriteFile(caFile, caPem, 0600))
+	client, err = NewHTTPClient(&config.Context{TransportOptions: config.TransportOptions{CACertFile: caFile}})
+	assert.Nil(t, err)
+	resp, err := client.Get(server.URL)
+	assert.Nil(t, err)
+	if resp != nil {
+		resp.Body.Close()
+	}
+
+	// insecure skips verification
+	client, err = NewHTTPClient(&config.Context{TransportOptions: config.TransportOptions{InsecureSkipVerify: true}})
+	assert.Nil(t, err)
+	resp, err = client.Get(server.URL)
+	assert.Nil(t, err)
+	if resp != nil {
+		resp.Body.Close()
+	}
+}
+
+func TestNewHTTPClientInvalidSettings(t *testing.T) {
+	_, err := NewHTTPClient(&config.Context{TransportOptions: config.TransportOptions{CACertFile: "/nonexistent/ca.pem"}})
+	assert.NotNil(t, err)
+	_, err = NewHTTPClient(&config.Context{TransportOptions: config.TransportOptions{ClientCertFile: "cert.pem"}})
+	assert.ErrorContains(t, err, "client key")
+}
@@ -19,28 +19,115 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 )
 
-type user struct {
-	ID string `json:"sub"`
+// tokenClaims are the JWT access token claims that fsoc uses
+type tokenClaims struct {
+	Subject  string  `json:"sub"`
+	Expiry   float64 `json:"exp"` // seconds since the epoch (JWT NumericDate); 0 if not present
+	IssuedAt float64 `json:"iat"`
+	Issuer   string  `json:"iss"`
+	Audience any     `json:"aud"`   // string or array of strings
+	Scope    any     `json:"scope"` // space-separated string (RFC 8693) or array of strings
+	Scp      any     `json:"scp"`   // alternative name for scope used by some issuers
 }
 
-func extractUser(accessToken string) (string, error) {
-	var userData user
+// TokenInfo describes an access token, as decoded from its JWT claims. The token's signature is not verified.
+type TokenInfo struct {
+	Principal string     `json:"principal"`
+	Issuer    string     `json:"issuer,omitempty"`
+	Audience  []string   `json:"audience,omitempty"`
+	Scopes    []string   `json:"scopes,omitempty"`
+	IssuedAt  *time.Time `json:"issuedAt,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// DescribeToken decodes a JWT access token into a TokenInfo, without verifying its signature
+func DescribeToken(accessToken string) (*TokenInfo, error) {
+	claims, err := decodeTokenClaims(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	info := &TokenInfo{
+		Principal: claims.Subject,
+		Issuer:    claims.Issuer,
+		Audience:  claimStrings(claims.Audience, false),
+		Scopes:    claimStrings(claims.Scope, true),
+	}
+	if len(info.Scopes) == 0 {
+		info.Scopes = claimStrings(claims.Scp, true)
+	}
+	if claims.IssuedAt > 0 {
+		t := time.Unix(int64(claims.IssuedAt), 0)
+		info.IssuedAt = &t
+	}
+	if claims.Expiry > 0 {
+		t := time.Unix(int64(claims.Expiry), 0)
+		info.ExpiresAt = &t
+	}
+	return info, nil
+}
+
+// claimStrings converts a claim that can be a string or an array of strings to a list of strings,
+// optionally splitting strings on whitespace
+func claimStrings(claim any, split bool) []string {
+	var values []string
+	switch v := claim.(type) {
+	case string:
+		if split {
+			values = strings.Fields(v)
+		} else if v != "" {
+			values = []string{v}
+		}
+	case []any:
+		for _, elem := range v {
+			if s, ok := elem.(string); ok && s != "" {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
+
+// decodeTokenClaims decodes the claims of a JWT access token, without verifying its signature
+func decodeTokenClaims(accessToken string) (*tokenClaims, error) {
+	var claims tokenClaims
 	metaDataStringArray := strings.Split(accessToken, ".")
 	if len(metaDataStringArray) < 3 {
-		return "", fmt.Errorf("invalid bearer token detected")
+		return nil, fmt.Errorf("invalid bearer token detected")
 	}
 
-	// try to decode metadata token
+	// try to decode metadata token (JWTs use URL-safe encoding; accept the standard one for compatibility)
 	metaDataString := metaDataStringArray[1]
-	decodedMetaDataBytes, err := base64.RawStdEncoding.DecodeString(metaDataString)
+	decodedMetaDataBytes, err := base64.RawURLEncoding.DecodeString(metaDataString)
+	if err != nil {
+		decodedMetaDataBytes, err = base64.RawStdEncoding.DecodeString(metaDataString)
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to decode base64 string: %v", err.Error())
+		return nil, fmt.Errorf("failed to decode base64 string: %v", err.Error())
+	}
+	if err := json.Unmarshal(decodedMetaDataBytes, &claims); err != nil {
+		return nil, fmt.Errorf("failed to JSON parse the claims from the decoded bearer token with error %v", err.Error())
 	}
-	if err := json.Unmarshal(decodedMetaDataBytes, &userData); err != nil {
-		return "", fmt.Errorf("failed to JSON parse the `sub` from the decoded bearer token with error %v", err.Error())
+
+	return &claims, nil
+}
+
+func extractUser(accessToken string) (string, error) {
+	claims, err := decodeTokenClaims(accessToken)
+	if err != nil {
+		return "", err
 	}
+	return claims.Subject, nil
+}
 
-	return userData.ID, nil
+// tokenExpiry returns the expiration time of a JWT access token; false if the
+// token cannot be decoded or has no expiration time
+func tokenExpiry(accessToken string) (time.Time, bool) {
+	claims, err := decodeTokenClaims(accessToken)
+	if err != nil || claims.Expiry <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(claims.Expiry), 0), true
 }
@@ -25,6 +25,7 @@ import (
 	"github.com/cisco-open/fsoc/config"
 	"github.com/cisco-open/fsoc/output"
 	"github.com/cisco-open/fsoc/platform/api"
+	"github.com/cisco-open/fsoc/tracing"
 )
 
 const (
@@ -402,13 +403,23 @@ func (exp *Exporter) createOtelSpan(t *Span) *spans.Span {
 	return ots
 }
 
-func (exp *Exporter) exportHTTP(path string, m protoreflect.ProtoMessage) error {
+func (exp *Exporter) exportHTTP(path string, m protoreflect.ProtoMessage) (err error) {
+	// trace the export, linking it to the platform's trace of the ingestion (see traceresponse below)
+	ctx, span := tracing.Start(api.DefaultContext(), "MELT export "+path, tracing.SpanKindInternal, tracing.Attributes{
+		"fsoc.melt.kind":    path,
+		"fsoc.melt.dry_run": exp.DryRun,
+	})
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
 
 	options := api.Options{
 		Headers: map[string]string{
 			"Content-Type": "application/x-protobuf",
 			"Accept":       "application/x-protobuf",
 		},
+		Context: ctx,
 	}
 
 	// marshal into protobuf
@@ -416,6 +427,7 @@ func (exp *Exporter) exportHTTP(path string, m protoreflect.ProtoMessage) error
 	if err != nil {
 		return fmt.Errorf("failed to marshal MELT data: %w", err)
 	}
+	span.SetAttributes(tracing.Attributes{"fsoc.melt.payload_bytes": len(data)})
 
 	// dump data if requested
 	if exp.DumpFunc != nil {
@@ -432,10 +444,11 @@ func (exp *Exporter) exportHTTP(path string, m protoreflect.ProtoMessage) error
 			return err
 		}
 
-		// log traceresponse
+		// log (and link) traceresponse
 		tr := ""
 		if trh, ok := options.ResponseHeaders["Traceresponse"]; ok {
 			tr = trh[0] // first value only
+			span.AddLink(tr, tracing.Attributes{"fsoc.link": "traceresponse"})
 		}
 		log.WithFields(log.Fields{
 			"kind":           path,
@@ -0,0 +1,230 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	collspans "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	common "go.opentelemetry.io/proto/otlp/common/v1"
+	resource "go.opentelemetry.io/proto/otlp/resource/v1"
+	spans "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Supported span exporters
+const (
+	// Send spans to an OTLP/HTTP endpoint, e.g., a local OpenTelemetry collector
+	ExporterOTLP = "otlp"
+	// Append spans to a file, in the OTLP JSON format (one export request per line)
+	ExporterFile = "file"
+)
+
+// DefaultOTLPEndpoint is the OTLP/HTTP endpoint of a local OpenTelemetry collector
+const DefaultOTLPEndpoint = "http://localhost:4318"
+
+const (
+	serviceName   = "fsoc"
+	tracesPath    = "v1/traces"
+	exportTimeout = 5 * time.Second
+)
+
+// Options define where spans are exported
+type Options struct {
+	Exporter           string       // ExporterOTLP or ExporterFile
+	Endpoint           string       // OTLP/HTTP endpoint (ExporterOTLP only), DefaultOTLPEndpoint if empty
+	HTTPClient         *http.Client // HTTP client for the OTLP endpoint (ExporterOTLP only), e.g., with the profile's transport settings; if nil, the system defaults are used
+	File               string       // path of the file to append to (ExporterFile only)
+	ServiceVersion     string       // fsoc version
+	ResourceAttributes Attributes   // additional attributes of the fsoc process, e.g., the profile
+}
+
+// Exporters returns the names of the supported exporters
+func Exporters() []string {
+	return []string{ExporterOTLP, ExporterFile}
+}
+
+// Setup enables tracing with the given export options. Spans are collected in memory until Shutdown.
+func Setup(options Options) error {
+	switch options.Exporter {
+	case ExporterOTLP:
+		if options.Endpoint == "" {
+			options.Endpoint = DefaultOTLPEndpoint
+		}
+		if _, err := otlpTracesURL(options.Endpoint); err != nil {
+			return err
+		}
+	case ExporterFile:
+		if options.File == "" {
+			return fmt.Errorf("the %q span exporter requires a file", ExporterFile)
+		}
+	default:
+		return fmt.Errorf("unknown span exporter %q; must be one of {%q}", options.Exporter, strings.Join(Exporters(), `", "`))
+	}
+
+	tracer.Lock()
+	defer tracer.Unlock()
+	tracer.options = &options
+	tracer.remoteParent = inheritedTraceparent()
+	tracer.ended = nil
+	return nil
+}
+
+// Shutdown exports the ended spans and disables tracing. It does nothing if tracing is not set up.
+func Shutdown() error {
+	tracer.Lock()
+	options := tracer.options
+	ended := tracer.ended
+	tracer.options = nil
+	tracer.remoteParent = nil
+	tracer.ended = nil
+	tracer.Unlock()
+
+	if options == nil || len(ended) == 0 {
+		return nil
+	}
+	request := buildExportRequest(options, ended)
+	switch options.Exporter {
+	case ExporterOTLP:
+		return exportOTLP(options.HTTPClient, options.Endpoint, request)
+	case ExporterFile:
+		return exportFile(options.File, request)
+	}
+	return nil
+}
+
+func buildExportRequest(options *Options, ended []*spans.Span) *collspans.ExportTraceServiceRequest {
+	attributes := Attributes{
+		"service.name": serviceName,
+		"process.pid":  os.Getpid(),
+	}
+	if options.ServiceVersion != "" {
+		attributes["service.version"] = options.ServiceVersion
+	}
+	for k, v := range options.ResourceAttributes {
+		attributes[k] = v
+	}
+	return &collspans.ExportTraceServiceRequest{
+		ResourceSpans: []*spans.ResourceSpans{{
+			Resource: &resource.Resource{Attributes: toKeyValueList(attributes)},
+			ScopeSpans: []*spans.ScopeSpans{{
+				Scope: &common.InstrumentationScope{Name: serviceName, Version: options.ServiceVersion},
+				Spans: ended,
+			}},
+		}},
+	}
+}
+
+// otlpTracesURL returns the URL for sending traces to an OTLP/HTTP endpoint; the signal's
+// path is appended to a base endpoint (e.g., http://localhost:4318), as OpenTelemetry SDKs do
+func otlpTracesURL(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid OTLP endpoint %q: must be an http or https URL, e.g., %v", endpoint, DefaultOTLPEndpoint)
+	}
+	if strings.HasSuffix(u.Path, "/"+tracesPath) {
+		return u.String(), nil
+	}
+	return u.JoinPath(tracesPath).String(), nil
+}
+
+func exportOTLP(httpClient *http.Client, endpoint string, request *collspans.ExportTraceServiceRequest) error {
+	tracesURL, err := otlpTracesURL(endpoint)
+	if err != nil {
+		return err
+	}
+	data, err := proto.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal spans: %w", err)
+	}
+
+	// nb: not a platform API call, so it is neither traced nor recorded
+	client := &http.Client{}
+	if httpClient != nil {
+		*client = *httpClient // shallow copy, so that the timeout applies only to the export
+	}
+	client.Timeout = exportTimeout
+	resp, err := client.Post(tracesURL, "application/x-protobuf", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to export spans to %q: %w", tracesURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to export spans to %q: status %v: %s", tracesURL, resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+func exportFile(path string, request *collspans.ExportTraceServiceRequest) error {
+	data, err := otlpJSON(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal spans: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open the trace file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write spans to %q: %w", path, err)
+	}
+	return nil
+}
+
+// otlpJSON encodes an export request per the OTLP JSON encoding, which, unlike the generic protobuf
+// JSON mapping, uses integer enums and hex-encoded trace and span IDs
+func otlpJSON(request *collspans.ExportTraceServiceRequest) ([]byte, error) {
+	data, err := protojson.MarshalOptions{UseEnumNumbers: true}.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	hexEncodeIDs(doc)
+	return json.Marshal(doc)
+}
+
+func hexEncodeIDs(node any) {
+	switch node := node.(type) {
+	case map[string]any:
+		for k, v := range node {
+			if s, ok := v.(string); ok && (k == "traceId" || k == "spanId" || k == "parentSpanId") {
+				if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+					node[k] = hex.EncodeToString(b)
+				}
+				continue
+			}
+			hexEncodeIDs(v)
+		}
+	case []any:
+		for _, v := range node {
+			hexEncodeIDs(v)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracing creates OpenTelemetry spans for fsoc's own operations (commands, platform API
+// calls, logins, etc.), so that fsoc itself can be observed, and exports them when fsoc exits.
+// Tracing is disabled until Setup is called; all functions and Span methods are no-ops then.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	common "go.opentelemetry.io/proto/otlp/common/v1"
+	spans "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// TraceparentEnvVar is the environment variable with a W3C traceparent to continue, e.g., a CI
+// pipeline's trace; the command's span becomes a child of the span it identifies
+const TraceparentEnvVar = "TRACEPARENT"
+
+const (
+	traceparentHeader = "traceparent"
+	traceVersion      = "00"
+	flagSampled       = "01"
+)
+
+// SpanKind is the OpenTelemetry kind of a span
+type SpanKind = spans.Span_SpanKind
+
+const (
+	SpanKindInternal = spans.Span_SPAN_KIND_INTERNAL // an operation within fsoc (e.g., command, login)
+	SpanKindClient   = spans.Span_SPAN_KIND_CLIENT   // an HTTP request made by fsoc
+)
+
+// Attributes are span attributes; values may be strings, bools, integers or floats
+type Attributes map[string]any
+
+// Span is an operation being traced. A nil *Span (returned when tracing is disabled) is valid
+// and ignores all calls.
+type Span struct {
+	mu          sync.Mutex
+	name        string
+	kind        SpanKind
+	traceID     [16]byte
+	spanID      [8]byte
+	parentID    []byte // nil for root spans
+	start       time.Time
+	attributes  Attributes
+	links       []*spans.Span_Link
+	statusError string
+	failed      bool
+	ended       bool
+}
+
+type spanKey struct{}
+
+// tracer keeps the tracing settings and the ended spans until they are exported
+var tracer struct {
+	sync.Mutex
+	options      *Options
+	remoteParent *spans.Span_Link // trace context inherited from TraceparentEnvVar, if any
+	ended        []*spans.Span
+}
+
+// Enabled returns true if tracing is set up
+func Enabled() bool {
+	tracer.Lock()
+	defer tracer.Unlock()
+	return tracer.options != nil
+}
+
+// Start creates a span as a child of the span in ctx, if any, and returns a context with the new span.
+// Root spans continue the trace in TraceparentEnvVar, if set.
+func Start(ctx context.Context, name string, kind SpanKind, attributes Attributes) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	tracer.Lock()
+	enabled := tracer.options != nil
+	remoteParent := tracer.remoteParent
+	tracer.Unlock()
+	if !enabled {
+		return ctx, nil
+	}
+
+	span := &Span{
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: Attributes{},
+	}
+	for k, v := range attributes {
+		span.attributes[k] = v
+	}
+	_, _ = rand.Read(span.spanID[:])
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID[:]
+	} else if remoteParent != nil {
+		copy(span.traceID[:], remoteParent.TraceId)
+		span.parentID = remoteParent.SpanId
+	} else {
+		_, _ = rand.Read(span.traceID[:])
+	}
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SpanFromContext returns the span in the context, nil if none
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// Inject adds the W3C traceparent header for the span in ctx to an outgoing request's headers
+func Inject(ctx context.Context, header http.Header) {
+	if span := SpanFromContext(ctx); span != nil {
+		header.Set(traceparentHeader, span.Traceparent())
+	}
+}
+
+// Traceparent returns the W3C traceparent that identifies the span
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+	return strings.Join([]string{traceVersion, hex.EncodeToString(s.traceID[:]), hex.EncodeToString(s.spanID[:]), flagSampled}, "-")
+}
+
+// SetAttributes adds (or replaces) attributes of the span
+func (s *Span) SetAttributes(attributes Attributes) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range attributes {
+		s.attributes[k] = v
+	}
+}
+
+// SetError marks the span as failed with the error's message; nil errors are ignored
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed = true
+	s.statusError = err.Error()
+}
+
+// AddLink links the span to another span, identified by a W3C traceparent (or traceresponse)
+// value, e.g., the server-side trace of a request. Values that cannot be parsed are ignored.
+func (s *Span) AddLink(traceparent string, attributes Attributes) {
+	if s == nil {
+		return
+	}
+	link, err := parseTraceparent(traceparent)
+	if err != nil {
+		return
+	}
+	link.Attributes = toKeyValueList(attributes)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.links = append(s.links, link)
+}
+
+// End completes the span; it is exported when fsoc exits (see Shutdown). Ending a span again has no effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	span := &spans.Span{
+		TraceId:           append([]byte{}, s.traceID[:]...),
+		SpanId:            append([]byte{}, s.spanID[:]...),
+		ParentSpanId:      s.parentID,
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: uint64(s.start.UnixNano()),
+		EndTimeUnixNano:   uint64(time.Now().UnixNano()),
+		Attributes:        toKeyValueList(s.attributes),
+		Links:             s.links,
+		Status:            &spans.Status{Code: spans.Status_STATUS_CODE_UNSET},
+	}
+	if s.failed {
+		span.Status = &spans.Status{Code: spans.Status_STATUS_CODE_ERROR, Message: s.statusError}
+	}
+	s.mu.Unlock()
+
+	tracer.Lock()
+	defer tracer.Unlock()
+	if tracer.options != nil {
+		tracer.ended = append(tracer.ended, span)
+	}
+}
+
+// parseTraceparent parses a W3C traceparent value ("00-<trace id>-<span id>-<flags>")
+func parseTraceparent(value string) (*spans.Span_Link, error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return nil, fmt.Errorf("invalid traceparent %q", value)
+	}
+	traceID, err := hex.DecodeString(parts[1])
+	if err != nil || len(traceID) != 16 || isZero(traceID) {
+		return nil, fmt.Errorf("invalid trace ID in traceparent %q", value)
+	}
+	spanID, err := hex.DecodeString(parts[2])
+	if err != nil || len(spanID) != 8 || isZero(spanID) {
+		return nil, fmt.Errorf("invalid span ID in traceparent %q", value)
+	}
+	return &spans.Span_Link{TraceId: traceID, SpanId: spanID}, nil
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func toKeyValueList(attributes Attributes) []*common.KeyValue {
+	list := []*common.KeyValue{}
+	for k, v := range attributes {
+		value := &common.AnyValue{}
+		switch v := v.(type) {
+		case string:
+			value.Value = &common.AnyValue_StringValue{StringValue: v}
+		case bool:
+			value.Value = &common.AnyValue_BoolValue{BoolValue: v}
+		case int:
+			value.Value = &common.AnyValue_IntValue{IntValue: int64(v)}
+		case int64:
+			value.Value = &common.AnyValue_IntValue{IntValue: v}
+		case float64:
+			value.Value = &common.AnyValue_DoubleValue{DoubleValue: v}
+		case nil:
+			continue
+		default:
+			value.Value = &common.AnyValue_StringValue{StringValue: fmt.Sprintf("%v", v)}
+		}
+		list = append(list, &common.KeyValue{Key: k, Value: value})
+	}
+	return list
+}
+
+// inheritedTraceparent returns the trace context to continue from the environment, if any
+func inheritedTraceparent() *spans.Span_Link {
+	value := os.Getenv(TraceparentEnvVar)
+	if value == "" {
+		return nil
+	}
+	link, err := parseTraceparent(value)
+	if err != nil {
+		return nil
+	}
+	return link
+}
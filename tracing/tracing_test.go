@@ -0,0 +1,184 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collspans "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	spans "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDisabled(t *testing.T) {
+	ctx, span := Start(context.Background(), "op", SpanKindInternal, nil)
+	assert.Nil(t, span)
+	assert.Nil(t, SpanFromContext(ctx))
+
+	// nil spans ignore all calls
+	span.SetAttributes(Attributes{"a": 1})
+	span.SetError(errors.New("failed"))
+	span.AddLink("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", nil)
+	span.End()
+	assert.Equal(t, "", span.Traceparent())
+	header := http.Header{}
+	Inject(ctx, header)
+	assert.Equal(t, "", header.Get("traceparent"))
+	assert.Nil(t, Shutdown())
+}
+
+func TestSpansToFile(t *testing.T) {
+	t.Setenv(TraceparentEnvVar, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	file := filepath.Join(t.TempDir(), "traces.json")
+	require.Nil(t, Setup(Options{Exporter: ExporterFile, File: file, ServiceVersion: "1.2.3"}))
+
+	ctx, command := Start(context.Background(), "fsoc test", SpanKindInternal, Attributes{"fsoc.profile": "ci"})
+	callCtx, call := Start(ctx, "GET some/path", SpanKindClient, nil)
+	header := http.Header{}
+	Inject(callCtx, header)
+	assert.Equal(t, call.Traceparent(), header.Get("traceparent"))
+	assert.True(t, strings.HasPrefix(call.Traceparent(), "00-0af7651916cd43dd8448eb211c80319c-"))
+	call.AddLink("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", Attributes{"fsoc.link": "traceresponse"})
+	call.AddLink("invalid", nil)
+	call.SetError(errors.New("status 503"))
+	call.End()
+	call.End() // no effect
+	command.End()
+	require.Nil(t, Shutdown())
+	assert.False(t, Enabled())
+
+	data, err := os.ReadFile(file)
+	require.Nil(t, err)
+	var request struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []map[string]any `json:"attributes"`
+			} `json:"resource"`
+			ScopeSpans []struct {
+				Spans []struct {
+					Name         string `json:"name"`
+					Kind         int    `json:"kind"`
+					TraceID      string `json:"traceId"`
+					SpanID       string `json:"spanId"`
+					ParentSpanID string `json:"parentSpanId"`
+					Links        []struct {
+						TraceID string `json:"traceId"`
+					} `json:"links"`
+					Status struct {
+						Code    int    `json:"code"`
+						Message string `json:"message"`
+					} `json:"status"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	require.Nil(t, json.Unmarshal(data, &request))
+	ended := request.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Equal(t, 2, len(ended))
+	assert.Equal(t, "GET some/path", ended[0].Name)
+	assert.Equal(t, int(SpanKindClient), ended[0].Kind)
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", ended[0].TraceID)
+	assert.Equal(t, ended[1].SpanID, ended[0].ParentSpanID)
+	assert.Equal(t, 1, len(ended[0].Links))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", ended[0].Links[0].TraceID)
+	assert.Equal(t, int(spans.Status_STATUS_CODE_ERROR), ended[0].Status.Code)
+	assert.Equal(t, "status 503", ended[0].Status.Message)
+	assert.Equal(t, "fsoc test", ended[1].Name)
+	assert.Equal(t, "b7ad6b7169203331", ended[1].ParentSpanID) // continues the inherited trace
+	assert.Contains(t, string(data), `"service.name"`)
+	assert.Contains(t, string(data), `"1.2.3"`)
+}
+
+func TestSpansToOTLP(t *testing.T) {
+	var received collspans.ExportTraceServiceRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		assert.Nil(t, proto.Unmarshal(body, &received))
+	}))
+	defer server.Close()
+
+	require.Nil(t, Setup(Options{Exporter: ExporterOTLP, Endpoint: server.URL}))
+	_, span := Start(context.Background(), "fsoc test", SpanKindInternal, Attributes{"count": 3, "ok": true})
+	span.End()
+	require.Nil(t, Shutdown())
+
+	ended := received.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Equal(t, 1, len(ended))
+	assert.Equal(t, "fsoc test", ended[0].Name)
+	assert.Nil(t, ended[0].ParentSpanId)
+	assert.Equal(t, 16, len(ended[0].TraceId))
+	assert.Equal(t, 2, len(ended[0].Attributes))
+
+	// export failures are reported
+	failing := httptest.NewServer(http.NotFoundHandler())
+	defer failing.Close()
+	require.Nil(t, Setup(Options{Exporter: ExporterOTLP, Endpoint: failing.URL}))
+	_, span = Start(context.Background(), "fsoc test", SpanKindInternal, nil)
+	span.End()
+	assert.NotNil(t, Shutdown())
+
+	// the provided HTTP client is used, e.g., to trust the endpoint's certificate
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsServer.Close()
+	require.Nil(t, Setup(Options{Exporter: ExporterOTLP, Endpoint: tlsServer.URL}))
+	_, span = Start(context.Background(), "fsoc test", SpanKindInternal, nil)
+	span.End()
+	assert.NotNil(t, Shutdown()) // unknown certificate authority
+	require.Nil(t, Setup(Options{Exporter: ExporterOTLP, Endpoint: tlsServer.URL, HTTPClient: tlsServer.Client()}))
+	_, span = Start(context.Background(), "fsoc test", SpanKindInternal, nil)
+	span.End()
+	assert.Nil(t, Shutdown())
+}
+
+func TestSetupErrors(t *testing.T) {
+	assert.NotNil(t, Setup(Options{Exporter: "zipkin"}))
+	assert.NotNil(t, Setup(Options{Exporter: ExporterFile}))
+	assert.NotNil(t, Setup(Options{Exporter: ExporterOTLP, Endpoint: "localhost:4318"}))
+	assert.False(t, Enabled())
+
+	u, err := otlpTracesURL("http://localhost:4318")
+	assert.Nil(t, err)
+	assert.Equal(t, "http://localhost:4318/v1/traces", u)
+	u, err = otlpTracesURL("https://collector.example.com/otlp/v1/traces")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://collector.example.com/otlp/v1/traces", u)
+}
+
+func TestParseTraceparent(t *testing.T) {
+	link, err := parseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.Nil(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", hex.EncodeToString(link.TraceId))
+	assert.Equal(t, "00f067aa0ba902b7", hex.EncodeToString(link.SpanId))
+
+	for _, value := range []string{"", "00-abc-def-01", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"} {
+		_, err := parseTraceparent(value)
+		assert.NotNil(t, err, value)
+	}
+}